@@ -1,15 +1,23 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"github.com/lanxin/im-backend/config"
 	"github.com/lanxin/im-backend/internal/api"
 	"github.com/lanxin/im-backend/internal/middleware"
 	"github.com/lanxin/im-backend/internal/pkg/mysql"
 	"github.com/lanxin/im-backend/internal/pkg/redis"
+	"github.com/lanxin/im-backend/internal/service"
 	"github.com/lanxin/im-backend/internal/websocket"
 	"github.com/lanxin/im-backend/pkg/kafka"
 )
@@ -37,10 +45,37 @@ func main() {
 
 	// 初始化WebSocket Hub
 	hub := websocket.NewHub()
+	if cfg.WebSocket.ClusterEnabled {
+		hub.EnableCluster(redis.GetClient(), nodeID(cfg))
+	}
 	go hub.Run()
 
+	// 启动发件箱中继（把事务内写入的事件投递到Kafka）
+	relay := service.NewOutboxRelay(cfg.Kafka.Outbox, map[string]*kafka.Producer{
+		cfg.Kafka.Topic.Message: producer,
+	})
+	relayCtx, stopRelay := context.WithCancel(context.Background())
+	defer stopRelay()
+	go relay.Run(relayCtx)
+
+	// 启动会话变更日志清理器（删除超过保留期的同步变更）
+	go service.NewChangeLogPruner().Run(relayCtx)
+
+	// 启动定时消息调度器（到期后按普通消息发送）
+	dispatcher := service.NewScheduledMessageDispatcher(cfg, hub)
+	go dispatcher.Run(relayCtx)
+
+	// 启动阅后即焚消息清理器
+	reaper := service.NewMessageReaper(cfg, hub)
+	go reaper.Run(relayCtx)
+
+	// 启动在线状态服务（向联系人推送状态变化，定期写回最后在线时间）
+	presence := service.NewPresenceService(cfg, hub)
+	hub.SetPresenceHandler(presence.Notify)
+	go presence.Run(relayCtx)
+
 	// 创建路由
-	router := setupRouter(cfg, hub, producer)
+	router := setupRouter(cfg, hub, relay, presence)
 
 	// 启动服务器
 	addr := fmt.Sprintf(":%d", cfg.Server.Port)
@@ -49,12 +84,41 @@ func main() {
 	log.Printf("Domain: %s", cfg.Server.Domain)
 	log.Printf("WebSocket Hub started")
 
-	if err := router.Run(addr); err != nil {
-		log.Fatalf("Failed to start server: %v", err)
+	srv := &http.Server{
+		Addr:    addr,
+		Handler: router,
+	}
+	serverErr := make(chan error, 1)
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			serverErr <- err
+		}
+	}()
+
+	// 收到退出信号后停止接收新请求，再注销集群节点
+	// 不能用log.Fatalf退出，否则defer中的清理不会执行
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	select {
+	case sig := <-quit:
+		log.Printf("Received %s, shutting down server...", sig)
+	case err := <-serverErr:
+		log.Printf("Failed to start server: %v", err)
+	}
+
+	stopRelay()
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Server shutdown error: %v", err)
 	}
+
+	hub.Shutdown()
+	log.Println("Server exited")
 }
 
-func setupRouter(cfg *config.Config, hub *websocket.Hub, producer *kafka.Producer) *gin.Engine {
+func setupRouter(cfg *config.Config, hub *websocket.Hub, relay *service.OutboxRelay, presence *service.PresenceService) *gin.Engine {
 	r := gin.New()
 
 	// 全局中间件
@@ -66,24 +130,36 @@ func setupRouter(cfg *config.Config, hub *websocket.Hub, producer *kafka.Produce
 		r.Use(middleware.RateLimit(cfg.Security.RateLimit.RequestsPerMinute))
 	}
 
+	// WebSocket上行帧（send/ack/read/typing/recall）
+	hub.SetUpstreamHandler(api.NewWSFrameHandler(cfg, hub))
+	// 推送后未被确认的消息放回离线队列
+	hub.SetUnackedHandler(service.NewMessageService(cfg, hub).RequeueUnacked)
+	// 建立连接时和连接登记后检查设备会话是否已撤销
+	hub.SetSessionChecker(middleware.SessionRevoked)
+
 	// 创建Handler
-	authHandler := api.NewAuthHandler(cfg)
+	authHandler := api.NewAuthHandler(cfg, hub)
 	userHandler := api.NewUserHandler()
-	messageHandler := api.NewMessageHandler(hub, producer)
+	messageHandler := api.NewMessageHandler(cfg, hub)
 	fileHandler, _ := api.NewFileHandler(cfg)
 	trtcHandler := api.NewTRTCHandler(cfg, hub)
 	conversationHandler := api.NewConversationHandler()
 	contactHandler := api.NewContactHandler()
 	favoriteHandler := api.NewFavoriteHandler()
 	reportHandler := api.NewReportHandler()
-	groupHandler := api.NewGroupHandler(hub)
+	groupHandler := api.NewGroupHandler(cfg, hub)
+	scheduledHandler := api.NewScheduledMessageHandler()
+	presenceHandler := api.NewPresenceHandler(presence)
 
 	// 健康检查
 	r.GET("/health", func(c *gin.Context) {
 		c.JSON(200, gin.H{
-			"status":       "ok",
-			"message":      "LanXin IM Server is running",
-			"online_users": hub.GetOnlineUserCount(),
+			"status":            "ok",
+			"message":           "LanXin IM Server is running",
+			"online_users":      hub.GetOnlineUserCount(),
+			"node_id":           hub.NodeID(),
+			"node_online_users": hub.GetLocalOnlineUserCount(),
+			"outbox":            relay.Stats(),
 		})
 	})
 
@@ -114,12 +190,18 @@ func setupRouter(cfg *config.Config, hub *websocket.Hub, producer *kafka.Produce
 			// 认证相关
 			authorized.POST("/auth/refresh", authHandler.RefreshToken)
 			authorized.POST("/auth/logout", authHandler.Logout)
+			authorized.GET("/auth/sessions", authHandler.GetSessions)
+			authorized.DELETE("/auth/sessions/:id", authHandler.RevokeSession)
 
 			// 用户相关
 			authorized.GET("/users/me", userHandler.GetCurrentUser)
 			authorized.PUT("/users/me", userHandler.UpdateProfile)
 			authorized.PUT("/users/me/password", userHandler.ChangePassword)
 			authorized.GET("/users/search", userHandler.SearchUsers)
+			authorized.GET("/users/presence", presenceHandler.GetPresence)
+			authorized.PUT("/users/me/status", presenceHandler.SetCustomStatus)
+			authorized.DELETE("/users/me/status", presenceHandler.ClearCustomStatus)
+			authorized.PUT("/users/me/privacy", presenceHandler.UpdatePrivacy)
 
 			// 会话相关（Android客户端需要）
 			authorized.GET("/conversations", conversationHandler.GetConversations)
@@ -134,12 +216,30 @@ func setupRouter(cfg *config.Config, hub *websocket.Hub, producer *kafka.Produce
 
 			// 消息相关
 			authorized.POST("/messages", messageHandler.SendMessage)
+			authorized.POST("/messages/forward", messageHandler.ForwardMessages)
+			authorized.POST("/messages/scheduled", scheduledHandler.CreateScheduledMessage)
+			authorized.GET("/messages/scheduled", scheduledHandler.GetScheduledMessages)
+			authorized.PUT("/messages/scheduled/:id", scheduledHandler.UpdateScheduledMessage)
+			authorized.DELETE("/messages/scheduled/:id", scheduledHandler.CancelScheduledMessage)
 			authorized.POST("/messages/:id/recall", messageHandler.RecallMessage)
+			authorized.PUT("/messages/:id", messageHandler.EditMessage)
+			authorized.DELETE("/messages/:id", messageHandler.DeleteMessage)
+			authorized.GET("/messages/:id/edits", messageHandler.GetMessageEdits)
+			authorized.GET("/messages/:id/thread", messageHandler.GetThread)
+			authorized.POST("/messages/:id/pin", messageHandler.PinMessage)
+			authorized.DELETE("/messages/:id/pin", messageHandler.UnpinMessage)
+			authorized.POST("/messages/:id/reactions", messageHandler.AddReaction)
+			authorized.DELETE("/messages/:id/reactions", messageHandler.RemoveReaction)
 			authorized.GET("/conversations/:id/messages", messageHandler.GetMessages)
 			authorized.GET("/conversations/:id/messages/history", messageHandler.GetHistoryMessages)
 			authorized.GET("/messages/search", messageHandler.SearchMessages)
 			authorized.GET("/messages/offline", messageHandler.GetOfflineMessages)
 			authorized.POST("/conversations/:id/read", messageHandler.MarkAsRead)
+			authorized.POST("/conversations/:id/clear", messageHandler.ClearConversation)
+			authorized.DELETE("/conversations/:id", messageHandler.HideConversation)
+			authorized.PUT("/conversations/:id/disappearing", messageHandler.SetDisappearing)
+			authorized.GET("/conversations/:id/pins", messageHandler.GetPins)
+			authorized.GET("/sync", messageHandler.Sync)
 
 			// 文件相关
 			authorized.GET("/files/upload-token", fileHandler.GetUploadToken)
@@ -188,3 +288,15 @@ func setupRouter(cfg *config.Config, hub *websocket.Hub, producer *kafka.Produce
 
 	return r
 }
+
+// nodeID 返回集群节点ID，未配置时使用 主机名-随机串
+func nodeID(cfg *config.Config) string {
+	if cfg.Server.NodeID != "" {
+		return cfg.Server.NodeID
+	}
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "node"
+	}
+	return fmt.Sprintf("%s-%s", hostname, uuid.New().String()[:8])
+}
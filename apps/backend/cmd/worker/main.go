@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/google/uuid"
+	"github.com/lanxin/im-backend/config"
+	"github.com/lanxin/im-backend/internal/pkg/mysql"
+	"github.com/lanxin/im-backend/internal/pkg/redis"
+	"github.com/lanxin/im-backend/internal/service"
+	"github.com/lanxin/im-backend/internal/websocket"
+	"github.com/lanxin/im-backend/internal/worker"
+	"github.com/lanxin/im-backend/pkg/kafka"
+)
+
+// worker 消费消息Topic，负责推送、送达状态更新、离线队列和离线通知
+// 推送通过Redis集群频道转发到用户所在的API节点，因此要求开启websocket.cluster_enabled
+func main() {
+	// 加载配置
+	cfg := config.Load()
+
+	// 未开启集群时API节点不订阅集群频道，worker的推送无法到达任何连接，
+	// 在线用户的消息会全部被当作离线处理
+	if !cfg.WebSocket.ClusterEnabled {
+		log.Fatal("Worker requires websocket.cluster_enabled: pushes reach API nodes through the cluster channels")
+	}
+
+	// 初始化数据库
+	mysql.Init(cfg.Database.MySQL)
+	defer mysql.Close()
+
+	// 初始化Redis
+	redis.Init(cfg.Redis)
+	defer redis.Close()
+
+	// worker本身没有WebSocket连接，以集群节点身份把推送转发给API节点
+	hub := websocket.NewHub()
+	hub.EnableCluster(redis.GetClient(), workerNodeID())
+	defer hub.Shutdown()
+	go hub.Run()
+
+	// 死信队列生产者
+	dlqProducer := kafka.NewProducer(cfg.Kafka.Brokers, cfg.Kafka.Topic.DeadLetter)
+	defer dlqProducer.Close()
+
+	handler := worker.NewMessageHandler(service.NewMessageService(cfg, hub))
+
+	consumer := kafka.NewConsumer(cfg.Kafka.Brokers, cfg.Kafka.Topic.Message, cfg.Kafka.Consumer.GroupID, handler)
+	consumer.SetDeadLetter(dlqProducer, cfg.Kafka.Consumer.MaxRetries)
+	defer consumer.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// 收到退出信号后停止消费
+	go func() {
+		quit := make(chan os.Signal, 1)
+		signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+		<-quit
+		log.Println("Shutting down worker...")
+		cancel()
+	}()
+
+	log.Printf("Worker started: topic=%s, group=%s, dead_letter=%s",
+		cfg.Kafka.Topic.Message, cfg.Kafka.Consumer.GroupID, cfg.Kafka.Topic.DeadLetter)
+
+	consumer.Start(ctx)
+}
+
+// workerNodeID 生成worker的集群节点ID
+func workerNodeID() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "worker"
+	}
+	return fmt.Sprintf("worker-%s-%s", hostname, uuid.New().String()[:8])
+}
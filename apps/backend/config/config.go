@@ -12,9 +12,12 @@ type Config struct {
 	Redis     RedisConfig     `mapstructure:"redis"`
 	Kafka     KafkaConfig     `mapstructure:"kafka"`
 	JWT       JWTConfig       `mapstructure:"jwt"`
+	Session   SessionConfig   `mapstructure:"session"`
 	Storage   StorageConfig   `mapstructure:"storage"`
 	TRTC      TRTCConfig      `mapstructure:"trtc"`
+	Push      PushConfig      `mapstructure:"push"`
 	WebSocket WebSocketConfig `mapstructure:"websocket"`
+	Message   MessageConfig   `mapstructure:"message"`
 	Security  SecurityConfig  `mapstructure:"security"`
 }
 
@@ -22,6 +25,7 @@ type ServerConfig struct {
 	Port   int    `mapstructure:"port"`
 	Mode   string `mapstructure:"mode"`
 	Domain string `mapstructure:"domain"`
+	NodeID string `mapstructure:"node_id"` // 集群节点ID，为空时自动生成
 }
 
 type DatabaseConfig struct {
@@ -49,13 +53,28 @@ type RedisConfig struct {
 }
 
 type KafkaConfig struct {
-	Brokers []string     `mapstructure:"brokers"`
-	Topic   TopicConfig  `mapstructure:"topic"`
+	Brokers       []string       `mapstructure:"brokers"`
+	Topic         TopicConfig    `mapstructure:"topic"`
+	Consumer      ConsumerConfig `mapstructure:"consumer"`
+	Outbox        OutboxConfig   `mapstructure:"outbox"`
+	AsyncDelivery bool           `mapstructure:"async_delivery"` // 为true时消息投递交给worker，API只负责落库和写Kafka
 }
 
 type TopicConfig struct {
 	Message      string `mapstructure:"message"`
 	Notification string `mapstructure:"notification"`
+	DeadLetter   string `mapstructure:"dead_letter"`
+}
+
+type OutboxConfig struct {
+	PollIntervalMs int `mapstructure:"poll_interval_ms"`
+	BatchSize      int `mapstructure:"batch_size"`
+	RetentionHours int `mapstructure:"retention_hours"` // 已投递事件保留时长
+}
+
+type ConsumerConfig struct {
+	GroupID    string `mapstructure:"group_id"`
+	MaxRetries int    `mapstructure:"max_retries"`
 }
 
 type JWTConfig struct {
@@ -64,6 +83,13 @@ type JWTConfig struct {
 	RefreshExpireHours int    `mapstructure:"refresh_expire_hours"`
 }
 
+// SessionConfig 设备登录会话策略
+type SessionConfig struct {
+	// 每类平台（mobile/desktop/web/unknown）同时在线的设备数上限，新登录挤掉同类中最早登录的设备
+	// 未配置或不大于0的类别不限制
+	MaxPerPlatform map[string]int `mapstructure:"max_per_platform"`
+}
+
 type StorageConfig struct {
 	COS COSConfig `mapstructure:"cos"`
 }
@@ -82,11 +108,30 @@ type TRTCConfig struct {
 	ExpireTime int    `mapstructure:"expire_time"`
 }
 
+// PushConfig 离线推送网关
+type PushConfig struct {
+	Endpoint  string `mapstructure:"endpoint"` // 为空时不发送离线推送
+	AppKey    string `mapstructure:"app_key"`
+	AppSecret string `mapstructure:"app_secret"`
+	TimeoutMs int    `mapstructure:"timeout_ms"`
+}
+
 type WebSocketConfig struct {
-	ReadBufferSize    int `mapstructure:"read_buffer_size"`
-	WriteBufferSize   int `mapstructure:"write_buffer_size"`
-	HeartbeatInterval int `mapstructure:"heartbeat_interval"`
-	MaxMessageSize    int `mapstructure:"max_message_size"`
+	ReadBufferSize          int  `mapstructure:"read_buffer_size"`
+	WriteBufferSize         int  `mapstructure:"write_buffer_size"`
+	HeartbeatInterval       int  `mapstructure:"heartbeat_interval"`
+	MaxMessageSize          int  `mapstructure:"max_message_size"`
+	ClusterEnabled          bool `mapstructure:"cluster_enabled"`            // 多节点部署时通过Redis转发推送
+	PresenceFlushIntervalMs int  `mapstructure:"presence_flush_interval_ms"` // 最后在线时间从Redis写回数据库的间隔
+}
+
+// MessageConfig 消息相关的业务规则
+type MessageConfig struct {
+	EditWindowSeconds       int `mapstructure:"edit_window_seconds"`        // 发送后允许编辑的时长
+	RecallWindowSeconds     int `mapstructure:"recall_window_seconds"`      // 发送者撤回自己消息的时限，群主/管理员不受限制
+	ScheduledPollIntervalMs int `mapstructure:"scheduled_poll_interval_ms"` // 定时消息调度器的轮询间隔
+	ReaperIntervalMs        int `mapstructure:"reaper_interval_ms"`         // 清理过期阅后即焚消息的间隔
+	MaxPinsPerConversation  int `mapstructure:"max_pins_per_conversation"`  // 每个会话最多置顶的消息数
 }
 
 type SecurityConfig struct {
@@ -142,10 +187,18 @@ func Load() *Config {
 	if secretKey := os.Getenv("COS_SECRET_KEY"); secretKey != "" {
 		config.Storage.COS.SecretKey = secretKey
 	}
+	// 集群节点ID（同一台机器运行多个实例时需区分）
+	if nodeID := os.Getenv("NODE_ID"); nodeID != "" {
+		config.Server.NodeID = nodeID
+	}
 	// 腾讯云TRTC配置
 	if secretKey := os.Getenv("TRTC_SECRET_KEY"); secretKey != "" {
 		config.TRTC.SecretKey = secretKey
 	}
+	// 离线推送网关
+	if secret := os.Getenv("PUSH_APP_SECRET"); secret != "" {
+		config.Push.AppSecret = secret
+	}
 
 	return &config
 }
@@ -1,14 +1,19 @@
 package api
 
 import (
+	"errors"
 	"log"
 	"net/http"
+	"strconv"
 	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/lanxin/im-backend/config"
+	"github.com/lanxin/im-backend/internal/middleware"
+	"github.com/lanxin/im-backend/internal/model"
 	"github.com/lanxin/im-backend/internal/pkg/redis"
 	"github.com/lanxin/im-backend/internal/service"
+	"github.com/lanxin/im-backend/internal/websocket"
 )
 
 type AuthHandler struct {
@@ -16,9 +21,9 @@ type AuthHandler struct {
 	cfg         *config.Config
 }
 
-func NewAuthHandler(cfg *config.Config) *AuthHandler {
+func NewAuthHandler(cfg *config.Config, hub *websocket.Hub) *AuthHandler {
 	return &AuthHandler{
-		authService: service.NewAuthService(cfg),
+		authService: service.NewAuthService(cfg, hub),
 		cfg:         cfg,
 	}
 }
@@ -65,6 +70,10 @@ func (h *AuthHandler) Login(c *gin.Context) {
 	var req struct {
 		Identifier string `json:"identifier" binding:"required"`
 		Password   string `json:"password" binding:"required"`
+		DeviceID   string `json:"device_id" binding:"max=64"`
+		DeviceName string `json:"device_name" binding:"max=100"`
+		Platform   string `json:"platform"` // android/ios/windows/macos/linux/web
+		AppVersion string `json:"app_version" binding:"max=32"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -76,7 +85,13 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
-	token, user, err := h.authService.Login(req.Identifier, req.Password)
+	token, user, session, err := h.authService.Login(req.Identifier, req.Password, service.DeviceInfo{
+		DeviceID:   req.DeviceID,
+		DeviceName: req.DeviceName,
+		Platform:   req.Platform,
+		AppVersion: req.AppVersion,
+		IP:         c.ClientIP(),
+	})
 	if err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{
 			"code":    401,
@@ -90,8 +105,9 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		"code":    0,
 		"message": "success",
 		"data": gin.H{
-			"token": token,
-			"user":  user.ToResponse(),
+			"token":      token,
+			"session_id": session.ID,
+			"user":       user.ToResponse(),
 		},
 	})
 }
@@ -109,7 +125,7 @@ func (h *AuthHandler) RefreshToken(c *gin.Context) {
 	}
 
 	oldToken := authHeader[7:] // 去除 "Bearer "
-	newToken, err := h.authService.RefreshToken(oldToken)
+	newToken, err := h.authService.RefreshToken(oldToken, c.ClientIP())
 	if err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{
 			"code":    401,
@@ -144,7 +160,15 @@ func (h *AuthHandler) Logout(c *gin.Context) {
 			}
 		}
 	}
-	
+
+	// 结束当前设备会话，该设备上的其他连接一并断开
+	if sessionID := middleware.GetSessionID(c); sessionID != 0 {
+		userID, _ := middleware.GetUserID(c)
+		if err := h.authService.RevokeSession(userID, sessionID, model.SessionRevokeLogout, c.ClientIP(), c.GetHeader("User-Agent")); err != nil {
+			log.Printf("Failed to revoke session %d: %v", sessionID, err)
+		}
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"code":    0,
 		"message": "Logged out successfully",
@@ -152,3 +176,66 @@ func (h *AuthHandler) Logout(c *gin.Context) {
 	})
 }
 
+// GetSessions 获取登录设备列表
+// GET /auth/sessions
+func (h *AuthHandler) GetSessions(c *gin.Context) {
+	userID, _ := middleware.GetUserID(c)
+
+	sessions, err := h.authService.GetSessions(userID, middleware.GetSessionID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "Failed to get sessions",
+			"data":    nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data": gin.H{
+			"sessions": sessions,
+		},
+	})
+}
+
+// RevokeSession 让指定设备下线
+// DELETE /auth/sessions/:id
+func (h *AuthHandler) RevokeSession(c *gin.Context) {
+	userID, _ := middleware.GetUserID(c)
+
+	sessionID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "Invalid session ID",
+			"data":    nil,
+		})
+		return
+	}
+
+	err = h.authService.RevokeSession(userID, uint(sessionID), model.SessionRevokeRevoked, c.ClientIP(), c.GetHeader("User-Agent"))
+	if errors.Is(err, service.ErrSessionRevokeIncomplete) {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "Failed to revoke session",
+			"data":    nil,
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": err.Error(),
+			"data":    nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "Session revoked",
+		"data":    nil,
+	})
+}
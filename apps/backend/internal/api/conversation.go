@@ -1,21 +1,34 @@
 package api
 
 import (
+	"errors"
+	"log"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/lanxin/im-backend/internal/dao"
 	"github.com/lanxin/im-backend/internal/middleware"
+	"github.com/lanxin/im-backend/internal/model"
+	"gorm.io/gorm"
 )
 
 type ConversationHandler struct {
 	conversationDAO *dao.ConversationDAO
+	memberDAO       *dao.ConversationMemberDAO
+	changeDAO       *dao.ConversationChangeDAO
+	messageDAO      *dao.MessageDAO
+	deletionDAO     *dao.MessageDeletionDAO
 }
 
 func NewConversationHandler() *ConversationHandler {
 	return &ConversationHandler{
 		conversationDAO: dao.NewConversationDAO(),
+		memberDAO:       dao.NewConversationMemberDAO(),
+		changeDAO:       dao.NewConversationChangeDAO(),
+		messageDAO:      dao.NewMessageDAO(),
+		deletionDAO:     dao.NewMessageDeletionDAO(),
 	}
 }
 
@@ -33,18 +46,68 @@ func (h *ConversationHandler) GetConversations(c *gin.Context) {
 		return
 	}
 
+	// 当前用户在各会话中的个人状态（设置、未读数、草稿）
+	conversationIDs := make([]uint, len(conversations))
+	for i, conv := range conversations {
+		conversationIDs[i] = conv.ID
+	}
+	members, err := h.memberDAO.GetByConversations(userID, conversationIDs)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": err.Error(),
+			"data":    nil,
+		})
+		return
+	}
+
+	// 最后一条消息被本人删除、已清空或已过期时，改为展示本人可见的最新消息
+	now := time.Now()
+	lastMessageIDs := make([]uint, 0, len(conversations))
+	for _, conv := range conversations {
+		if conv.LastMessage != nil {
+			lastMessageIDs = append(lastMessageIDs, conv.LastMessage.ID)
+		}
+	}
+	deleted, err := h.deletionDAO.GetDeletedIDs(userID, lastMessageIDs)
+	if err != nil {
+		deleted = map[uint]bool{}
+	}
+	for i := range conversations {
+		conv := &conversations[i]
+		if conv.LastMessage == nil {
+			continue
+		}
+		member, ok := members[conv.ID]
+		if deleted[conv.LastMessage.ID] || (ok && member.ClearedSeq >= conv.LastMessage.Seq) || conv.LastMessage.Expired(now) {
+			conv.LastMessage, _ = h.messageDAO.GetLatestVisible(userID, conv.ID)
+		}
+	}
+
 	// 转换为响应格式（包含完整数据）
 	items := make([]map[string]interface{}, len(conversations))
 	for i, conv := range conversations {
-		// ✅ 计算真实未读数（不再是硬编码0）
-		unreadCount := h.conversationDAO.GetUnreadCount(conv.ID, userID)
-
 		item := map[string]interface{}{
 			"id":           conv.ID,
 			"type":         conv.Type,
-			"unread_count": unreadCount, // ✅ 真实计算的未读数
 			"updated_at":   conv.UpdatedAt.Unix(),
 			"last_message": conv.LastMessage, // ✅ 完整的最后一条消息
+
+			"disappear_mode":    conv.DisappearMode,
+			"disappear_seconds": conv.DisappearSeconds,
+		}
+
+		if member, ok := members[conv.ID]; ok {
+			item["unread_count"] = member.UnreadCount
+			item["last_read_seq"] = member.LastReadSeq
+			item["cleared_seq"] = member.ClearedSeq
+			item["is_muted"] = member.IsMuted
+			item["is_top"] = member.IsTop
+			item["is_starred"] = member.IsStarred
+			item["is_blocked"] = member.IsBlocked
+			item["draft"] = member.Draft
+			item["mentioned"] = member.MentionMessageID != nil
+			item["mention_message_id"] = member.MentionMessageID
 		}
 
 		// ✅ 添加对方用户信息（单聊）
@@ -73,7 +136,8 @@ func (h *ConversationHandler) GetConversations(c *gin.Context) {
 
 // UpdateConversationSettings 更新会话设置
 // PUT /conversations/:id/settings
-// Body: {"is_muted": true, "is_top": false, "is_starred": true, "is_blocked": false}
+// Body: {"is_muted": true, "is_top": false, "is_starred": true, "is_blocked": false, "draft": "..."}
+// 设置只对当前用户生效
 func (h *ConversationHandler) UpdateConversationSettings(c *gin.Context) {
 	userID, _ := middleware.GetUserID(c)
 	conversationID, err := strconv.ParseUint(c.Param("id"), 10, 32)
@@ -87,10 +151,11 @@ func (h *ConversationHandler) UpdateConversationSettings(c *gin.Context) {
 	}
 
 	var req struct {
-		IsMuted   *bool `json:"is_muted"`
-		IsTop     *bool `json:"is_top"`
-		IsStarred *bool `json:"is_starred"`
-		IsBlocked *bool `json:"is_blocked"`
+		IsMuted   *bool   `json:"is_muted"`
+		IsTop     *bool   `json:"is_top"`
+		IsStarred *bool   `json:"is_starred"`
+		IsBlocked *bool   `json:"is_blocked"`
+		Draft     *string `json:"draft" binding:"omitempty,max=5000"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -116,6 +181,10 @@ func (h *ConversationHandler) UpdateConversationSettings(c *gin.Context) {
 	if req.IsBlocked != nil {
 		settings["is_blocked"] = *req.IsBlocked
 	}
+	if req.Draft != nil {
+		settings["draft"] = *req.Draft
+		settings["draft_updated_at"] = time.Now()
+	}
 
 	if len(settings) == 0 {
 		c.JSON(http.StatusBadRequest, gin.H{
@@ -127,7 +196,15 @@ func (h *ConversationHandler) UpdateConversationSettings(c *gin.Context) {
 	}
 
 	// 更新设置
-	if err := h.conversationDAO.UpdateSettings(uint(conversationID), userID, settings); err != nil {
+	if err := h.memberDAO.UpdateSettings(uint(conversationID), userID, settings); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"code":    404,
+				"message": "Conversation not found",
+				"data":    nil,
+			})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"code":    500,
 			"message": "Failed to update settings",
@@ -136,6 +213,11 @@ func (h *ConversationHandler) UpdateConversationSettings(c *gin.Context) {
 		return
 	}
 
+	// 用户的其他设备通过 /sync 获取新设置
+	if err := h.changeDAO.Record(uint(conversationID), userID, model.ChangeSettingsUpdated, nil); err != nil {
+		log.Printf("Failed to record settings change of conversation %d: %v", conversationID, err)
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"code":    0,
 		"message": "Settings updated successfully",
@@ -157,7 +239,16 @@ func (h *ConversationHandler) GetConversationSettings(c *gin.Context) {
 		return
 	}
 
-	settings, err := h.conversationDAO.GetConversationSettings(uint(conversationID), userID)
+	settings, err := h.memberDAO.Get(uint(conversationID), userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code":    404,
+			"message": "Conversation not found",
+			"data":    nil,
+		})
+		return
+	}
+	conv, err := h.conversationDAO.GetByID(uint(conversationID))
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{
 			"code":    404,
@@ -171,10 +262,19 @@ func (h *ConversationHandler) GetConversationSettings(c *gin.Context) {
 		"code":    0,
 		"message": "success",
 		"data": gin.H{
-			"is_muted":   settings.IsMuted,
-			"is_top":     settings.IsTop,
-			"is_starred": settings.IsStarred,
-			"is_blocked": settings.IsBlocked,
+			"is_muted":           settings.IsMuted,
+			"is_top":             settings.IsTop,
+			"is_starred":         settings.IsStarred,
+			"is_blocked":         settings.IsBlocked,
+			"unread_count":       settings.UnreadCount,
+			"last_read_seq":      settings.LastReadSeq,
+			"cleared_seq":        settings.ClearedSeq,
+			"draft":              settings.Draft,
+			"draft_updated_at":   settings.DraftUpdatedAt,
+			"mentioned":          settings.MentionMessageID != nil,
+			"mention_message_id": settings.MentionMessageID,
+			"disappear_mode":     conv.DisappearMode,
+			"disappear_seconds":  conv.DisappearSeconds,
 		},
 	})
 }
@@ -3,6 +3,7 @@ package api
 import (
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/lanxin/im-backend/internal/dao"
@@ -43,9 +44,9 @@ func (h *FavoriteHandler) CollectMessage(c *gin.Context) {
 		return
 	}
 	
-	// 检查消息是否存在
+	// 检查消息是否存在（已过期的阅后即焚消息视为不存在）
 	message, err := h.messageDAO.GetByID(req.MessageID)
-	if err != nil {
+	if err != nil || message.Expired(time.Now()) {
 		c.JSON(http.StatusNotFound, gin.H{
 			"code":    404,
 			"message": "Message not found",
@@ -1,10 +1,13 @@
 package api
 
 import (
+	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"github.com/lanxin/im-backend/config"
 	"github.com/lanxin/im-backend/internal/middleware"
 	"github.com/lanxin/im-backend/internal/service"
 	"github.com/lanxin/im-backend/internal/websocket"
@@ -14,9 +17,9 @@ type GroupHandler struct {
 	groupService *service.GroupService
 }
 
-func NewGroupHandler(hub *websocket.Hub) *GroupHandler {
+func NewGroupHandler(cfg *config.Config, hub *websocket.Hub) *GroupHandler {
 	return &GroupHandler{
-		groupService: service.NewGroupService(hub),
+		groupService: service.NewGroupService(cfg, hub),
 	}
 }
 
@@ -240,11 +243,18 @@ func (h *GroupHandler) SendGroupMessage(c *gin.Context) {
 	}
 
 	var req struct {
-		Content  string  `json:"content" binding:"required"`
-		Type     string  `json:"type"`
-		FileURL  *string `json:"file_url"`
-		FileSize *int64  `json:"file_size"`
-		Duration *int    `json:"duration"`
+		Content     string  `json:"content"` // 文本内容或摘要，位置、名片等类型由服务端生成
+		Type        string  `json:"type"`
+		FileURL     *string `json:"file_url"`
+		FileSize    *int64  `json:"file_size"`
+		Duration    *int    `json:"duration"`
+		ClientMsgID string  `json:"client_msg_id" binding:"max=64"` // 客户端生成的去重ID
+
+		Payload          json.RawMessage `json:"payload"`             // 结构化内容，格式由type决定
+		ReplyToMessageID *uint           `json:"reply_to_message_id"` // 引用回复的消息
+		ThreadRootID     *uint           `json:"thread_root_id"`      // 话题根消息
+		MentionUserIDs   []uint          `json:"mention_user_ids"`    // 被@的成员
+		MentionAll       bool            `json:"mention_all"`         // @所有人（群主/管理员）
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -268,8 +278,24 @@ func (h *GroupHandler) SendGroupMessage(c *gin.Context) {
 		req.FileURL,
 		req.FileSize,
 		req.Duration,
+		req.ClientMsgID,
+		service.SendOptions{
+			Payload:          req.Payload,
+			ReplyToMessageID: req.ReplyToMessageID,
+			ThreadRootID:     req.ThreadRootID,
+			MentionUserIDs:   req.MentionUserIDs,
+			MentionAll:       req.MentionAll,
+		},
 	)
 
+	if errors.Is(err, service.ErrClientMsgIDConflict) {
+		c.JSON(http.StatusConflict, gin.H{
+			"code":    409,
+			"message": err.Error(),
+			"data":    nil,
+		})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"code":    400,
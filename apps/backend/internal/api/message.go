@@ -1,23 +1,29 @@
 package api
 
 import (
+	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/lanxin/im-backend/config"
 	"github.com/lanxin/im-backend/internal/middleware"
 	"github.com/lanxin/im-backend/internal/service"
 	"github.com/lanxin/im-backend/internal/websocket"
-	"github.com/lanxin/im-backend/pkg/kafka"
 )
 
 type MessageHandler struct {
 	messageService *service.MessageService
+	forwardService *service.ForwardService
 }
 
-func NewMessageHandler(hub *websocket.Hub, producer *kafka.Producer) *MessageHandler {
+func NewMessageHandler(cfg *config.Config, hub *websocket.Hub) *MessageHandler {
 	return &MessageHandler{
-		messageService: service.NewMessageService(hub, producer),
+		messageService: service.NewMessageService(cfg, hub),
+		forwardService: service.NewForwardService(cfg, hub),
 	}
 }
 
@@ -26,12 +32,17 @@ func (h *MessageHandler) SendMessage(c *gin.Context) {
 	senderID, _ := middleware.GetUserID(c)
 
 	var req struct {
-		ReceiverID uint    `json:"receiver_id" binding:"required"`
-		Content    string  `json:"content" binding:"required"`
-		Type       string  `json:"type"` // text, image, voice, video, file
-		FileURL    *string `json:"file_url"`
-		FileSize   *int64  `json:"file_size"`
-		Duration   *int    `json:"duration"`
+		ReceiverID  uint    `json:"receiver_id" binding:"required"`
+		Content     string  `json:"content"` // 文本内容或摘要，位置、名片等类型由服务端生成
+		Type        string  `json:"type"`    // text, image, voice, video, file, location, card, sticker, rich_text
+		FileURL     *string `json:"file_url"`
+		FileSize    *int64  `json:"file_size"`
+		Duration    *int    `json:"duration"`
+		ClientMsgID string  `json:"client_msg_id" binding:"max=64"` // 客户端生成的去重ID
+
+		Payload          json.RawMessage `json:"payload"`             // 结构化内容，格式由type决定
+		ReplyToMessageID *uint           `json:"reply_to_message_id"` // 引用回复的消息
+		ThreadRootID     *uint           `json:"thread_root_id"`      // 话题根消息
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -58,10 +69,24 @@ func (h *MessageHandler) SendMessage(c *gin.Context) {
 		req.FileURL,
 		req.FileSize,
 		req.Duration,
+		req.ClientMsgID,
+		service.SendOptions{
+			Payload:          req.Payload,
+			ReplyToMessageID: req.ReplyToMessageID,
+			ThreadRootID:     req.ThreadRootID,
+		},
 		ip,
 		userAgent,
 	)
 
+	if errors.Is(err, service.ErrClientMsgIDConflict) {
+		c.JSON(http.StatusConflict, gin.H{
+			"code":    409,
+			"message": err.Error(),
+			"data":    nil,
+		})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"code":    400,
@@ -80,6 +105,51 @@ func (h *MessageHandler) SendMessage(c *gin.Context) {
 	})
 }
 
+// ForwardMessages 转发消息
+// POST /messages/forward
+// Body: {"message_ids": [1, 2], "mode": "single|merged", "title": "...", "user_ids": [3], "group_ids": [4]}
+func (h *MessageHandler) ForwardMessages(c *gin.Context) {
+	userID, _ := middleware.GetUserID(c)
+
+	var req struct {
+		MessageIDs []uint `json:"message_ids" binding:"required,min=1"`
+		Mode       string `json:"mode" binding:"omitempty,oneof=single merged"` // 默认逐条转发
+		Title      string `json:"title" binding:"max=100"`                      // 合并转发的标题，为空时自动生成
+		UserIDs    []uint `json:"user_ids"`
+		GroupIDs   []uint `json:"group_ids"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "Invalid request",
+			"data":    nil,
+		})
+		return
+	}
+
+	ip := c.ClientIP()
+	userAgent := c.GetHeader("User-Agent")
+
+	results, err := h.forwardService.ForwardMessages(userID, req.MessageIDs, req.Mode, req.Title, req.UserIDs, req.GroupIDs, ip, userAgent)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": err.Error(),
+			"data":    nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data": gin.H{
+			"results": results,
+		},
+	})
+}
+
 // RecallMessage 撤回消息
 func (h *MessageHandler) RecallMessage(c *gin.Context) {
 	userID, _ := middleware.GetUserID(c)
@@ -104,13 +174,204 @@ func (h *MessageHandler) RecallMessage(c *gin.Context) {
 	})
 }
 
+// EditMessage 编辑消息
+// PUT /messages/:id
+// Body: {"content": "..."}
+func (h *MessageHandler) EditMessage(c *gin.Context) {
+	userID, _ := middleware.GetUserID(c)
+	messageID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "Invalid message ID",
+			"data":    nil,
+		})
+		return
+	}
+
+	var req struct {
+		Content string `json:"content" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "Invalid request",
+			"data":    nil,
+		})
+		return
+	}
+
+	message, err := h.messageService.EditMessage(uint(messageID), userID, req.Content, c.ClientIP(), c.GetHeader("User-Agent"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": err.Error(),
+			"data":    nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data": gin.H{
+			"message": message,
+		},
+	})
+}
+
+// GetMessageEdits 获取消息的编辑历史
+// GET /messages/:id/edits
+func (h *MessageHandler) GetMessageEdits(c *gin.Context) {
+	userID, _ := middleware.GetUserID(c)
+	messageID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "Invalid message ID",
+			"data":    nil,
+		})
+		return
+	}
+
+	edits, err := h.messageService.GetMessageEdits(uint(messageID), userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": err.Error(),
+			"data":    nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data": gin.H{
+			"edits": edits,
+		},
+	})
+}
+
+// AddReaction 添加表情回应
+// POST /messages/:id/reactions
+// Body: {"emoji": "👍"}
+func (h *MessageHandler) AddReaction(c *gin.Context) {
+	userID, _ := middleware.GetUserID(c)
+	messageID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "Invalid message ID",
+			"data":    nil,
+		})
+		return
+	}
+
+	var req struct {
+		Emoji string `json:"emoji" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "Invalid request",
+			"data":    nil,
+		})
+		return
+	}
+
+	if err := h.messageService.AddReaction(uint(messageID), userID, req.Emoji); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": err.Error(),
+			"data":    nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    nil,
+	})
+}
+
+// RemoveReaction 移除表情回应
+// DELETE /messages/:id/reactions?emoji=👍
+func (h *MessageHandler) RemoveReaction(c *gin.Context) {
+	userID, _ := middleware.GetUserID(c)
+	messageID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "Invalid message ID",
+			"data":    nil,
+		})
+		return
+	}
+
+	if err := h.messageService.RemoveReaction(uint(messageID), userID, c.Query("emoji")); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": err.Error(),
+			"data":    nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    nil,
+	})
+}
+
+// GetThread 获取话题
+// GET /messages/:id/thread?after_seq=0&limit=50
+// 返回根消息和序号大于after_seq的回复，has_more为true时以最后一条回复的seq继续翻页
+func (h *MessageHandler) GetThread(c *gin.Context) {
+	userID, _ := middleware.GetUserID(c)
+	rootID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "Invalid message ID",
+			"data":    nil,
+		})
+		return
+	}
+	afterSeq, _ := strconv.ParseUint(c.DefaultQuery("after_seq", "0"), 10, 64)
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+
+	root, replies, hasMore, err := h.messageService.GetThread(uint(rootID), userID, afterSeq, limit)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": err.Error(),
+			"data":    nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data": gin.H{
+			"root":     root,
+			"replies":  replies,
+			"has_more": hasMore,
+		},
+	})
+}
+
 // GetMessages 获取消息历史
 func (h *MessageHandler) GetMessages(c *gin.Context) {
+	userID, _ := middleware.GetUserID(c)
 	conversationID, _ := strconv.ParseUint(c.Param("id"), 10, 32)
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
 	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "50"))
 
-	messages, total, err := h.messageService.GetMessages(uint(conversationID), page, pageSize)
+	messages, total, err := h.messageService.GetMessages(userID, uint(conversationID), page, pageSize)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"code":    500,
@@ -160,6 +421,8 @@ func (h *MessageHandler) MarkAsRead(c *gin.Context) {
 // 返回: 
 //   {code: 0, message: "success", data: {total: 20, messages: [...]}}
 func (h *MessageHandler) GetHistoryMessages(c *gin.Context) {
+	userID, _ := middleware.GetUserID(c)
+
 	// 解析路径参数
 	conversationID, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
@@ -177,6 +440,7 @@ func (h *MessageHandler) GetHistoryMessages(c *gin.Context) {
 	
 	// 调用Service层
 	messages, err := h.messageService.GetHistoryMessages(
+		userID,
 		uint(conversationID),
 		uint(beforeMessageID),
 		limit,
@@ -266,3 +530,329 @@ func (h *MessageHandler) GetOfflineMessages(c *gin.Context) {
 	})
 }
 
+
+// Sync 增量同步消息
+// GET /api/v1/sync?since=12:40,15:3&change_cursor=980&limit=200
+// 参数:
+//   - since (query): 客户端每个会话已有的最大seq，格式为 会话ID:seq，多个以逗号分隔
+//   - change_cursor (query): 上次同步返回的变更游标，首次同步不传
+//   - limit (query): 每个会话最多返回的消息数，默认200
+// 返回:
+//   {code: 0, message: "success", data: {conversations: [{conversation, messages, has_more}], changes, change_cursor, has_more_changes, changes_expired, server_time}}
+func (h *MessageHandler) Sync(c *gin.Context) {
+	userID, _ := middleware.GetUserID(c)
+
+	since, err := parseSinceSeq(c.Query("since"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "Invalid since parameter",
+			"data":    nil,
+		})
+		return
+	}
+	changeCursor, err := strconv.ParseUint(c.DefaultQuery("change_cursor", "0"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "Invalid change_cursor parameter",
+			"data":    nil,
+		})
+		return
+	}
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "200"))
+
+	result, err := h.messageService.Sync(userID, since, changeCursor, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": err.Error(),
+			"data":    nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data": gin.H{
+			"conversations":    result.Conversations,
+			"changes":          result.Changes,
+			"change_cursor":    result.ChangeCursor,
+			"has_more_changes": result.HasMoreChanges,
+			"changes_expired":  result.ChangesExpired,
+			"server_time":      time.Now().Unix(),
+		},
+	})
+}
+
+// parseSinceSeq 解析 "会话ID:seq,会话ID:seq" 格式的同步游标
+func parseSinceSeq(raw string) (map[uint]uint64, error) {
+	since := make(map[uint]uint64)
+	if raw == "" {
+		return since, nil
+	}
+
+	for _, pair := range strings.Split(raw, ",") {
+		parts := strings.SplitN(strings.TrimSpace(pair), ":", 2)
+		if len(parts) != 2 {
+			return nil, strconv.ErrSyntax
+		}
+		conversationID, err := strconv.ParseUint(parts[0], 10, 32)
+		if err != nil {
+			return nil, err
+		}
+		seq, err := strconv.ParseUint(parts[1], 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		since[uint(conversationID)] = seq
+	}
+
+	return since, nil
+}
+
+// DeleteMessage 删除消息
+// DELETE /messages/:id?scope=me
+// 目前只支持仅对自己删除，对所有人删除请使用撤回
+func (h *MessageHandler) DeleteMessage(c *gin.Context) {
+	userID, _ := middleware.GetUserID(c)
+	messageID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "Invalid message ID",
+			"data":    nil,
+		})
+		return
+	}
+
+	if scope := c.DefaultQuery("scope", "me"); scope != "me" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "unsupported scope, use recall to remove a message for everyone",
+			"data":    nil,
+		})
+		return
+	}
+
+	if err := h.messageService.DeleteMessageForMe(uint(messageID), userID, c.ClientIP(), c.GetHeader("User-Agent")); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": err.Error(),
+			"data":    nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    nil,
+	})
+}
+
+// ClearConversation 清空聊天记录（仅对自己）
+// POST /conversations/:id/clear
+func (h *MessageHandler) ClearConversation(c *gin.Context) {
+	userID, _ := middleware.GetUserID(c)
+	conversationID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "Invalid conversation ID",
+			"data":    nil,
+		})
+		return
+	}
+
+	if err := h.messageService.ClearConversation(uint(conversationID), userID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": err.Error(),
+			"data":    nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    nil,
+	})
+}
+
+// HideConversation 从会话列表中删除会话，收到新消息后重新出现
+// DELETE /conversations/:id
+func (h *MessageHandler) HideConversation(c *gin.Context) {
+	userID, _ := middleware.GetUserID(c)
+	conversationID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "Invalid conversation ID",
+			"data":    nil,
+		})
+		return
+	}
+
+	if err := h.messageService.HideConversation(uint(conversationID), userID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": err.Error(),
+			"data":    nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    nil,
+	})
+}
+
+// SetDisappearing 修改会话的阅后即焚设置
+// PUT /conversations/:id/disappearing
+// Body: {"mode": "off|after_read|after_send", "seconds": 30}
+func (h *MessageHandler) SetDisappearing(c *gin.Context) {
+	userID, _ := middleware.GetUserID(c)
+	conversationID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "Invalid conversation ID",
+			"data":    nil,
+		})
+		return
+	}
+
+	var req struct {
+		Mode    string `json:"mode" binding:"required,oneof=off after_read after_send"`
+		Seconds int    `json:"seconds"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "Invalid request",
+			"data":    nil,
+		})
+		return
+	}
+
+	conv, err := h.messageService.SetDisappearing(uint(conversationID), userID, req.Mode, req.Seconds)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": err.Error(),
+			"data":    nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data": gin.H{
+			"conversation_id":   conv.ID,
+			"disappear_mode":    conv.DisappearMode,
+			"disappear_seconds": conv.DisappearSeconds,
+		},
+	})
+}
+
+// PinMessage 置顶消息
+// POST /messages/:id/pin
+func (h *MessageHandler) PinMessage(c *gin.Context) {
+	userID, _ := middleware.GetUserID(c)
+	messageID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "Invalid message ID",
+			"data":    nil,
+		})
+		return
+	}
+
+	pin, err := h.messageService.PinMessage(uint(messageID), userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": err.Error(),
+			"data":    nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data": gin.H{
+			"pin": pin,
+		},
+	})
+}
+
+// UnpinMessage 取消置顶
+// DELETE /messages/:id/pin
+func (h *MessageHandler) UnpinMessage(c *gin.Context) {
+	userID, _ := middleware.GetUserID(c)
+	messageID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "Invalid message ID",
+			"data":    nil,
+		})
+		return
+	}
+
+	if err := h.messageService.UnpinMessage(uint(messageID), userID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": err.Error(),
+			"data":    nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    nil,
+	})
+}
+
+// GetPins 获取会话的置顶消息
+// GET /conversations/:id/pins
+func (h *MessageHandler) GetPins(c *gin.Context) {
+	userID, _ := middleware.GetUserID(c)
+	conversationID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "Invalid conversation ID",
+			"data":    nil,
+		})
+		return
+	}
+
+	pins, err := h.messageService.GetPins(uint(conversationID), userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": err.Error(),
+			"data":    nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data": gin.H{
+			"pins": pins,
+		},
+	})
+}
@@ -0,0 +1,165 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lanxin/im-backend/internal/middleware"
+	"github.com/lanxin/im-backend/internal/service"
+)
+
+type PresenceHandler struct {
+	presenceService *service.PresenceService
+}
+
+// NewPresenceHandler 使用main中启动的在线状态服务，状态变化都由同一个实例推送
+func NewPresenceHandler(presenceService *service.PresenceService) *PresenceHandler {
+	return &PresenceHandler{
+		presenceService: presenceService,
+	}
+}
+
+// GetPresence 批量查询用户在线状态
+// GET /users/presence?ids=1,2,3
+func (h *PresenceHandler) GetPresence(c *gin.Context) {
+	userID, _ := middleware.GetUserID(c)
+
+	var ids []uint
+	for _, part := range strings.Split(c.Query("ids"), ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		id, err := strconv.ParseUint(part, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    400,
+				"message": "Invalid user ID: " + part,
+				"data":    nil,
+			})
+			return
+		}
+		ids = append(ids, uint(id))
+	}
+	if len(ids) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "ids required",
+			"data":    nil,
+		})
+		return
+	}
+
+	list, err := h.presenceService.GetPresence(userID, ids)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": err.Error(),
+			"data":    nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data": gin.H{
+			"presence": list,
+		},
+	})
+}
+
+// SetCustomStatus 设置自定义状态
+// PUT /users/me/status
+// Body: {"text": "开会中", "expires_in": 3600}
+func (h *PresenceHandler) SetCustomStatus(c *gin.Context) {
+	userID, _ := middleware.GetUserID(c)
+
+	var req struct {
+		Text      string `json:"text" binding:"required"`
+		ExpiresIn int    `json:"expires_in"` // 秒，0表示不过期
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "Invalid request",
+			"data":    nil,
+		})
+		return
+	}
+
+	presence, err := h.presenceService.SetCustomStatus(userID, req.Text, req.ExpiresIn)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": err.Error(),
+			"data":    nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    presence,
+	})
+}
+
+// ClearCustomStatus 清除自定义状态
+// DELETE /users/me/status
+func (h *PresenceHandler) ClearCustomStatus(c *gin.Context) {
+	userID, _ := middleware.GetUserID(c)
+
+	presence, err := h.presenceService.SetCustomStatus(userID, "", 0)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "Failed to clear custom status",
+			"data":    nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    presence,
+	})
+}
+
+// UpdatePrivacy 修改在线状态相关的隐私设置
+// PUT /users/me/privacy
+// Body: {"hide_last_seen": true}
+func (h *PresenceHandler) UpdatePrivacy(c *gin.Context) {
+	userID, _ := middleware.GetUserID(c)
+
+	var req struct {
+		HideLastSeen *bool `json:"hide_last_seen" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "Invalid request",
+			"data":    nil,
+		})
+		return
+	}
+
+	presence, err := h.presenceService.SetHideLastSeen(userID, *req.HideLastSeen)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "Failed to update privacy settings",
+			"data":    nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    presence,
+	})
+}
@@ -0,0 +1,189 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lanxin/im-backend/internal/middleware"
+	"github.com/lanxin/im-backend/internal/service"
+)
+
+type ScheduledMessageHandler struct {
+	scheduledService *service.ScheduledMessageService
+}
+
+func NewScheduledMessageHandler() *ScheduledMessageHandler {
+	return &ScheduledMessageHandler{
+		scheduledService: service.NewScheduledMessageService(),
+	}
+}
+
+// scheduledMessageRequest 创建/修改定时消息的请求体，修改时忽略receiver_id和group_id
+type scheduledMessageRequest struct {
+	ReceiverID     *uint           `json:"receiver_id"` // 单聊接收者，与group_id二选一
+	GroupID        *uint           `json:"group_id"`    // 群聊
+	Content        string          `json:"content"`
+	Type           string          `json:"type"` // 与立即发送支持的类型相同
+	FileURL        string          `json:"file_url"`
+	FileSize       int64           `json:"file_size"`
+	Duration       int             `json:"duration"`
+	Payload        json.RawMessage `json:"payload"`
+	MentionUserIDs []uint          `json:"mention_user_ids"`
+	MentionAll     bool            `json:"mention_all"`
+	ScheduledAt    time.Time       `json:"scheduled_at" binding:"required"` // RFC3339，如 2024-01-02T09:00:00+08:00
+}
+
+func (r *scheduledMessageRequest) input() service.ScheduledMessageInput {
+	return service.ScheduledMessageInput{
+		ReceiverID:     r.ReceiverID,
+		GroupID:        r.GroupID,
+		Content:        r.Content,
+		Type:           r.Type,
+		FileURL:        r.FileURL,
+		FileSize:       r.FileSize,
+		Duration:       r.Duration,
+		Payload:        r.Payload,
+		MentionUserIDs: r.MentionUserIDs,
+		MentionAll:     r.MentionAll,
+		ScheduledAt:    r.ScheduledAt,
+	}
+}
+
+// CreateScheduledMessage 创建定时消息
+// POST /messages/scheduled
+func (h *ScheduledMessageHandler) CreateScheduledMessage(c *gin.Context) {
+	userID, _ := middleware.GetUserID(c)
+
+	var req scheduledMessageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "Invalid request",
+			"data":    nil,
+		})
+		return
+	}
+
+	scheduled, err := h.scheduledService.Create(userID, req.input(), c.ClientIP(), c.GetHeader("User-Agent"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": err.Error(),
+			"data":    nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data": gin.H{
+			"scheduled_message": scheduled,
+		},
+	})
+}
+
+// GetScheduledMessages 获取定时消息列表
+// GET /messages/scheduled?status=pending
+func (h *ScheduledMessageHandler) GetScheduledMessages(c *gin.Context) {
+	userID, _ := middleware.GetUserID(c)
+
+	status := c.DefaultQuery("status", "pending")
+	if status == "all" {
+		status = ""
+	}
+
+	list, err := h.scheduledService.List(userID, status)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "Failed to get scheduled messages",
+			"data":    nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data": gin.H{
+			"scheduled_messages": list,
+		},
+	})
+}
+
+// UpdateScheduledMessage 修改待发送的定时消息
+// PUT /messages/scheduled/:id
+func (h *ScheduledMessageHandler) UpdateScheduledMessage(c *gin.Context) {
+	userID, _ := middleware.GetUserID(c)
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "Invalid scheduled message ID",
+			"data":    nil,
+		})
+		return
+	}
+
+	var req scheduledMessageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "Invalid request",
+			"data":    nil,
+		})
+		return
+	}
+
+	scheduled, err := h.scheduledService.Update(uint(id), userID, req.input(), c.ClientIP(), c.GetHeader("User-Agent"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": err.Error(),
+			"data":    nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data": gin.H{
+			"scheduled_message": scheduled,
+		},
+	})
+}
+
+// CancelScheduledMessage 取消待发送的定时消息
+// DELETE /messages/scheduled/:id
+func (h *ScheduledMessageHandler) CancelScheduledMessage(c *gin.Context) {
+	userID, _ := middleware.GetUserID(c)
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "Invalid scheduled message ID",
+			"data":    nil,
+		})
+		return
+	}
+
+	if err := h.scheduledService.Cancel(uint(id), userID, c.ClientIP(), c.GetHeader("User-Agent")); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": err.Error(),
+			"data":    nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    nil,
+	})
+}
@@ -0,0 +1,184 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/lanxin/im-backend/config"
+	"github.com/lanxin/im-backend/internal/model"
+	"github.com/lanxin/im-backend/internal/service"
+	"github.com/lanxin/im-backend/internal/websocket"
+)
+
+// WSFrameHandler 处理客户端通过WebSocket上行的业务帧
+// 与REST接口共用MessageService/GroupService，行为保持一致
+type WSFrameHandler struct {
+	messageService *service.MessageService
+	groupService   *service.GroupService
+}
+
+func NewWSFrameHandler(cfg *config.Config, hub *websocket.Hub) *WSFrameHandler {
+	return &WSFrameHandler{
+		messageService: service.NewMessageService(cfg, hub),
+		groupService:   service.NewGroupService(cfg, hub),
+	}
+}
+
+// HandleFrame 实现websocket.UpstreamHandler
+func (h *WSFrameHandler) HandleFrame(ctx *websocket.FrameContext, frame *websocket.ClientFrame) (interface{}, error) {
+	switch frame.Type {
+	case websocket.FrameTypeSend:
+		return h.handleSend(ctx, frame.Data)
+	case websocket.FrameTypeAck:
+		return h.handleAck(ctx, frame.Data)
+	case websocket.FrameTypeRead:
+		return h.handleRead(ctx, frame.Data)
+	case websocket.FrameTypeTyping, websocket.FrameTypeRecordingVoice:
+		return h.handleSignal(ctx, frame.Type, frame.Data)
+	case websocket.FrameTypeRecall:
+		return h.handleRecall(ctx, frame.Data)
+	default:
+		return nil, errors.New("unsupported frame type")
+	}
+}
+
+// handleSend 发送消息
+// data: {"receiver_id": 2 | "group_id": 3, "content": "...", "type": "text", "client_msg_id": "...",
+//        "payload": {...}, "reply_to_message_id": 10, "thread_root_id": 8, "mention_user_ids": [5], "mention_all": false}
+func (h *WSFrameHandler) handleSend(ctx *websocket.FrameContext, raw json.RawMessage) (interface{}, error) {
+	var req struct {
+		ReceiverID  uint    `json:"receiver_id"`
+		GroupID     uint    `json:"group_id"`
+		Content     string  `json:"content"`
+		Type        string  `json:"type"`
+		FileURL     *string `json:"file_url"`
+		FileSize    *int64  `json:"file_size"`
+		Duration    *int    `json:"duration"`
+		ClientMsgID string  `json:"client_msg_id"`
+
+		Payload          json.RawMessage `json:"payload"`
+		ReplyToMessageID *uint           `json:"reply_to_message_id"`
+		ThreadRootID     *uint           `json:"thread_root_id"`
+		MentionUserIDs   []uint          `json:"mention_user_ids"`
+		MentionAll       bool            `json:"mention_all"`
+	}
+	if err := json.Unmarshal(raw, &req); err != nil {
+		return nil, errors.New("invalid send frame")
+	}
+	if len(req.ClientMsgID) > 64 {
+		return nil, errors.New("client_msg_id too long")
+	}
+	if req.Type == "" {
+		req.Type = model.MessageTypeText
+	}
+
+	opts := service.SendOptions{
+		Payload:          req.Payload,
+		ReplyToMessageID: req.ReplyToMessageID,
+		ThreadRootID:     req.ThreadRootID,
+		MentionUserIDs:   req.MentionUserIDs,
+		MentionAll:       req.MentionAll,
+	}
+
+	var message *model.Message
+	var err error
+	switch {
+	case req.GroupID != 0:
+		message, err = h.groupService.SendGroupMessage(
+			req.GroupID,
+			ctx.UserID,
+			req.Content,
+			req.Type,
+			req.FileURL,
+			req.FileSize,
+			req.Duration,
+			req.ClientMsgID,
+			opts,
+		)
+	case req.ReceiverID != 0:
+		message, err = h.messageService.SendMessage(
+			ctx.UserID,
+			req.ReceiverID,
+			req.Content,
+			req.Type,
+			req.FileURL,
+			req.FileSize,
+			req.Duration,
+			req.ClientMsgID,
+			opts,
+			ctx.IP,
+			ctx.UserAgent,
+		)
+	default:
+		return nil, errors.New("receiver_id or group_id required")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{
+		"message_id":      message.ID,
+		"conversation_id": message.ConversationID,
+		"seq":             message.Seq,
+		"client_msg_id":   message.ClientMsgID,
+		"created_at":      message.CreatedAt,
+	}, nil
+}
+
+// handleAck 确认收到消息
+// data: {"message_ids": [1, 2, 3]}
+func (h *WSFrameHandler) handleAck(ctx *websocket.FrameContext, raw json.RawMessage) (interface{}, error) {
+	var req struct {
+		MessageIDs []uint `json:"message_ids"`
+	}
+	if err := json.Unmarshal(raw, &req); err != nil {
+		return nil, errors.New("invalid ack frame")
+	}
+
+	return nil, h.messageService.AckDelivered(ctx.UserID, req.MessageIDs)
+}
+
+// handleRead 标记会话已读
+// data: {"conversation_id": 1}
+func (h *WSFrameHandler) handleRead(ctx *websocket.FrameContext, raw json.RawMessage) (interface{}, error) {
+	var req struct {
+		ConversationID uint `json:"conversation_id"`
+	}
+	if err := json.Unmarshal(raw, &req); err != nil || req.ConversationID == 0 {
+		return nil, errors.New("invalid read frame")
+	}
+
+	return nil, h.messageService.MarkAsRead(req.ConversationID, ctx.UserID)
+}
+
+// handleSignal 正在输入/正在录音，只转发不落库
+// data: {"conversation_id": 1, "state": "start" | "stop"}，state缺省为start
+func (h *WSFrameHandler) handleSignal(ctx *websocket.FrameContext, signal string, raw json.RawMessage) (interface{}, error) {
+	var req struct {
+		ConversationID uint   `json:"conversation_id"`
+		State          string `json:"state"`
+	}
+	if err := json.Unmarshal(raw, &req); err != nil || req.ConversationID == 0 {
+		return nil, errors.New("invalid " + signal + " frame")
+	}
+
+	return nil, h.messageService.RelaySignal(ctx.UserID, req.ConversationID, signal, req.State)
+}
+
+// handleRecall 撤回消息
+// data: {"message_id": 1}
+func (h *WSFrameHandler) handleRecall(ctx *websocket.FrameContext, raw json.RawMessage) (interface{}, error) {
+	var req struct {
+		MessageID uint `json:"message_id"`
+	}
+	if err := json.Unmarshal(raw, &req); err != nil || req.MessageID == 0 {
+		return nil, errors.New("invalid recall frame")
+	}
+
+	if err := h.messageService.RecallMessage(req.MessageID, ctx.UserID, ctx.IP, ctx.UserAgent); err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"message_id": req.MessageID,
+	}, nil
+}
@@ -87,3 +87,37 @@ func (d *ContactDAO) CheckExists(userID, contactID uint) bool {
 		Count(&count)
 	return count > 0
 }
+
+// GetWatcherIDs 获取把该用户加为联系人、且未被该用户拉黑的用户ID（在线状态变化的推送对象）
+func (d *ContactDAO) GetWatcherIDs(userID uint) ([]uint, error) {
+	blocked := d.db.Model(&model.Contact{}).
+		Select("contact_id").
+		Where("user_id = ? AND status = ?", userID, model.ContactStatusBlocked)
+
+	var ids []uint
+	err := d.db.Model(&model.Contact{}).
+		Where("contact_id = ? AND status = ?", userID, model.ContactStatusNormal).
+		Where("user_id NOT IN (?)", blocked).
+		Distinct().
+		Pluck("user_id", &ids).Error
+	return ids, err
+}
+
+// GetWatchedIDs 获取userIDs中viewerID可以查看在线状态的用户：viewerID把对方加为联系人，且未被对方拉黑
+// 与GetWatcherIDs相对，查询和推送的可见范围一致
+func (d *ContactDAO) GetWatchedIDs(viewerID uint, userIDs []uint) ([]uint, error) {
+	var ids []uint
+	if len(userIDs) == 0 {
+		return ids, nil
+	}
+	blockers := d.db.Model(&model.Contact{}).
+		Select("user_id").
+		Where("user_id IN ? AND contact_id = ? AND status = ?", userIDs, viewerID, model.ContactStatusBlocked)
+
+	err := d.db.Model(&model.Contact{}).
+		Where("user_id = ? AND contact_id IN ? AND status = ?", viewerID, userIDs, model.ContactStatusNormal).
+		Where("contact_id NOT IN (?)", blockers).
+		Distinct().
+		Pluck("contact_id", &ids).Error
+	return ids, err
+}
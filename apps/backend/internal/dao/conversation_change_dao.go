@@ -0,0 +1,82 @@
+package dao
+
+import (
+	"time"
+
+	"github.com/lanxin/im-backend/internal/model"
+	"github.com/lanxin/im-backend/internal/pkg/mysql"
+	"gorm.io/gorm"
+)
+
+// ConversationChangeDAO 会话变更日志
+type ConversationChangeDAO struct {
+	db *gorm.DB
+}
+
+func NewConversationChangeDAO() *ConversationChangeDAO {
+	return &ConversationChangeDAO{
+		db: mysql.GetDB(),
+	}
+}
+
+// WithTx 返回使用指定事务的DAO
+func (d *ConversationChangeDAO) WithTx(tx *gorm.DB) *ConversationChangeDAO {
+	return &ConversationChangeDAO{db: tx}
+}
+
+// Record 写入一条变更，userID为0表示对会话所有参与者可见
+func (d *ConversationChangeDAO) Record(conversationID, userID uint, kind string, messageID *uint) error {
+	return d.db.Create(&model.ConversationChange{
+		ConversationID: conversationID,
+		UserID:         userID,
+		Kind:           kind,
+		MessageID:      messageID,
+	}).Error
+}
+
+// RecordMessages 为多条消息写入同一类变更
+func (d *ConversationChangeDAO) RecordMessages(conversationID uint, kind string, messageIDs []uint) error {
+	if len(messageIDs) == 0 {
+		return nil
+	}
+	changes := make([]model.ConversationChange, len(messageIDs))
+	for i := range messageIDs {
+		changes[i] = model.ConversationChange{
+			ConversationID: conversationID,
+			Kind:           kind,
+			MessageID:      &messageIDs[i],
+		}
+	}
+	return d.db.Create(&changes).Error
+}
+
+// ListSince 获取用户在这些会话中ID大于afterID、写入时间早于before的变更，按ID正序
+func (d *ConversationChangeDAO) ListSince(userID uint, conversationIDs []uint, afterID uint64, before time.Time, limit int) ([]model.ConversationChange, error) {
+	var changes []model.ConversationChange
+	if len(conversationIDs) == 0 {
+		return changes, nil
+	}
+	err := d.db.Where("id > ? AND created_at < ? AND conversation_id IN ? AND user_id IN ?", afterID, before, conversationIDs, []uint{0, userID}).
+		Order("id ASC").
+		Limit(limit).
+		Find(&changes).Error
+	return changes, err
+}
+
+// Bounds 返回日志中最小和最大的变更ID，日志为空时都为0
+func (d *ConversationChangeDAO) Bounds() (minID, maxID uint64, err error) {
+	var bounds struct {
+		MinID uint64
+		MaxID uint64
+	}
+	err = d.db.Model(&model.ConversationChange{}).
+		Select("COALESCE(MIN(id), 0) AS min_id, COALESCE(MAX(id), 0) AS max_id").
+		Scan(&bounds).Error
+	return bounds.MinID, bounds.MaxID, err
+}
+
+// PruneBefore 删除早于before的变更，每次最多limit条，返回删除的条数
+func (d *ConversationChangeDAO) PruneBefore(before time.Time, limit int) (int64, error) {
+	result := d.db.Where("created_at < ?", before).Limit(limit).Delete(&model.ConversationChange{})
+	return result.RowsAffected, result.Error
+}
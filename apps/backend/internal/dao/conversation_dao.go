@@ -6,6 +6,7 @@ import (
 	"github.com/lanxin/im-backend/internal/model"
 	"github.com/lanxin/im-backend/internal/pkg/mysql"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type ConversationDAO struct {
@@ -18,79 +19,79 @@ func NewConversationDAO() *ConversationDAO {
 	}
 }
 
-// GetUserConversations 获取用户的所有会话（含完整关联数据）
-func (d *ConversationDAO) GetUserConversations(userID uint) ([]model.Conversation, error) {
-	var conversations []model.Conversation
-	err := d.db.Where("user1_id = ? OR user2_id = ?", userID, userID).
-		Preload("User1").              // 加载User1完整信息
-		Preload("User2").              // 加载User2完整信息
-		Preload("Group").              // 加载Group信息（如果是群聊）
-		Preload("LastMessage").        // ✅ 加载最后一条消息
-		Preload("LastMessage.Sender"). // ✅ 加载消息发送者信息
-		Order("updated_at DESC").      // 按更新时间倒序
-		Find(&conversations).Error
-	return conversations, err
+// WithTx 返回使用指定事务的DAO
+func (d *ConversationDAO) WithTx(tx *gorm.DB) *ConversationDAO {
+	return &ConversationDAO{db: tx}
 }
 
-// Create 创建会话
-func (d *ConversationDAO) Create(conversation *model.Conversation) error {
-	return d.db.Create(conversation).Error
+// GetByID 根据ID获取会话
+func (d *ConversationDAO) GetByID(id uint) (*model.Conversation, error) {
+	var conv model.Conversation
+	err := d.db.Where("id = ?", id).First(&conv).Error
+	if err != nil {
+		return nil, err
+	}
+	return &conv, nil
 }
 
-// GetUnreadCount 获取会话的未读消息数量
-//
-// 参数说明：
-//
-//	conversationID - 会话ID
-//	userID - 当前用户ID（作为接收者）
-//
-// 返回说明：
-//
-//	返回该会话中，接收者为userID且状态不为"read"的消息数量
-//
-// 使用场景：
-//
-//	在会话列表中显示未读徽章
-func (d *ConversationDAO) GetUnreadCount(conversationID, userID uint) int {
-	var count int64
-
-	// 统计条件：
-	// 1. 属于该会话
-	// 2. 接收者是当前用户
-	// 3. 状态不是"read"（包括sent和delivered）
-	d.db.Model(&model.Message{}).
-		Where("conversation_id = ? AND receiver_id = ? AND status != ?",
-			conversationID,
-			userID,
-			model.MessageStatusRead).
-		Count(&count)
-
-	return int(count)
+// Lock 锁定会话行，串行化同一会话中需要先检查再写入的操作（必须在事务中调用）
+func (d *ConversationDAO) Lock(id uint) error {
+	var conv model.Conversation
+	return d.db.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Select("id").
+		Where("id = ?", id).
+		First(&conv).Error
 }
 
-// UpdateSettings 更新会话设置
-func (d *ConversationDAO) UpdateSettings(conversationID, userID uint, settings map[string]interface{}) error {
-	// 验证会话属于当前用户
+// GetByGroupID 获取群组的会话
+func (d *ConversationDAO) GetByGroupID(groupID uint) (*model.Conversation, error) {
 	var conv model.Conversation
-	err := d.db.Where("id = ? AND (user1_id = ? OR user2_id = ?)", conversationID, userID, userID).
+	err := d.db.Where("type = ? AND group_id = ?", model.ConversationTypeGroup, groupID).
 		First(&conv).Error
 	if err != nil {
-		return err
+		return nil, err
 	}
+	return &conv, nil
+}
 
-	// 更新设置
-	return d.db.Model(&model.Conversation{}).
-		Where("id = ?", conversationID).
-		Updates(settings).Error
+// GetUserConversations 获取用户会话列表中的会话（含完整关联数据）
+// 单聊和所在群的群聊都通过conversation_members关联，已隐藏的会话不返回，置顶的排在前面
+func (d *ConversationDAO) GetUserConversations(userID uint) ([]model.Conversation, error) {
+	var conversations []model.Conversation
+	err := d.db.
+		Select("conversations.*").
+		Joins("JOIN conversation_members cm ON cm.conversation_id = conversations.id AND cm.user_id = ?", userID).
+		Where("cm.is_hidden = ?", false).
+		Preload("User1").                       // 加载User1完整信息
+		Preload("User2").                       // 加载User2完整信息
+		Preload("Group").                       // 加载Group信息（如果是群聊）
+		Preload("LastMessage").                 // ✅ 加载最后一条消息
+		Preload("LastMessage.Sender").          // ✅ 加载消息发送者信息
+		Order("cm.is_top DESC").                // 置顶会话在前
+		Order("conversations.updated_at DESC"). // 按更新时间倒序
+		Find(&conversations).Error
+	return conversations, err
 }
 
-// GetConversationSettings 获取会话设置
-func (d *ConversationDAO) GetConversationSettings(conversationID, userID uint) (*model.Conversation, error) {
-	var conv model.Conversation
-	err := d.db.Where("id = ? AND (user1_id = ? OR user2_id = ?)", conversationID, userID, userID).
-		Select("is_muted", "is_top", "is_starred", "is_blocked").
-		First(&conv).Error
-	return &conv, err
+// GetSyncConversations 获取用户参与的所有会话（单聊和所在群的群聊），用于增量同步
+func (d *ConversationDAO) GetSyncConversations(userID uint) ([]model.Conversation, error) {
+	var conversations []model.Conversation
+	err := d.db.
+		Where("(type = ? AND (user1_id = ? OR user2_id = ?)) OR (type = ? AND group_id IN (?))",
+			model.ConversationTypeSingle, userID, userID,
+			model.ConversationTypeGroup,
+			d.db.Model(&model.GroupMember{}).Select("group_id").Where("user_id = ?", userID),
+		).
+		Preload("User1").
+		Preload("User2").
+		Preload("Group").
+		Find(&conversations).Error
+	return conversations, err
+}
+
+// Create 创建会话
+func (d *ConversationDAO) Create(conversation *model.Conversation) error {
+	return d.db.Create(conversation).Error
 }
 
 // GetOrCreateSingleConversation 获取或创建单聊会话
@@ -194,3 +195,30 @@ func (d *ConversationDAO) GetOrCreateGroupConversation(groupID uint) (uint, erro
 	
 	return newConv.ID, nil
 }
+
+// UpdateDisappearing 更新会话的阅后即焚设置
+func (d *ConversationDAO) UpdateDisappearing(conversationID uint, mode string, seconds int) error {
+	return d.db.Model(&model.Conversation{}).
+		Where("id = ?", conversationID).
+		Updates(map[string]interface{}{
+			"disappear_mode":    mode,
+			"disappear_seconds": seconds,
+		}).Error
+}
+
+// ResetLastMessage 最后一条消息被清理后，改为指向会话中剩余的最新消息，最后消息时间随之回退
+// 只在last_message_id属于purgedIDs时更新，不影响期间写入的新消息
+func (d *ConversationDAO) ResetLastMessage(conversationID uint, purgedIDs []uint) error {
+	return d.db.Model(&model.Conversation{}).
+		Where("id = ? AND last_message_id IN ?", conversationID, purgedIDs).
+		Updates(map[string]interface{}{
+			"last_message_id": gorm.Expr(
+				"(SELECT m.id FROM messages m WHERE m.conversation_id = ? AND m.deleted_at IS NULL ORDER BY m.seq DESC LIMIT 1)",
+				conversationID,
+			),
+			"last_message_at": gorm.Expr(
+				"(SELECT m.created_at FROM messages m WHERE m.conversation_id = ? AND m.deleted_at IS NULL ORDER BY m.seq DESC LIMIT 1)",
+				conversationID,
+			),
+		}).Error
+}
@@ -0,0 +1,181 @@
+package dao
+
+import (
+	"github.com/lanxin/im-backend/internal/model"
+	"github.com/lanxin/im-backend/internal/pkg/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ConversationMemberDAO 用户会话状态（设置、未读数、已读位置、草稿）
+type ConversationMemberDAO struct {
+	db *gorm.DB
+}
+
+func NewConversationMemberDAO() *ConversationMemberDAO {
+	return &ConversationMemberDAO{
+		db: mysql.GetDB(),
+	}
+}
+
+// WithTx 返回使用指定事务的DAO
+func (d *ConversationMemberDAO) WithTx(tx *gorm.DB) *ConversationMemberDAO {
+	return &ConversationMemberDAO{db: tx}
+}
+
+// EnsureMembers 为会话参与者创建状态行，已存在的保持不变
+func (d *ConversationMemberDAO) EnsureMembers(conversationID uint, userIDs ...uint) error {
+	return d.JoinMembers(conversationID, 0, userIDs...)
+}
+
+// JoinMembers 为新加入的参与者创建状态行，已读位置从readSeq开始（加入前的消息不计未读）
+func (d *ConversationMemberDAO) JoinMembers(conversationID uint, readSeq uint64, userIDs ...uint) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+
+	members := make([]model.ConversationMember, len(userIDs))
+	for i, userID := range userIDs {
+		members[i] = model.ConversationMember{
+			ConversationID: conversationID,
+			UserID:         userID,
+			LastReadSeq:    readSeq,
+		}
+	}
+
+	return d.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&members).Error
+}
+
+// RemoveMembers 删除参与者的状态行（退群、被移出群），会话不再出现在其列表中
+func (d *ConversationMemberDAO) RemoveMembers(conversationID uint, userIDs ...uint) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+	return d.db.Where("conversation_id = ? AND user_id IN ?", conversationID, userIDs).
+		Delete(&model.ConversationMember{}).Error
+}
+
+// Get 获取用户在会话中的状态
+func (d *ConversationMemberDAO) Get(conversationID, userID uint) (*model.ConversationMember, error) {
+	var member model.ConversationMember
+	err := d.db.Where("conversation_id = ? AND user_id = ?", conversationID, userID).
+		First(&member).Error
+	if err != nil {
+		return nil, err
+	}
+	return &member, nil
+}
+
+// GetByConversations 批量获取用户在多个会话中的状态（conversationID -> 状态）
+func (d *ConversationMemberDAO) GetByConversations(userID uint, conversationIDs []uint) (map[uint]*model.ConversationMember, error) {
+	result := make(map[uint]*model.ConversationMember, len(conversationIDs))
+	if len(conversationIDs) == 0 {
+		return result, nil
+	}
+
+	var members []model.ConversationMember
+	err := d.db.Where("user_id = ? AND conversation_id IN ?", userID, conversationIDs).
+		Find(&members).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range members {
+		result[members[i].ConversationID] = &members[i]
+	}
+	return result, nil
+}
+
+// GetByConversation 获取会话所有参与者的状态（userID -> 状态）
+func (d *ConversationMemberDAO) GetByConversation(conversationID uint) (map[uint]*model.ConversationMember, error) {
+	var members []model.ConversationMember
+	if err := d.db.Where("conversation_id = ?", conversationID).Find(&members).Error; err != nil {
+		return nil, err
+	}
+
+	result := make(map[uint]*model.ConversationMember, len(members))
+	for i := range members {
+		result[members[i].UserID] = &members[i]
+	}
+	return result, nil
+}
+
+// UpdateSettings 更新用户自己的会话设置，不影响其他参与者
+func (d *ConversationMemberDAO) UpdateSettings(conversationID, userID uint, settings map[string]interface{}) error {
+	result := d.db.Model(&model.ConversationMember{}).
+		Where("conversation_id = ? AND user_id = ?", conversationID, userID).
+		Updates(settings)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		// 值未变化时RowsAffected也为0，需确认记录是否存在
+		if _, err := d.Get(conversationID, userID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// OnNewMessage 会话收到新消息：其他参与者未读数+1，发送者已读位置推进到该消息
+// 所有参与者的隐藏状态解除，会话重新出现在列表中
+func (d *ConversationMemberDAO) OnNewMessage(conversationID, senderID uint, seq uint64) error {
+	err := d.db.Model(&model.ConversationMember{}).
+		Where("conversation_id = ? AND user_id <> ?", conversationID, senderID).
+		Updates(map[string]interface{}{
+			"unread_count": gorm.Expr("unread_count + 1"),
+			"is_hidden":    false,
+		}).Error
+	if err != nil {
+		return err
+	}
+
+	return d.db.Model(&model.ConversationMember{}).
+		Where("conversation_id = ? AND user_id = ?", conversationID, senderID).
+		Updates(map[string]interface{}{
+			"last_read_seq": gorm.Expr("GREATEST(last_read_seq, ?)", seq),
+			"is_hidden":     false,
+		}).Error
+}
+
+// MarkMentioned 标记用户在会话中被@，保留最早一条未读的@消息便于客户端跳转
+// userIDs为空时标记除发送者外的所有参与者（@所有人）
+func (d *ConversationMemberDAO) MarkMentioned(conversationID, senderID, messageID uint, userIDs []uint) error {
+	query := d.db.Model(&model.ConversationMember{}).
+		Where("conversation_id = ? AND user_id <> ?", conversationID, senderID)
+	if len(userIDs) > 0 {
+		query = query.Where("user_id IN ?", userIDs)
+	}
+
+	return query.Update("mention_message_id", gorm.Expr("COALESCE(mention_message_id, ?)", messageID)).Error
+}
+
+// MarkRead 清零未读数和@标记，并把已读位置推进到seq
+func (d *ConversationMemberDAO) MarkRead(conversationID, userID uint, seq uint64) error {
+	return d.db.Model(&model.ConversationMember{}).
+		Where("conversation_id = ? AND user_id = ?", conversationID, userID).
+		Updates(map[string]interface{}{
+			"unread_count":       0,
+			"mention_message_id": nil,
+			"last_read_seq":      gorm.Expr("GREATEST(last_read_seq, ?)", seq),
+		}).Error
+}
+
+// Clear 清空用户的聊天记录：序号不大于seq的消息对本人不可见，同时清零未读和@标记
+func (d *ConversationMemberDAO) Clear(conversationID, userID uint, seq uint64) error {
+	return d.UpdateSettings(conversationID, userID, map[string]interface{}{
+		"cleared_seq":        gorm.Expr("GREATEST(cleared_seq, ?)", seq),
+		"last_read_seq":      gorm.Expr("GREATEST(last_read_seq, ?)", seq),
+		"unread_count":       0,
+		"mention_message_id": nil,
+	})
+}
+
+// Hide 把会话从用户的会话列表中移除，收到新消息后恢复
+func (d *ConversationMemberDAO) Hide(conversationID, userID uint) error {
+	return d.UpdateSettings(conversationID, userID, map[string]interface{}{
+		"is_hidden":          true,
+		"unread_count":       0,
+		"mention_message_id": nil,
+	})
+}
@@ -0,0 +1,94 @@
+package dao
+
+import (
+	"time"
+
+	"github.com/lanxin/im-backend/internal/model"
+	"github.com/lanxin/im-backend/internal/pkg/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// DeviceSessionDAO 设备登录会话
+type DeviceSessionDAO struct {
+	db *gorm.DB
+}
+
+func NewDeviceSessionDAO() *DeviceSessionDAO {
+	return &DeviceSessionDAO{
+		db: mysql.GetDB(),
+	}
+}
+
+// WithTx 返回使用指定事务的DAO
+func (d *DeviceSessionDAO) WithTx(tx *gorm.DB) *DeviceSessionDAO {
+	return &DeviceSessionDAO{db: tx}
+}
+
+// LockUser 锁定用户行，串行化同一用户的并发登录（必须在事务中调用）
+func (d *DeviceSessionDAO) LockUser(userID uint) error {
+	var user model.User
+	return d.db.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Select("id").
+		Where("id = ?", userID).
+		First(&user).Error
+}
+
+// Create 创建会话
+func (d *DeviceSessionDAO) Create(session *model.DeviceSession) error {
+	return d.db.Create(session).Error
+}
+
+// GetByID 获取用户的会话
+func (d *DeviceSessionDAO) GetByID(id, userID uint) (*model.DeviceSession, error) {
+	var session model.DeviceSession
+	err := d.db.Where("id = ? AND user_id = ?", id, userID).First(&session).Error
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// ListActive 获取用户未撤销且未过期的会话，按登录时间从早到晚排列
+func (d *DeviceSessionDAO) ListActive(userID uint) ([]model.DeviceSession, error) {
+	var sessions []model.DeviceSession
+	err := d.db.Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, time.Now()).
+		Order("created_at ASC, id ASC").
+		Find(&sessions).Error
+	return sessions, err
+}
+
+// Revoke 撤销会话，返回实际被撤销（之前未撤销）的数量
+func (d *DeviceSessionDAO) Revoke(ids []uint, reason string) (int64, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	result := d.db.Model(&model.DeviceSession{}).
+		Where("id IN ? AND revoked_at IS NULL", ids).
+		Updates(map[string]interface{}{
+			"revoked_at":    gorm.Expr("NOW()"),
+			"revoke_reason": reason,
+		})
+	return result.RowsAffected, result.Error
+}
+
+// Refresh 签发新Token后延长会话有效期
+func (d *DeviceSessionDAO) Refresh(id uint, expiresAt time.Time, ip string) error {
+	return d.db.Model(&model.DeviceSession{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Updates(map[string]interface{}{
+			"expires_at":     expiresAt,
+			"last_active_at": gorm.Expr("NOW()"),
+			"last_ip":        ip,
+		}).Error
+}
+
+// Touch 更新会话的最近活跃时间和IP
+func (d *DeviceSessionDAO) Touch(id uint, ip string) error {
+	return d.db.Model(&model.DeviceSession{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Updates(map[string]interface{}{
+			"last_active_at": gorm.Expr("NOW()"),
+			"last_ip":        ip,
+		}).Error
+}
@@ -1,6 +1,8 @@
 package dao
 
 import (
+	"time"
+
 	"github.com/lanxin/im-backend/internal/model"
 	"github.com/lanxin/im-backend/internal/pkg/mysql"
 	"gorm.io/gorm"
@@ -16,6 +18,11 @@ func NewFavoriteDAO() *FavoriteDAO {
 	}
 }
 
+// WithTx 返回使用指定事务的DAO
+func (d *FavoriteDAO) WithTx(tx *gorm.DB) *FavoriteDAO {
+	return &FavoriteDAO{db: tx}
+}
+
 // Create 添加收藏
 func (d *FavoriteDAO) Create(favorite *model.Favorite) error {
 	return d.db.Create(favorite).Error
@@ -29,10 +36,10 @@ func (d *FavoriteDAO) GetUserFavorites(userID uint, page, pageSize int) ([]model
 	offset := (page - 1) * pageSize
 	
 	// 统计总数
-	d.db.Model(&model.Favorite{}).Where("user_id = ?", userID).Count(&total)
+	d.db.Model(&model.Favorite{}).Where("user_id = ?", userID).Scopes(notExpiredFavorite).Count(&total)
 	
 	// 分页查询
-	err := d.db.Where("user_id = ?", userID).
+	err := d.db.Where("user_id = ?", userID).Scopes(notExpiredFavorite).
 		Preload("Message").
 		Order("created_at DESC").
 		Offset(offset).
@@ -42,6 +49,19 @@ func (d *FavoriteDAO) GetUserFavorites(userID uint, page, pageSize int) ([]model
 	return favorites, total, err
 }
 
+// notExpiredFavorite 过滤掉源消息已过期、等待清理的收藏
+func notExpiredFavorite(db *gorm.DB) *gorm.DB {
+	return db.Where("NOT EXISTS (SELECT 1 FROM messages m WHERE m.id = favorites.message_id AND m.expires_at <= ?)", time.Now())
+}
+
+// DeleteByMessageIDs 删除引用这些消息的收藏（阅后即焚消息过期时随消息一起清理）
+func (d *FavoriteDAO) DeleteByMessageIDs(messageIDs []uint) error {
+	if len(messageIDs) == 0 {
+		return nil
+	}
+	return d.db.Where("message_id IN ?", messageIDs).Delete(&model.Favorite{}).Error
+}
+
 // Delete 删除收藏
 func (d *FavoriteDAO) Delete(favoriteID, userID uint) error {
 	return d.db.Where("id = ? AND user_id = ?", favoriteID, userID).
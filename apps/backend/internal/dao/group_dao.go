@@ -90,6 +90,15 @@ func (d *GroupMemberDAO) GetMembers(groupID uint) ([]model.GroupMember, error) {
 	return members, err
 }
 
+// GetMemberIDs 获取群组所有成员的用户ID
+func (d *GroupMemberDAO) GetMemberIDs(groupID uint) ([]uint, error) {
+	var userIDs []uint
+	err := d.db.Model(&model.GroupMember{}).
+		Where("group_id = ?", groupID).
+		Pluck("user_id", &userIDs).Error
+	return userIDs, err
+}
+
 // IsMember 检查用户是否是群成员
 func (d *GroupMemberDAO) IsMember(groupID, userID uint) bool {
 	var count int64
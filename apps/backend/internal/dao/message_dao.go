@@ -1,9 +1,12 @@
 package dao
 
 import (
+	"time"
+
 	"github.com/lanxin/im-backend/internal/model"
 	"github.com/lanxin/im-backend/internal/pkg/mysql"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type MessageDAO struct {
@@ -16,11 +19,36 @@ func NewMessageDAO() *MessageDAO {
 	}
 }
 
+// WithTx 返回使用指定事务的DAO
+func (d *MessageDAO) WithTx(tx *gorm.DB) *MessageDAO {
+	return &MessageDAO{db: tx}
+}
+
 // Create 创建消息
 func (d *MessageDAO) Create(message *model.Message) error {
 	return d.db.Create(message).Error
 }
 
+// CreateWithSeq 分配会话内序号并创建消息
+// 通过 UPDATE conversations SET max_seq = max_seq + 1 持有会话行锁，保证同一会话的序号连续且不重复
+// 必须在事务中调用（WithTx），否则递增和读取之间不是原子的
+func (d *MessageDAO) CreateWithSeq(message *model.Message) error {
+	err := d.db.Model(&model.Conversation{}).
+		Where("id = ?", message.ConversationID).
+		UpdateColumn("max_seq", gorm.Expr("max_seq + 1")).Error
+	if err != nil {
+		return err
+	}
+
+	var conv model.Conversation
+	if err := d.db.Select("max_seq").Where("id = ?", message.ConversationID).First(&conv).Error; err != nil {
+		return err
+	}
+
+	message.Seq = conv.MaxSeq
+	return d.db.Create(message).Error
+}
+
 // GetByID 根据ID获取消息
 func (d *MessageDAO) GetByID(id uint) (*model.Message, error) {
 	var message model.Message
@@ -31,12 +59,84 @@ func (d *MessageDAO) GetByID(id uint) (*model.Message, error) {
 	return &message, nil
 }
 
-// GetByConversationID 获取会话的消息列表
-func (d *MessageDAO) GetByConversationID(conversationID uint, page, pageSize int) ([]model.Message, int64, error) {
+// Lock 锁定消息行，串行化针对同一消息需要先检查再写入的操作（必须在事务中调用）
+func (d *MessageDAO) Lock(id uint) error {
+	var message model.Message
+	return d.db.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Select("id").
+		Where("id = ?", id).
+		First(&message).Error
+}
+
+// GetByIDs 批量获取消息，按会话和会话内序号排序
+func (d *MessageDAO) GetByIDs(ids []uint) ([]model.Message, error) {
+	var messages []model.Message
+	if len(ids) == 0 {
+		return messages, nil
+	}
+	err := d.db.Preload("Sender").
+		Where("id IN ?", ids).
+		Order("conversation_id ASC, seq ASC").
+		Find(&messages).Error
+	return messages, err
+}
+
+// IsVisible 消息是否对用户可见（未被用户删除、不在清空记录之前且未过期）
+func (d *MessageDAO) IsVisible(userID, id uint) (bool, error) {
+	var count int64
+	err := d.db.Model(&model.Message{}).Scopes(visibleTo(userID)).Where("id = ?", id).Count(&count).Error
+	return count > 0, err
+}
+
+// GetVisibleByIDs 批量获取用户可见的消息，已删除和清空前的消息不返回
+func (d *MessageDAO) GetVisibleByIDs(userID uint, ids []uint) ([]model.Message, error) {
+	var messages []model.Message
+	if len(ids) == 0 {
+		return messages, nil
+	}
+	err := d.db.Scopes(visibleTo(userID)).Preload("Sender").Preload("Receiver").
+		Where("id IN ?", ids).
+		Order("conversation_id ASC, seq ASC").
+		Find(&messages).Error
+	return messages, err
+}
+
+// GetByClientMsgID 根据发送者和客户端消息ID获取消息（用于重试去重）
+func (d *MessageDAO) GetByClientMsgID(senderID uint, clientMsgID string) (*model.Message, error) {
+	var message model.Message
+	err := d.db.Preload("Sender").Preload("Receiver").
+		Where("sender_id = ? AND client_msg_id = ?", senderID, clientMsgID).
+		First(&message).Error
+	if err != nil {
+		return nil, err
+	}
+	return &message, nil
+}
+
+// maskReplyPreviews 被引用的消息已撤回或已删除时，引用预览替换为占位
+// 引用的消息以visibleTo条件预加载，用户删除或清空掉的被引用消息同样显示为已删除
+func maskReplyPreviews(messages []model.Message) {
+	for i := range messages {
+		messages[i].MaskReplyPreview()
+	}
+}
+
+// visibleTo 过滤掉用户仅对自己删除的消息、清空聊天记录之前的消息和已过期等待清理的消息
+func visibleTo(userID uint) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.
+			Where("messages.seq > COALESCE((SELECT cm.cleared_seq FROM conversation_members cm WHERE cm.conversation_id = messages.conversation_id AND cm.user_id = ?), 0)", userID).
+			Where("NOT EXISTS (SELECT 1 FROM message_deletions md WHERE md.message_id = messages.id AND md.user_id = ?)", userID).
+			Where("(messages.expires_at IS NULL OR messages.expires_at > ?)", time.Now())
+	}
+}
+
+// GetByConversationID 获取会话中用户可见的消息列表
+func (d *MessageDAO) GetByConversationID(userID, conversationID uint, page, pageSize int) ([]model.Message, int64, error) {
 	var messages []model.Message
 	var total int64
 
-	query := d.db.Model(&model.Message{}).Where("conversation_id = ?", conversationID)
+	query := d.db.Model(&model.Message{}).Scopes(visibleTo(userID)).Where("conversation_id = ?", conversationID)
 
 	// 统计总数
 	if err := query.Count(&total).Error; err != nil {
@@ -45,13 +145,14 @@ func (d *MessageDAO) GetByConversationID(conversationID uint, page, pageSize int
 
 	// 分页查询，按时间倒序
 	offset := (page - 1) * pageSize
-	if err := query.Preload("Sender").Preload("Receiver").
+	if err := query.Preload("Sender").Preload("Receiver").Preload("ReplyTo", visibleTo(userID)).
 		Order("created_at DESC").
 		Offset(offset).Limit(pageSize).
 		Find(&messages).Error; err != nil {
 		return nil, 0, err
 	}
 
+	maskReplyPreviews(messages)
 	return messages, total, nil
 }
 
@@ -65,11 +166,123 @@ func (d *MessageDAO) BatchUpdateStatus(ids []uint, status string) error {
 	return d.db.Model(&model.Message{}).Where("id IN ?", ids).Update("status", status).Error
 }
 
+// GetUndelivered 获取指定消息中接收者为receiverID且仍未送达的消息ID
+// 群消息没有逐个成员的送达状态，未撤回的都视为未送达
+func (d *MessageDAO) GetUndelivered(ids []uint, receiverID uint) ([]uint, error) {
+	var undelivered []uint
+	err := d.db.Model(&model.Message{}).
+		Where("id IN ? AND (receiver_id = ? OR group_id IS NOT NULL) AND status = ?", ids, receiverID, model.MessageStatusSent).
+		Order("id ASC").
+		Pluck("id", &undelivered).Error
+	return undelivered, err
+}
+
+// MarkDelivered 将接收者确认收到的消息标记为已送达，返回实际更新的消息
+// 只更新状态为sent的消息，已读/已撤回的不会被回退
+func (d *MessageDAO) MarkDelivered(ids []uint, receiverID uint) ([]model.Message, error) {
+	var messages []model.Message
+	err := d.db.Where("id IN ? AND receiver_id = ? AND status = ?", ids, receiverID, model.MessageStatusSent).
+		Find(&messages).Error
+	if err != nil || len(messages) == 0 {
+		return messages, err
+	}
+
+	updateIDs := make([]uint, len(messages))
+	for i, m := range messages {
+		updateIDs[i] = m.ID
+	}
+	err = d.db.Model(&model.Message{}).
+		Where("id IN ? AND status = ?", updateIDs, model.MessageStatusSent).
+		Update("status", model.MessageStatusDelivered).Error
+	return messages, err
+}
+
 // RecallMessage 撤回消息
 func (d *MessageDAO) RecallMessage(id uint) error {
 	return d.UpdateStatus(id, model.MessageStatusRecalled)
 }
 
+// UpdateContent 编辑消息内容，编辑次数+1
+// 以编辑前的内容为条件，并发编辑时只有一方成功（返回false）
+func (d *MessageDAO) UpdateContent(id uint, previousContent, content string, editedAt time.Time) (bool, error) {
+	result := d.db.Model(&model.Message{}).
+		Where("id = ? AND content = ? AND status <> ?", id, previousContent, model.MessageStatusRecalled).
+		Updates(map[string]interface{}{
+			"content":    content,
+			"edited_at":  editedAt,
+			"edit_count": gorm.Expr("edit_count + 1"),
+		})
+	return result.RowsAffected > 0, result.Error
+}
+
+// IncrThreadReply 根消息的话题回复数+1，并记录最后一条回复
+func (d *MessageDAO) IncrThreadReply(rootID, replyID uint, repliedAt time.Time) error {
+	return d.db.Model(&model.Message{}).
+		Where("id = ?", rootID).
+		UpdateColumns(map[string]interface{}{
+			"thread_reply_count":   gorm.Expr("thread_reply_count + 1"),
+			"last_thread_reply_id": replyID,
+			"last_thread_reply_at": repliedAt,
+		}).Error
+}
+
+// GetThreadReplies 获取话题中序号大于afterSeq、用户可见的回复（按seq正序）
+func (d *MessageDAO) GetThreadReplies(userID, rootID uint, afterSeq uint64, limit int) ([]model.Message, error) {
+	var messages []model.Message
+	err := d.db.Scopes(visibleTo(userID)).Where("thread_root_id = ? AND seq > ?", rootID, afterSeq).
+		Order("seq ASC").
+		Limit(limit).
+		Preload("Sender").
+		Preload("ReplyTo", visibleTo(userID)).
+		Find(&messages).Error
+	maskReplyPreviews(messages)
+	return messages, err
+}
+
+// StartReadTimers 为读者收到的已读后计时消息写入过期时间
+// 只处理尚未开始计时的消息，群聊中第一位读取的成员开始计时
+// 计时起点取应用服务器时间，与发送后计时和清理时的过期判断使用同一个时钟
+func (d *MessageDAO) StartReadTimers(conversationID, readerID uint, readSeq uint64) error {
+	return d.db.Model(&model.Message{}).
+		Where("conversation_id = ? AND seq <= ? AND sender_id <> ?", conversationID, readSeq, readerID).
+		Where("expire_seconds > 0 AND expires_at IS NULL").
+		UpdateColumn("expires_at", gorm.Expr("DATE_ADD(?, INTERVAL expire_seconds SECOND)", time.Now())).Error
+}
+
+// LockExpired 锁定一批已过期的消息（包括已软删除的）
+// 使用 FOR UPDATE SKIP LOCKED，多个节点同时清理时不会重复处理
+// 必须在事务中调用（WithTx）
+func (d *MessageDAO) LockExpired(limit int) ([]model.Message, error) {
+	var messages []model.Message
+	err := d.db.Unscoped().
+		Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+		Select("id", "conversation_id", "type", "file_url").
+		Where("expires_at IS NOT NULL AND expires_at <= ?", time.Now()).
+		Order("expires_at ASC").
+		Limit(limit).
+		Find(&messages).Error
+	return messages, err
+}
+
+// Purge 物理删除消息，编辑历史、表情回应和删除记录随外键级联删除
+func (d *MessageDAO) Purge(ids []uint) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return d.db.Unscoped().Where("id IN ?", ids).Delete(&model.Message{}).Error
+}
+
+// CountFileRefs 统计除excludeIDs外仍引用该文件的消息数（清理文件前确认没有其他消息在用）
+func (d *MessageDAO) CountFileRefs(fileURL string, excludeIDs []uint) (int64, error) {
+	var count int64
+	query := d.db.Unscoped().Model(&model.Message{}).Where("file_url = ?", fileURL)
+	if len(excludeIDs) > 0 {
+		query = query.Where("id NOT IN ?", excludeIDs)
+	}
+	err := query.Count(&count).Error
+	return count, err
+}
+
 // Delete 删除消息（软删除）
 func (d *MessageDAO) Delete(id uint) error {
 	return d.db.Delete(&model.Message{}, id).Error
@@ -103,10 +316,25 @@ func (d *MessageDAO) GetLatestMessage(conversationID uint) (*model.Message, erro
 	return &message, nil
 }
 
+// GetLatestVisible 获取会话中用户可见的最新一条消息（用于会话列表预览）
+func (d *MessageDAO) GetLatestVisible(userID, conversationID uint) (*model.Message, error) {
+	var message model.Message
+	err := d.db.Scopes(visibleTo(userID)).
+		Where("conversation_id = ?", conversationID).
+		Order("seq DESC").
+		Preload("Sender").
+		First(&message).Error
+	if err != nil {
+		return nil, err
+	}
+	return &message, nil
+}
+
 // GetHistoryMessages 获取历史消息（分页加载）
 // 用途：支持Android客户端下拉加载更早的聊天记录
 // 
 // 参数说明：
+//   userID - 当前用户ID（过滤掉其删除的消息和清空前的消息）
 //   conversationID - 会话ID
 //   beforeMessageID - 加载此消息ID之前的消息（0表示加载最新的）
 //   limit - 返回的消息数量限制（建议20条）
@@ -121,11 +349,11 @@ func (d *MessageDAO) GetLatestMessage(conversationID uint) (*model.Message, erro
 //   3. 按id降序排列，取limit条
 //   4. Preload关联的用户信息
 //   5. 反转数组使最早的消息在前
-func (d *MessageDAO) GetHistoryMessages(conversationID, beforeMessageID uint, limit int) ([]model.Message, error) {
+func (d *MessageDAO) GetHistoryMessages(userID, conversationID, beforeMessageID uint, limit int) ([]model.Message, error) {
 	var messages []model.Message
 	
-	// 构建查询条件
-	query := d.db.Where("conversation_id = ?", conversationID)
+	// 构建查询条件（只包含用户可见的消息）
+	query := d.db.Scopes(visibleTo(userID)).Where("conversation_id = ?", conversationID)
 	
 	// 如果指定了beforeMessageID，只获取ID更小的消息（更早的消息）
 	if beforeMessageID > 0 {
@@ -136,8 +364,9 @@ func (d *MessageDAO) GetHistoryMessages(conversationID, beforeMessageID uint, li
 	err := query.
 		Order("id DESC").
 		Limit(limit).
-		Preload("Sender").   // 加载发送者信息
-		Preload("Receiver"). // 加载接收者信息
+		Preload("Sender").                     // 加载发送者信息
+		Preload("Receiver").                   // 加载接收者信息
+		Preload("ReplyTo", visibleTo(userID)). // 加载引用的消息，对用户不可见的不加载
 		Find(&messages).Error
 		
 	if err != nil {
@@ -149,6 +378,42 @@ func (d *MessageDAO) GetHistoryMessages(conversationID, beforeMessageID uint, li
 		messages[i], messages[j] = messages[j], messages[i]
 	}
 	
+	maskReplyPreviews(messages)
+	return messages, nil
+}
+
+// GetAfterSeq 获取会话中序号大于afterSeq、用户可见的消息（按seq正序）
+func (d *MessageDAO) GetAfterSeq(userID, conversationID uint, afterSeq uint64, limit int) ([]model.Message, error) {
+	var messages []model.Message
+	err := d.db.Scopes(visibleTo(userID)).Where("conversation_id = ? AND seq > ?", conversationID, afterSeq).
+		Order("seq ASC").
+		Limit(limit).
+		Preload("Sender").
+		Preload("Receiver").
+		Preload("ReplyTo", visibleTo(userID)).
+		Find(&messages).Error
+	maskReplyPreviews(messages)
+	return messages, err
+}
+
+// GetLatestBySeq 获取会话中用户可见的最新limit条消息（按seq正序返回）
+func (d *MessageDAO) GetLatestBySeq(userID, conversationID uint, limit int) ([]model.Message, error) {
+	var messages []model.Message
+	err := d.db.Scopes(visibleTo(userID)).Where("conversation_id = ?", conversationID).
+		Order("seq DESC").
+		Limit(limit).
+		Preload("Sender").
+		Preload("Receiver").
+		Preload("ReplyTo", visibleTo(userID)).
+		Find(&messages).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for i, j := 0, len(messages)-1; i < j; i, j = i+1, j-1 {
+		messages[i], messages[j] = messages[j], messages[i]
+	}
+	maskReplyPreviews(messages)
 	return messages, nil
 }
 
@@ -169,14 +434,14 @@ func (d *MessageDAO) SearchMessages(userID uint, keyword string, page, pageSize
 	
 	// ✅ 尝试使用全文搜索（MySQL 5.7+支持）
 	// 搜索条件：消息内容包含关键词，且用户是发送者或接收者
-	query := d.db.Model(&model.Message{}).
+	query := d.db.Model(&model.Message{}).Scopes(visibleTo(userID)).
 		Where("(sender_id = ? OR receiver_id = ?)", userID, userID).
 		Where("MATCH(content) AGAINST(? IN BOOLEAN MODE)", keyword)
 	
 	// 统计总数
 	if err := query.Count(&total).Error; err != nil {
 		// 如果全文索引不存在，降级为LIKE查询
-		query = d.db.Model(&model.Message{}).
+		query = d.db.Model(&model.Message{}).Scopes(visibleTo(userID)).
 			Where("(sender_id = ? OR receiver_id = ?) AND content LIKE ?", 
 				userID, userID, "%"+keyword+"%")
 		query.Count(&total)
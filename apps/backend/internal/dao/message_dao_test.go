@@ -0,0 +1,104 @@
+package dao
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lanxin/im-backend/internal/model"
+	"github.com/lanxin/im-backend/internal/pkg/mysql"
+	"github.com/lanxin/im-backend/internal/testutil"
+	"gorm.io/gorm"
+)
+
+func TestCreateWithSeqAssignsNextSeq(t *testing.T) {
+	mock := testutil.NewMockDB(t)
+	messageDAO := NewMessageDAO()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `conversations` SET `max_seq`=max_seq \\+ 1 WHERE id = \\?").
+		WithArgs(12).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("SELECT `max_seq` FROM `conversations` WHERE id = \\?").
+		WithArgs(12).
+		WillReturnRows(sqlmock.NewRows([]string{"max_seq"}).AddRow(8))
+	mock.ExpectExec("INSERT INTO `messages`").
+		WillReturnResult(sqlmock.NewResult(101, 1))
+	mock.ExpectCommit()
+
+	message := &model.Message{ConversationID: 12, SenderID: 1, ReceiverID: 2, Content: "hi", Type: model.MessageTypeText}
+	err := mysql.GetDB().Transaction(func(tx *gorm.DB) error {
+		return messageDAO.WithTx(tx).CreateWithSeq(message)
+	})
+	if err != nil {
+		t.Fatalf("CreateWithSeq: %v", err)
+	}
+	if message.Seq != 8 {
+		t.Errorf("Seq = %d, want 8", message.Seq)
+	}
+	if message.ID != 101 {
+		t.Errorf("ID = %d, want 101", message.ID)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestCreateWithSeqDoesNotInsertWhenIncrementFails(t *testing.T) {
+	mock := testutil.NewMockDB(t)
+	messageDAO := NewMessageDAO()
+
+	lockErr := errors.New("lock wait timeout")
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `conversations` SET `max_seq`=max_seq \\+ 1").
+		WillReturnError(lockErr)
+	mock.ExpectRollback()
+
+	message := &model.Message{ConversationID: 12, SenderID: 1, Content: "hi", Type: model.MessageTypeText}
+	err := mysql.GetDB().Transaction(func(tx *gorm.DB) error {
+		return messageDAO.WithTx(tx).CreateWithSeq(message)
+	})
+	if !errors.Is(err, lockErr) {
+		t.Fatalf("err = %v, want %v", err, lockErr)
+	}
+	if message.Seq != 0 || message.ID != 0 {
+		t.Errorf("message was assigned seq %d id %d after a failed increment", message.Seq, message.ID)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestGetAfterSeqMasksReplyToInvisibleMessage(t *testing.T) {
+	mock := testutil.NewMockDB(t)
+	mock.MatchExpectationsInOrder(false)
+	messageDAO := NewMessageDAO()
+
+	visibility := "cm.user_id = \\?\\), 0\\)\\) AND \\(NOT EXISTS \\(SELECT 1 FROM message_deletions md WHERE md.message_id = messages.id AND md.user_id = \\?\\)\\)"
+	mock.ExpectQuery("SELECT \\* FROM `messages` WHERE \\(conversation_id = \\? AND seq > \\?\\) AND .*"+visibility).
+		WithArgs(12, 5, 1, 1, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "conversation_id", "seq", "sender_id", "receiver_id", "reply_to_message_id"}).
+			AddRow(101, 12, 6, 2, 1, 90))
+	mock.ExpectQuery("SELECT \\* FROM `users` WHERE `users`.`id` = \\?").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectQuery("SELECT \\* FROM `users` WHERE `users`.`id` = \\?").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	// 被引用的消息同样按读者的可见性加载，读者已删除时查不到
+	mock.ExpectQuery("SELECT \\* FROM `messages` WHERE .*"+visibility+".*`messages`.`id` = \\?").
+		WithArgs(1, 1, sqlmock.AnyArg(), 90).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	messages, err := messageDAO.GetAfterSeq(1, 12, 5, 50)
+	if err != nil {
+		t.Fatalf("GetAfterSeq: %v", err)
+	}
+	if len(messages) != 1 || messages[0].ReplyTo == nil {
+		t.Fatalf("messages = %+v, want one message with a reply preview", messages)
+	}
+	if reply := messages[0].ReplyTo; reply.ID != 90 || reply.Content != model.ReplyPlaceholderDeleted {
+		t.Errorf("ReplyTo = %+v, want deleted placeholder for message 90", reply)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,53 @@
+package dao
+
+import (
+	"github.com/lanxin/im-backend/internal/model"
+	"github.com/lanxin/im-backend/internal/pkg/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// MessageDeletionDAO 用户仅对自己删除的消息
+type MessageDeletionDAO struct {
+	db *gorm.DB
+}
+
+func NewMessageDeletionDAO() *MessageDeletionDAO {
+	return &MessageDeletionDAO{
+		db: mysql.GetDB(),
+	}
+}
+
+// WithTx 返回使用指定事务的DAO
+func (d *MessageDeletionDAO) WithTx(tx *gorm.DB) *MessageDeletionDAO {
+	return &MessageDeletionDAO{db: tx}
+}
+
+// Add 记录用户删除了消息，重复删除忽略
+func (d *MessageDeletionDAO) Add(userID, messageID, conversationID uint) error {
+	return d.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&model.MessageDeletion{
+		UserID:         userID,
+		MessageID:      messageID,
+		ConversationID: conversationID,
+	}).Error
+}
+
+// GetDeletedIDs 返回messageIDs中用户已删除的消息
+func (d *MessageDeletionDAO) GetDeletedIDs(userID uint, messageIDs []uint) (map[uint]bool, error) {
+	result := make(map[uint]bool)
+	if len(messageIDs) == 0 {
+		return result, nil
+	}
+
+	var ids []uint
+	err := d.db.Model(&model.MessageDeletion{}).
+		Where("user_id = ? AND message_id IN ?", userID, messageIDs).
+		Pluck("message_id", &ids).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range ids {
+		result[id] = true
+	}
+	return result, nil
+}
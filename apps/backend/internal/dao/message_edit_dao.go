@@ -0,0 +1,36 @@
+package dao
+
+import (
+	"github.com/lanxin/im-backend/internal/model"
+	"github.com/lanxin/im-backend/internal/pkg/mysql"
+	"gorm.io/gorm"
+)
+
+type MessageEditDAO struct {
+	db *gorm.DB
+}
+
+func NewMessageEditDAO() *MessageEditDAO {
+	return &MessageEditDAO{
+		db: mysql.GetDB(),
+	}
+}
+
+// WithTx 返回使用指定事务的DAO
+func (d *MessageEditDAO) WithTx(tx *gorm.DB) *MessageEditDAO {
+	return &MessageEditDAO{db: tx}
+}
+
+// Create 记录一次编辑
+func (d *MessageEditDAO) Create(edit *model.MessageEdit) error {
+	return d.db.Create(edit).Error
+}
+
+// GetByMessageID 获取消息的编辑历史（按编辑时间正序）
+func (d *MessageEditDAO) GetByMessageID(messageID uint) ([]model.MessageEdit, error) {
+	var edits []model.MessageEdit
+	err := d.db.Where("message_id = ?", messageID).
+		Order("id ASC").
+		Find(&edits).Error
+	return edits, err
+}
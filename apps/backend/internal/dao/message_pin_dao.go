@@ -0,0 +1,82 @@
+package dao
+
+import (
+	"github.com/lanxin/im-backend/internal/model"
+	"github.com/lanxin/im-backend/internal/pkg/mysql"
+	"gorm.io/gorm"
+)
+
+// MessagePinDAO 会话置顶消息
+type MessagePinDAO struct {
+	db *gorm.DB
+}
+
+func NewMessagePinDAO() *MessagePinDAO {
+	return &MessagePinDAO{
+		db: mysql.GetDB(),
+	}
+}
+
+// WithTx 返回使用指定事务的DAO
+func (d *MessagePinDAO) WithTx(tx *gorm.DB) *MessagePinDAO {
+	return &MessagePinDAO{db: tx}
+}
+
+// Create 置顶消息
+func (d *MessagePinDAO) Create(pin *model.MessagePin) error {
+	return d.db.Create(pin).Error
+}
+
+// Exists 消息是否已置顶
+func (d *MessagePinDAO) Exists(conversationID, messageID uint) bool {
+	var count int64
+	d.db.Model(&model.MessagePin{}).
+		Where("conversation_id = ? AND message_id = ?", conversationID, messageID).
+		Count(&count)
+	return count > 0
+}
+
+// Count 会话中置顶的消息数
+func (d *MessagePinDAO) Count(conversationID uint) (int64, error) {
+	var count int64
+	err := d.db.Model(&model.MessagePin{}).Where("conversation_id = ?", conversationID).Count(&count).Error
+	return count, err
+}
+
+// Delete 取消置顶，返回是否删除了记录
+func (d *MessagePinDAO) Delete(conversationID, messageID uint) (bool, error) {
+	result := d.db.Where("conversation_id = ? AND message_id = ?", conversationID, messageID).
+		Delete(&model.MessagePin{})
+	return result.RowsAffected > 0, result.Error
+}
+
+// DeleteByMessageIDs 取消这些消息的置顶（消息被清理前调用），返回被取消的置顶
+func (d *MessagePinDAO) DeleteByMessageIDs(messageIDs []uint) ([]model.MessagePin, error) {
+	var pins []model.MessagePin
+	if len(messageIDs) == 0 {
+		return pins, nil
+	}
+	if err := d.db.Where("message_id IN ?", messageIDs).Find(&pins).Error; err != nil || len(pins) == 0 {
+		return pins, err
+	}
+
+	ids := make([]uint, len(pins))
+	for i, pin := range pins {
+		ids[i] = pin.ID
+	}
+	return pins, d.db.Where("id IN ?", ids).Delete(&model.MessagePin{}).Error
+}
+
+// ListVisible 获取会话中用户可见的置顶消息，最新置顶的在前
+// 被本人删除、清空前和已过期的消息不返回
+func (d *MessagePinDAO) ListVisible(userID, conversationID uint) ([]model.MessagePin, error) {
+	var pins []model.MessagePin
+	err := d.db.Select("message_pins.*").
+		Joins("JOIN messages ON messages.id = message_pins.message_id AND messages.deleted_at IS NULL").
+		Scopes(visibleTo(userID)).
+		Where("message_pins.conversation_id = ?", conversationID).
+		Preload("Message.Sender").
+		Order("message_pins.created_at DESC, message_pins.id DESC").
+		Find(&pins).Error
+	return pins, err
+}
@@ -0,0 +1,83 @@
+package dao
+
+import (
+	"github.com/lanxin/im-backend/internal/model"
+	"github.com/lanxin/im-backend/internal/pkg/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type MessageReactionDAO struct {
+	db *gorm.DB
+}
+
+func NewMessageReactionDAO() *MessageReactionDAO {
+	return &MessageReactionDAO{
+		db: mysql.GetDB(),
+	}
+}
+
+// WithTx 返回使用指定事务的DAO
+func (d *MessageReactionDAO) WithTx(tx *gorm.DB) *MessageReactionDAO {
+	return &MessageReactionDAO{db: tx}
+}
+
+// Add 添加表情回应，已存在时返回false
+func (d *MessageReactionDAO) Add(messageID, userID uint, emoji string) (bool, error) {
+	result := d.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&model.MessageReaction{
+		MessageID: messageID,
+		UserID:    userID,
+		Emoji:     emoji,
+	})
+	return result.RowsAffected > 0, result.Error
+}
+
+// Remove 移除表情回应，不存在时返回false
+func (d *MessageReactionDAO) Remove(messageID, userID uint, emoji string) (bool, error) {
+	result := d.db.Where("message_id = ? AND user_id = ? AND emoji = ?", messageID, userID, emoji).
+		Delete(&model.MessageReaction{})
+	return result.RowsAffected > 0, result.Error
+}
+
+// CountUserEmojis 统计用户在一条消息上回应的不同表情数
+func (d *MessageReactionDAO) CountUserEmojis(messageID, userID uint) (int64, error) {
+	var count int64
+	err := d.db.Model(&model.MessageReaction{}).
+		Where("message_id = ? AND user_id = ?", messageID, userID).
+		Count(&count).Error
+	return count, err
+}
+
+// GetSummaries 按表情汇总多条消息的回应（messageID -> 汇总，按首次回应时间排序）
+// userID 用于标记当前用户是否回应过，为0时不标记
+func (d *MessageReactionDAO) GetSummaries(messageIDs []uint, userID uint) (map[uint][]model.ReactionSummary, error) {
+	result := make(map[uint][]model.ReactionSummary)
+	if len(messageIDs) == 0 {
+		return result, nil
+	}
+
+	var rows []struct {
+		MessageID uint
+		Emoji     string
+		Count     int
+		Reacted   bool
+	}
+	err := d.db.Model(&model.MessageReaction{}).
+		Select("message_id, emoji, COUNT(*) AS count, MAX(user_id = ?) AS reacted", userID).
+		Where("message_id IN ?", messageIDs).
+		Group("message_id, emoji").
+		Order("MIN(id) ASC").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		result[row.MessageID] = append(result[row.MessageID], model.ReactionSummary{
+			Emoji:   row.Emoji,
+			Count:   row.Count,
+			Reacted: row.Reacted,
+		})
+	}
+	return result, nil
+}
@@ -0,0 +1,146 @@
+package dao
+
+import (
+	"time"
+
+	"github.com/lanxin/im-backend/internal/model"
+	"github.com/lanxin/im-backend/internal/pkg/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type OutboxDAO struct {
+	db *gorm.DB
+}
+
+func NewOutboxDAO() *OutboxDAO {
+	return &OutboxDAO{
+		db: mysql.GetDB(),
+	}
+}
+
+// WithTx 返回使用指定事务的DAO
+func (d *OutboxDAO) WithTx(tx *gorm.DB) *OutboxDAO {
+	return &OutboxDAO{db: tx}
+}
+
+// Create 写入发件箱事件
+func (d *OutboxDAO) Create(event *model.OutboxEvent) error {
+	if event.NextAttemptAt.IsZero() {
+		event.NextAttemptAt = time.Now()
+	}
+	return d.db.Create(event).Error
+}
+
+// LockPending 锁定一批到期的待投递事件
+// 使用 FOR UPDATE SKIP LOCKED，多个节点同时运行中继时不会重复领取同一批事件
+// 同Key已有更早的事件在退避或已被领取（next_attempt_at未到）时，后面的事件不会被选中
+// 必须在事务中调用（WithTx）
+func (d *OutboxDAO) LockPending(limit int) ([]model.OutboxEvent, error) {
+	now := time.Now()
+	var events []model.OutboxEvent
+	err := d.db.
+		Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+		Where("status = ? AND next_attempt_at <= ?", model.OutboxStatusPending, now).
+		Where("NOT EXISTS (?)", d.db.Session(&gorm.Session{NewDB: true}).
+			Table("outbox AS earlier").
+			Select("1").
+			Where("earlier.message_key = outbox.message_key AND earlier.message_key <> '' AND earlier.status = ? AND earlier.id < outbox.id AND earlier.next_attempt_at > ?",
+				model.OutboxStatusPending, now)).
+		Order("id ASC").
+		Limit(limit).
+		Find(&events).Error
+	return events, err
+}
+
+// GetPendingKeyOrder 获取指定Key下ID不大于maxID的待投递事件，按ID升序
+// 用于确认同Key更早的事件都在本批中（可能被其他中继锁定而跳过）
+func (d *OutboxDAO) GetPendingKeyOrder(keys []string, maxID uint) ([]model.OutboxEvent, error) {
+	var events []model.OutboxEvent
+	if len(keys) == 0 {
+		return events, nil
+	}
+	err := d.db.Select("id", "message_key").
+		Where("message_key IN ? AND status = ? AND id <= ?", keys, model.OutboxStatusPending, maxID).
+		Order("id ASC").
+		Find(&events).Error
+	return events, err
+}
+
+// Claim 领取事件：把下次投递时间推迟到租约结束
+// 领取后提交事务再投递，投递期间不持有行锁；进程在投递中退出时，租约到期后事件会被重新投递
+func (d *OutboxDAO) Claim(ids []uint, leaseUntil time.Time) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return d.db.Model(&model.OutboxEvent{}).
+		Where("id IN ?", ids).
+		Update("next_attempt_at", leaseUntil).Error
+}
+
+// Release 放弃领取，事件立即可以再次投递（不计入失败次数）
+func (d *OutboxDAO) Release(ids []uint) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return d.db.Model(&model.OutboxEvent{}).
+		Where("id IN ? AND status = ?", ids, model.OutboxStatusPending).
+		Update("next_attempt_at", time.Now()).Error
+}
+
+// MarkSent 标记事件已投递
+func (d *OutboxDAO) MarkSent(ids []uint) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	now := time.Now()
+	return d.db.Model(&model.OutboxEvent{}).
+		Where("id IN ?", ids).
+		Updates(map[string]interface{}{
+			"status":  model.OutboxStatusSent,
+			"sent_at": &now,
+		}).Error
+}
+
+// MarkFailed 记录投递失败并设置下次重试时间
+func (d *OutboxDAO) MarkFailed(id uint, errMsg string, nextAttemptAt time.Time) error {
+	if len(errMsg) > 500 {
+		errMsg = errMsg[:500]
+	}
+	return d.db.Model(&model.OutboxEvent{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"attempts":        gorm.Expr("attempts + 1"),
+			"last_error":      errMsg,
+			"next_attempt_at": nextAttemptAt,
+		}).Error
+}
+
+// CountPending 统计待投递事件数量
+func (d *OutboxDAO) CountPending() (int64, error) {
+	var count int64
+	err := d.db.Model(&model.OutboxEvent{}).
+		Where("status = ?", model.OutboxStatusPending).
+		Count(&count).Error
+	return count, err
+}
+
+// GetOldestPending 获取最早的待投递事件（用于计算积压时长）
+func (d *OutboxDAO) GetOldestPending() (*model.OutboxEvent, error) {
+	var event model.OutboxEvent
+	err := d.db.Where("status = ?", model.OutboxStatusPending).
+		Order("id ASC").
+		First(&event).Error
+	if err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
+
+// DeleteSentBefore 清理指定时间之前已投递的事件
+func (d *OutboxDAO) DeleteSentBefore(before time.Time) (int64, error) {
+	result := d.db.
+		Where("status = ? AND sent_at < ?", model.OutboxStatusSent, before).
+		Delete(&model.OutboxEvent{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,122 @@
+package dao
+
+import (
+	"time"
+
+	"github.com/lanxin/im-backend/internal/model"
+	"github.com/lanxin/im-backend/internal/pkg/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ScheduledMessageDAO struct {
+	db *gorm.DB
+}
+
+func NewScheduledMessageDAO() *ScheduledMessageDAO {
+	return &ScheduledMessageDAO{
+		db: mysql.GetDB(),
+	}
+}
+
+// WithTx 返回使用指定事务的DAO
+func (d *ScheduledMessageDAO) WithTx(tx *gorm.DB) *ScheduledMessageDAO {
+	return &ScheduledMessageDAO{db: tx}
+}
+
+// Create 创建定时消息
+func (d *ScheduledMessageDAO) Create(scheduled *model.ScheduledMessage) error {
+	return d.db.Create(scheduled).Error
+}
+
+// GetByID 获取用户自己的定时消息
+func (d *ScheduledMessageDAO) GetByID(id, senderID uint) (*model.ScheduledMessage, error) {
+	var scheduled model.ScheduledMessage
+	err := d.db.Where("id = ? AND sender_id = ?", id, senderID).First(&scheduled).Error
+	if err != nil {
+		return nil, err
+	}
+	return &scheduled, nil
+}
+
+// ListBySender 获取用户的定时消息，status为空时返回全部，按计划时间排序
+func (d *ScheduledMessageDAO) ListBySender(senderID uint, status string) ([]model.ScheduledMessage, error) {
+	var list []model.ScheduledMessage
+	query := d.db.Where("sender_id = ?", senderID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	err := query.Order("scheduled_at ASC, id ASC").Find(&list).Error
+	return list, err
+}
+
+// CountPending 统计用户待发送的定时消息数
+func (d *ScheduledMessageDAO) CountPending(senderID uint) (int64, error) {
+	var count int64
+	err := d.db.Model(&model.ScheduledMessage{}).
+		Where("sender_id = ? AND status = ?", senderID, model.ScheduledStatusPending).
+		Count(&count).Error
+	return count, err
+}
+
+// UpdatePending 修改待发送的定时消息，已被调度器领取、已发送或已取消时返回false
+func (d *ScheduledMessageDAO) UpdatePending(id, senderID uint, updates map[string]interface{}) (bool, error) {
+	result := d.db.Model(&model.ScheduledMessage{}).
+		Where("id = ? AND sender_id = ? AND status = ?", id, senderID, model.ScheduledStatusPending).
+		Updates(updates)
+	return result.RowsAffected > 0, result.Error
+}
+
+// LockDue 锁定一批到期的待发送消息，以及在claimedBefore之前领取但没有完成的消息
+// 使用 FOR UPDATE SKIP LOCKED，多个节点同时运行调度器时不会重复领取
+// 必须在事务中调用（WithTx）
+func (d *ScheduledMessageDAO) LockDue(limit int, claimedBefore time.Time) ([]model.ScheduledMessage, error) {
+	var list []model.ScheduledMessage
+	err := d.db.
+		Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+		Where("(status = ? AND scheduled_at <= ?) OR (status = ? AND claimed_at <= ?)",
+			model.ScheduledStatusPending, time.Now(), model.ScheduledStatusDispatching, claimedBefore).
+		Order("scheduled_at ASC, id ASC").
+		Limit(limit).
+		Find(&list).Error
+	return list, err
+}
+
+// Claim 领取定时消息：标记为dispatching并记录领取时间
+// 领取后提交事务再发送，发送期间不持有行锁
+func (d *ScheduledMessageDAO) Claim(ids []uint) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return d.db.Model(&model.ScheduledMessage{}).
+		Where("id IN ?", ids).
+		Updates(map[string]interface{}{
+			"status":     model.ScheduledStatusDispatching,
+			"claimed_at": time.Now(),
+		}).Error
+}
+
+// MarkSent 标记已领取的消息已发送
+func (d *ScheduledMessageDAO) MarkSent(id, messageID uint) error {
+	now := time.Now()
+	return d.db.Model(&model.ScheduledMessage{}).
+		Where("id = ? AND status = ?", id, model.ScheduledStatusDispatching).
+		Updates(map[string]interface{}{
+			"status":     model.ScheduledStatusSent,
+			"message_id": messageID,
+			"sent_at":    &now,
+		}).Error
+}
+
+// MarkFailed 标记已领取的消息发送失败（如已不是群成员），不再重试
+func (d *ScheduledMessageDAO) MarkFailed(id uint, errMsg string) error {
+	if len(errMsg) > 500 {
+		errMsg = errMsg[:500]
+	}
+	return d.db.Model(&model.ScheduledMessage{}).
+		Where("id = ? AND status = ?", id, model.ScheduledStatusDispatching).
+		Updates(map[string]interface{}{
+			"status":     model.ScheduledStatusFailed,
+			"last_error": errMsg,
+		}).Error
+}
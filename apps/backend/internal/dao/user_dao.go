@@ -31,6 +31,16 @@ func (d *UserDAO) GetByID(id uint) (*model.User, error) {
 	return &user, nil
 }
 
+// GetByIDs 批量获取用户
+func (d *UserDAO) GetByIDs(ids []uint) ([]model.User, error) {
+	var users []model.User
+	if len(ids) == 0 {
+		return users, nil
+	}
+	err := d.db.Where("id IN ?", ids).Find(&users).Error
+	return users, err
+}
+
 // GetByUsername 根据用户名获取用户
 func (d *UserDAO) GetByUsername(username string) (*model.User, error) {
 	var user model.User
@@ -0,0 +1,81 @@
+package dao
+
+import (
+	"time"
+
+	"github.com/lanxin/im-backend/internal/model"
+	"github.com/lanxin/im-backend/internal/pkg/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// UserPresenceDAO 用户在线信息（最后在线时间、自定义状态、隐私设置）
+type UserPresenceDAO struct {
+	db *gorm.DB
+}
+
+func NewUserPresenceDAO() *UserPresenceDAO {
+	return &UserPresenceDAO{
+		db: mysql.GetDB(),
+	}
+}
+
+// GetByUserID 获取用户的在线信息，用户从未设置过时返回gorm.ErrRecordNotFound
+func (d *UserPresenceDAO) GetByUserID(userID uint) (*model.UserPresence, error) {
+	var presence model.UserPresence
+	err := d.db.Where("user_id = ?", userID).First(&presence).Error
+	if err != nil {
+		return nil, err
+	}
+	return &presence, nil
+}
+
+// GetByUserIDs 批量获取在线信息，没有记录的用户不在结果中
+func (d *UserPresenceDAO) GetByUserIDs(userIDs []uint) ([]model.UserPresence, error) {
+	var list []model.UserPresence
+	if len(userIDs) == 0 {
+		return list, nil
+	}
+	err := d.db.Where("user_id IN ?", userIDs).Find(&list).Error
+	return list, err
+}
+
+// UpdateCustomStatus 设置自定义状态，text为空表示清除
+func (d *UserPresenceDAO) UpdateCustomStatus(userID uint, text string, expiresAt *time.Time) error {
+	return d.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"custom_status", "custom_status_expires_at", "updated_at"}),
+	}).Create(&model.UserPresence{
+		UserID:                userID,
+		CustomStatus:          text,
+		CustomStatusExpiresAt: expiresAt,
+	}).Error
+}
+
+// UpdateHideLastSeen 修改是否隐藏最后在线时间
+func (d *UserPresenceDAO) UpdateHideLastSeen(userID uint, hide bool) error {
+	return d.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"hide_last_seen", "updated_at"}),
+	}).Create(&model.UserPresence{
+		UserID:       userID,
+		HideLastSeen: hide,
+	}).Error
+}
+
+// SaveLastSeen 批量写入最后在线时间
+func (d *UserPresenceDAO) SaveLastSeen(lastSeen map[uint]time.Time) error {
+	if len(lastSeen) == 0 {
+		return nil
+	}
+
+	list := make([]model.UserPresence, 0, len(lastSeen))
+	for userID, at := range lastSeen {
+		at := at
+		list = append(list, model.UserPresence{UserID: userID, LastSeenAt: &at})
+	}
+	return d.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"last_seen_at"}),
+	}).Create(&list).Error
+}
@@ -1,12 +1,15 @@
 package middleware
 
 import (
+	"errors"
 	"net/http"
 	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/lanxin/im-backend/internal/dao"
 	"github.com/lanxin/im-backend/internal/pkg/jwt"
 	"github.com/lanxin/im-backend/internal/pkg/redis"
+	"gorm.io/gorm"
 )
 
 // JWTAuth JWT认证中间件
@@ -55,15 +58,65 @@ func JWTAuth(secret string) gin.HandlerFunc {
 		return
 	}
 
+	// 设备会话已被撤销（在其他设备上移除或被新登录挤掉）
+	revoked, err := SessionRevoked(claims.UserID, claims.SessionID)
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"code":    503,
+			"message": "Failed to verify session",
+		})
+		c.Abort()
+		return
+	}
+	if revoked {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"code":    401,
+			"message": "Session has been revoked",
+		})
+		c.Abort()
+		return
+	}
+
 	// 将用户信息存储到context中
 	c.Set("user_id", claims.UserID)
 	c.Set("username", claims.Username)
 	c.Set("role", claims.Role)
+	c.Set("session_id", claims.SessionID)
+
+	// 更新会话最近活跃时间（限频，不阻塞请求）
+	if claims.SessionID != 0 && redis.ShouldTouchSession(claims.SessionID) {
+		go dao.NewDeviceSessionDAO().Touch(claims.SessionID, c.ClientIP())
+	}
 
 	c.Next()
 	}
 }
 
+// SessionRevoked 检查Token所属的设备会话是否已撤销
+// 优先读Redis，未命中或Redis出错时以数据库为准，无法确认时返回错误（不放行）
+// 不带会话ID的旧Token不检查
+func SessionRevoked(userID, sessionID uint) (bool, error) {
+	if sessionID == 0 {
+		return false, nil
+	}
+	if revoked, known := redis.SessionRevocation(sessionID); known {
+		return revoked, nil
+	}
+
+	session, err := dao.NewDeviceSessionDAO().GetByID(sessionID, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return true, nil
+		}
+		return false, err
+	}
+	if session.RevokedAt != nil {
+		return true, nil
+	}
+	redis.MarkSessionActive(sessionID)
+	return false, nil
+}
+
 // AdminAuth 管理员权限中间件（必须在JWTAuth之后使用）
 func AdminAuth() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -99,6 +152,15 @@ func GetUserID(c *gin.Context) (uint, bool) {
 	return userID.(uint), true
 }
 
+// GetSessionID 从context获取设备会话ID，旧Token没有会话时返回0
+func GetSessionID(c *gin.Context) uint {
+	sessionID, exists := c.Get("session_id")
+	if !exists {
+		return 0
+	}
+	return sessionID.(uint)
+}
+
 // GetUsername 从context获取用户名
 func GetUsername(c *gin.Context) (string, bool) {
 	username, exists := c.Get("username")
@@ -0,0 +1,96 @@
+package middleware
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lanxin/im-backend/internal/testutil"
+)
+
+func TestSessionRevoked(t *testing.T) {
+	sessionSQL := "SELECT \\* FROM `device_sessions` WHERE id = \\? AND user_id = \\? ORDER BY `device_sessions`.`id` LIMIT 1"
+	sessionRows := func(revokedAt interface{}) *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"id", "user_id", "revoked_at"}).AddRow(12, 1, revokedAt)
+	}
+
+	t.Run("revoke marker in redis", func(t *testing.T) {
+		testutil.NewMockDB(t)
+		mr := testutil.NewRedis(t)
+		mr.Set("session:revoked:12", "1")
+
+		revoked, err := SessionRevoked(1, 12)
+		if err != nil || !revoked {
+			t.Errorf("SessionRevoked() = %v, %v, want revoked", revoked, err)
+		}
+	})
+
+	t.Run("redis miss falls back to the database", func(t *testing.T) {
+		mock := testutil.NewMockDB(t)
+		mr := testutil.NewRedis(t)
+		mock.ExpectQuery(sessionSQL).WithArgs(12, 1).WillReturnRows(sessionRows(time.Now()))
+
+		revoked, err := SessionRevoked(1, 12)
+		if err != nil || !revoked {
+			t.Errorf("SessionRevoked() = %v, %v, want revoked", revoked, err)
+		}
+		if mr.Exists("session:revoked:12") {
+			t.Error("revoked session should not be cached as active")
+		}
+	})
+
+	t.Run("active session is cached", func(t *testing.T) {
+		mock := testutil.NewMockDB(t)
+		mr := testutil.NewRedis(t)
+		mock.ExpectQuery(sessionSQL).WithArgs(12, 1).WillReturnRows(sessionRows(nil))
+
+		revoked, err := SessionRevoked(1, 12)
+		if err != nil || revoked {
+			t.Errorf("SessionRevoked() = %v, %v, want active", revoked, err)
+		}
+		if got, _ := mr.Get("session:revoked:12"); got != "0" {
+			t.Errorf("cached state = %q, want \"0\"", got)
+		}
+		if ttl := mr.TTL("session:revoked:12"); ttl <= 0 || ttl > time.Minute {
+			t.Errorf("cache TTL = %v, want at most 1m", ttl)
+		}
+
+		// 缓存期间不再查询数据库
+		if revoked, err := SessionRevoked(1, 12); err != nil || revoked {
+			t.Errorf("SessionRevoked() = %v, %v, want active", revoked, err)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("redis unavailable and session missing", func(t *testing.T) {
+		mock := testutil.NewMockDB(t)
+		mr := testutil.NewRedis(t)
+		mr.Close()
+		mock.ExpectQuery(sessionSQL).WithArgs(12, 1).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+		revoked, err := SessionRevoked(1, 12)
+		if err != nil || !revoked {
+			t.Errorf("SessionRevoked() = %v, %v, want revoked", revoked, err)
+		}
+	})
+
+	t.Run("redis and database unavailable", func(t *testing.T) {
+		mock := testutil.NewMockDB(t)
+		mr := testutil.NewRedis(t)
+		mr.Close()
+		mock.ExpectQuery(sessionSQL).WithArgs(12, 1).WillReturnError(errors.New("connection refused"))
+
+		if _, err := SessionRevoked(1, 12); err == nil {
+			t.Error("SessionRevoked() should fail instead of letting the request through")
+		}
+	})
+
+	t.Run("token without session", func(t *testing.T) {
+		if revoked, err := SessionRevoked(1, 0); err != nil || revoked {
+			t.Errorf("SessionRevoked() = %v, %v, want active", revoked, err)
+		}
+	})
+}
@@ -0,0 +1,28 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// ChatRecord 合并转发的聊天记录快照，序列化后作为chat_record消息的content
+// 快照在转发时生成，源消息之后被编辑或撤回不影响已转发的记录
+type ChatRecord struct {
+	Title    string           `json:"title"`
+	Messages []ChatRecordItem `json:"messages"`
+}
+
+// ChatRecordItem 聊天记录中的一条消息
+type ChatRecordItem struct {
+	MessageID    uint            `json:"message_id"`
+	SenderID     uint            `json:"sender_id"`
+	SenderName   string          `json:"sender_name"`
+	SenderAvatar string          `json:"sender_avatar,omitempty"`
+	Content      string          `json:"content"`
+	Type         string          `json:"type"`
+	FileURL      string          `json:"file_url,omitempty"`
+	FileSize     int64           `json:"file_size,omitempty"`
+	Duration     int             `json:"duration,omitempty"`
+	Payload      json.RawMessage `json:"payload,omitempty"`
+	CreatedAt    time.Time       `json:"created_at"`
+}
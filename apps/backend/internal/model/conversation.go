@@ -4,6 +4,8 @@ import (
 	"time"
 )
 
+// Conversation 会话（双方/群成员共享）
+// 免打扰、置顶、未读数等个人状态见ConversationMember
 type Conversation struct {
 	ID            uint       `gorm:"primarykey" json:"id"`
 	Type          string     `gorm:"type:enum('single','group');default:'single';index" json:"type"`
@@ -12,12 +14,14 @@ type Conversation struct {
 	GroupID       *uint      `gorm:"index" json:"group_id,omitempty"`
 	LastMessageID *uint      `json:"last_message_id,omitempty"`
 	LastMessageAt *time.Time `gorm:"index" json:"last_message_at,omitempty"`
-	IsMuted       bool       `gorm:"default:false" json:"is_muted"`
-	IsTop         bool       `gorm:"default:false;index" json:"is_top"`
-	IsStarred     bool       `gorm:"default:false" json:"is_starred"`
-	IsBlocked     bool       `gorm:"default:false" json:"is_blocked"`
-	CreatedAt     time.Time  `json:"created_at"`
-	UpdatedAt     time.Time  `json:"updated_at"`
+	MaxSeq        uint64     `gorm:"not null;default:0" json:"max_seq"` // 会话内最大消息序号
+
+	// 阅后即焚设置，对会话内所有参与者生效，只影响设置之后发送的消息
+	DisappearMode    string `gorm:"type:enum('off','after_read','after_send');default:'off'" json:"disappear_mode"`
+	DisappearSeconds int    `gorm:"not null;default:0" json:"disappear_seconds"` // 已读后或发送后多少秒过期
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
 
 	// 关联
 	User1       *User    `gorm:"foreignKey:User1ID" json:"user1,omitempty"`
@@ -35,3 +39,10 @@ const (
 	ConversationTypeSingle = "single"
 	ConversationTypeGroup  = "group"
 )
+
+// DisappearMode 常量
+const (
+	DisappearModeOff       = "off"
+	DisappearModeAfterRead = "after_read" // 接收者读取后开始计时，群聊中由第一位读取的成员开始计时
+	DisappearModeAfterSend = "after_send" // 发送后开始计时
+)
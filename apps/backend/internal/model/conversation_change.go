@@ -0,0 +1,49 @@
+package model
+
+import (
+	"time"
+)
+
+// ConversationChange 会话变更日志
+// 记录不产生新seq的变更，离线设备增量同步时按ID游标补齐；消息被物理删除后日志仍保留，不设外键
+type ConversationChange struct {
+	ID             uint64    `gorm:"primarykey;index:idx_conversation_id,priority:2" json:"id"`
+	ConversationID uint      `gorm:"not null;index:idx_conversation_id,priority:1" json:"conversation_id"`
+	UserID         uint      `gorm:"not null;default:0" json:"-"` // 只对该用户可见的变更（删除、清空、设置），0表示所有参与者
+	Kind           string    `gorm:"size:32;not null" json:"kind"`
+	MessageID      *uint     `json:"message_id,omitempty"`
+	CreatedAt      time.Time `gorm:"index:idx_created_at" json:"created_at"`
+
+	// 同步时填充，不落库
+	Message      *Message            `gorm:"-" json:"message,omitempty"`      // 消息的当前状态，已删除、已过期或对用户不可见时为空
+	Settings     *ConversationMember `gorm:"-" json:"settings,omitempty"`     // 用户在会话中的当前设置
+	Conversation *Conversation       `gorm:"-" json:"conversation,omitempty"` // 会话的当前状态（会话共享设置变更）
+}
+
+func (ConversationChange) TableName() string {
+	return "conversation_changes"
+}
+
+// 变更类型
+const (
+	ChangeMessageEdited       = "message_edited"
+	ChangeMessageRecalled     = "message_recalled"
+	ChangeMessageDeleted      = "message_deleted"      // 仅对自己删除
+	ChangeMessageExpired      = "message_expired"      // 阅后即焚到期
+	ChangeThreadUpdated       = "thread_updated"       // 话题根消息的回复数变化
+	ChangeReactionUpdated     = "reaction_updated"     // 表情回应的增减
+	ChangePinUpdated          = "pin_updated"          // 会话置顶消息的增减
+	ChangeConversationUpdated = "conversation_updated" // 会话共享设置（阅后即焚）
+	ChangeConversationCleared = "conversation_cleared"
+	ChangeConversationHidden  = "conversation_hidden"
+	ChangeSettingsUpdated     = "settings_updated" // 免打扰、置顶、草稿等个人设置
+)
+
+// PersonalChange 变更是否只属于一个用户，同步时附带该用户的会话设置
+func PersonalChange(kind string) bool {
+	switch kind {
+	case ChangeConversationCleared, ChangeConversationHidden, ChangeSettingsUpdated:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,30 @@
+package model
+
+import (
+	"time"
+)
+
+// ConversationMember 用户在会话中的个人状态
+// 免打扰、置顶等设置只对本人生效，双方（或群成员）各有一行
+type ConversationMember struct {
+	ID               uint       `gorm:"primarykey" json:"id"`
+	ConversationID   uint       `gorm:"not null;uniqueIndex:uk_conversation_user,priority:1" json:"conversation_id"`
+	UserID           uint       `gorm:"not null;uniqueIndex:uk_conversation_user,priority:2;index:idx_user_hidden,priority:1" json:"user_id"`
+	IsMuted          bool       `gorm:"default:false" json:"is_muted"`
+	IsTop            bool       `gorm:"default:false" json:"is_top"`
+	IsStarred        bool       `gorm:"default:false" json:"is_starred"`
+	IsBlocked        bool       `gorm:"default:false" json:"is_blocked"`
+	IsHidden         bool       `gorm:"default:false;index:idx_user_hidden,priority:2" json:"is_hidden"` // 从会话列表移除，收到新消息后恢复
+	UnreadCount      int        `gorm:"not null;default:0" json:"unread_count"`
+	LastReadSeq      uint64     `gorm:"not null;default:0" json:"last_read_seq"` // 已读到的消息序号
+	ClearedSeq       uint64     `gorm:"not null;default:0" json:"cleared_seq"`   // 清空聊天记录时的序号，之前的消息对本人不可见
+	MentionMessageID *uint      `json:"mention_message_id,omitempty"`            // 最早一条未读的@本人消息，已读后清空
+	Draft            string     `gorm:"type:text" json:"draft"`
+	DraftUpdatedAt   *time.Time `json:"draft_updated_at,omitempty"`
+	CreatedAt        time.Time  `json:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at"`
+}
+
+func (ConversationMember) TableName() string {
+	return "conversation_members"
+}
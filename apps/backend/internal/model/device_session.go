@@ -0,0 +1,68 @@
+package model
+
+import (
+	"time"
+)
+
+// DeviceSession 设备登录会话
+// 每次登录创建一个会话，签发的Token携带会话ID；会话被撤销后该设备的Token全部失效
+type DeviceSession struct {
+	ID           uint       `gorm:"primarykey" json:"id"`
+	UserID       uint       `gorm:"not null;index" json:"user_id"`
+	DeviceID     string     `gorm:"size:64;not null" json:"device_id"` // 客户端生成的设备标识
+	DeviceName   string     `gorm:"size:100" json:"device_name"`       // 如 "Xiaomi 14"、"MacBook Pro"
+	Platform     string     `gorm:"type:enum('android','ios','windows','macos','linux','web','unknown');default:'unknown'" json:"platform"`
+	AppVersion   string     `gorm:"size:32" json:"app_version"`
+	LastIP       string     `gorm:"size:45" json:"last_ip"`
+	CreatedAt    time.Time  `json:"created_at"`
+	LastActiveAt time.Time  `json:"last_active_at"`
+	ExpiresAt    time.Time  `json:"expires_at"` // 最近一次签发的Token的过期时间
+	RevokedAt    *time.Time `gorm:"index" json:"revoked_at,omitempty"`
+	RevokeReason string     `gorm:"size:20" json:"revoke_reason,omitempty"`
+
+	// 是否为发起请求的会话（不存储）
+	Current bool `gorm:"-" json:"current"`
+}
+
+func (DeviceSession) TableName() string {
+	return "device_sessions"
+}
+
+// Platform 常量
+const (
+	PlatformAndroid = "android"
+	PlatformIOS     = "ios"
+	PlatformWindows = "windows"
+	PlatformMacOS   = "macos"
+	PlatformLinux   = "linux"
+	PlatformWeb     = "web"
+	PlatformUnknown = "unknown" // 未上报设备信息的旧版客户端
+)
+
+// 平台类别，同类设备共用登录数量限制
+const (
+	PlatformClassMobile  = "mobile"
+	PlatformClassDesktop = "desktop"
+	PlatformClassWeb     = "web"
+	PlatformClassUnknown = "unknown"
+)
+
+// PlatformClass 返回平台所属类别，无法识别的平台归为unknown
+func PlatformClass(platform string) string {
+	switch platform {
+	case PlatformAndroid, PlatformIOS:
+		return PlatformClassMobile
+	case PlatformWindows, PlatformMacOS, PlatformLinux:
+		return PlatformClassDesktop
+	case PlatformWeb:
+		return PlatformClassWeb
+	}
+	return PlatformClassUnknown
+}
+
+// 会话撤销原因
+const (
+	SessionRevokeLogout   = "logout"   // 用户退出登录
+	SessionRevokeRevoked  = "revoked"  // 用户在设备列表中移除
+	SessionRevokeReplaced = "replaced" // 同一设备或同类设备的新登录挤掉
+)
@@ -1,36 +1,80 @@
 package model
 
 import (
+	"encoding/json"
 	"time"
+
 	"gorm.io/gorm"
 )
 
 type Message struct {
 	ID             uint           `gorm:"primarykey" json:"id"`
-	ConversationID uint           `gorm:"not null;index" json:"conversation_id"`
-	SenderID       uint           `gorm:"not null;index" json:"sender_id"`
+	ConversationID uint           `gorm:"not null;index;uniqueIndex:uk_conversation_seq,priority:1" json:"conversation_id"`
+	Seq            uint64         `gorm:"not null;default:0;uniqueIndex:uk_conversation_seq,priority:2" json:"seq"` // 会话内单调递增序号
+	SenderID       uint           `gorm:"not null;index;uniqueIndex:uk_sender_client_msg,priority:1" json:"sender_id"`
 	ReceiverID     uint           `gorm:"index" json:"receiver_id"` // 群消息时为0
 	GroupID        *uint          `gorm:"index" json:"group_id,omitempty"` // 群消息ID，单聊时为null
+	ClientMsgID    *string        `gorm:"size:64;uniqueIndex:uk_sender_client_msg,priority:2" json:"client_msg_id,omitempty"` // 客户端生成的消息ID，用于重试去重
 	Content        string         `gorm:"type:text;not null" json:"content"`
-	Type           string         `gorm:"type:enum('text','image','voice','video','file');default:'text'" json:"type"`
+	Type           string         `gorm:"type:enum('text','image','voice','video','file','chat_record','location','card','sticker','rich_text','system');default:'text'" json:"type"`
 	FileURL        string         `gorm:"size:500" json:"file_url,omitempty"`
 	FileSize       int64          `json:"file_size,omitempty"`
 	Duration       int            `json:"duration,omitempty"` // 语音/视频时长（秒）
 	Status         string         `gorm:"type:enum('sent','delivered','read','recalled');default:'sent';index" json:"status"`
+	EditedAt       *time.Time     `json:"edited_at,omitempty"` // 最后一次编辑时间
+	EditCount      int            `gorm:"not null;default:0" json:"edit_count"` // 编辑次数
+
+	// 结构化内容（位置、名片、聊天记录等），content为其纯文本摘要，用于搜索、会话列表预览和不支持该类型的客户端
+	Payload        json.RawMessage `gorm:"type:json" json:"payload,omitempty"`
+	PayloadVersion int             `gorm:"not null;default:0" json:"payload_version,omitempty"` // payload结构版本
+
+	// 引用回复与话题
+	ReplyToMessageID  *uint      `gorm:"index" json:"reply_to_message_id,omitempty"`   // 引用回复的消息
+	ThreadRootID      *uint      `gorm:"index" json:"thread_root_id,omitempty"`        // 所属话题的根消息
+	ThreadReplyCount  int        `gorm:"not null;default:0" json:"thread_reply_count"` // 话题回复数（根消息）
+	LastThreadReplyID *uint      `json:"last_thread_reply_id,omitempty"`               // 最后一条话题回复（根消息）
+	LastThreadReplyAt *time.Time `json:"last_thread_reply_at,omitempty"`
+
+	// @提醒（群消息）
+	MentionUserIDs []uint `gorm:"serializer:json;type:json" json:"mention_user_ids,omitempty"` // 被@的成员
+	MentionAll     bool   `gorm:"default:false" json:"mention_all,omitempty"`                  // @所有人
+
+	// 转发来源
+	ForwardFromID   *uint `gorm:"index" json:"forward_from_id,omitempty"` // 转发的源消息
+	ForwardSenderID *uint `json:"forward_sender_id,omitempty"`           // 源消息的原始发送者，多次转发时保留最初的发送者
+
+	// 阅后即焚：按发送时会话的设置写入，到期后由MessageReaper删除
+	ExpireSeconds int        `gorm:"not null;default:0" json:"expire_seconds,omitempty"` // 消息的存活时长，0表示不过期
+	ExpiresAt     *time.Time `gorm:"index" json:"expires_at,omitempty"`                  // 过期时间，已读后计时的消息在首次被读取时写入
+
+	// 表情回应汇总，查询消息列表时填充，不落库
+	Reactions []ReactionSummary `gorm:"-" json:"reactions,omitempty"`
+
 	CreatedAt      time.Time      `json:"created_at"`
 	UpdatedAt      time.Time      `json:"updated_at"`
 	DeletedAt      gorm.DeletedAt `gorm:"index" json:"-"`
 	
 	// 关联
-	Sender   User   `gorm:"foreignKey:SenderID" json:"sender,omitempty"`
-	Receiver User   `gorm:"foreignKey:ReceiverID" json:"receiver,omitempty"`
-	Group    *Group `gorm:"foreignKey:GroupID" json:"group,omitempty"`
+	Sender   User     `gorm:"foreignKey:SenderID" json:"sender,omitempty"`
+	Receiver User     `gorm:"foreignKey:ReceiverID" json:"receiver,omitempty"`
+	Group    *Group   `gorm:"foreignKey:GroupID" json:"group,omitempty"`
+	ReplyTo  *Message `gorm:"foreignKey:ReplyToMessageID" json:"reply_to,omitempty"`
 }
 
 func (Message) TableName() string {
 	return "messages"
 }
 
+// Expired 消息是否已过期（等待清理）
+func (m *Message) Expired(now time.Time) bool {
+	return m.ExpiresAt != nil && !m.ExpiresAt.After(now)
+}
+
+// Disappearing 是否为阅后即焚消息
+func (m *Message) Disappearing() bool {
+	return m.ExpireSeconds > 0 || m.ExpiresAt != nil
+}
+
 // MessageType 常量
 const (
 	MessageTypeText  = "text"
@@ -38,6 +82,13 @@ const (
 	MessageTypeVoice = "voice"
 	MessageTypeVideo = "video"
 	MessageTypeFile  = "file"
+
+	MessageTypeChatRecord = "chat_record" // 合并转发的聊天记录，payload为ChatRecord
+	MessageTypeLocation   = "location"    // payload为LocationPayload
+	MessageTypeCard       = "card"        // payload为CardPayload
+	MessageTypeSticker    = "sticker"     // payload为StickerPayload
+	MessageTypeRichText   = "rich_text"   // payload为RichTextPayload
+	MessageTypeSystem     = "system"      // 服务端生成，payload为SystemPayload
 )
 
 // MessageStatus 常量
@@ -46,5 +97,43 @@ const (
 	MessageStatusDelivered = "delivered"
 	MessageStatusRead      = "read"
 	MessageStatusRecalled  = "recalled"
+	MessageStatusDeleted   = "deleted" // 仅用于引用预览的占位，表示被引用的消息已删除，不落库
+)
+
+// 引用预览的占位文本
+const (
+	ReplyPlaceholderRecalled = "[消息已撤回]"
+	ReplyPlaceholderDeleted  = "[消息已删除]"
 )
 
+// MaskReplyPreview 被引用的消息已撤回或已删除（含阅后即焚过期）时，把引用预览替换为占位，不返回原内容
+// ReplyTo须已预加载，被删除的消息预加载结果为空
+func (m *Message) MaskReplyPreview() {
+	if m.ReplyToMessageID == nil {
+		return
+	}
+
+	if m.ReplyTo == nil {
+		m.ReplyTo = &Message{
+			ID:             *m.ReplyToMessageID,
+			ConversationID: m.ConversationID,
+			Type:           MessageTypeText,
+			Content:        ReplyPlaceholderDeleted,
+			Status:         MessageStatusDeleted,
+		}
+		return
+	}
+
+	if m.ReplyTo.Status == MessageStatusRecalled {
+		m.ReplyTo = &Message{
+			ID:             m.ReplyTo.ID,
+			ConversationID: m.ReplyTo.ConversationID,
+			SenderID:       m.ReplyTo.SenderID,
+			Type:           MessageTypeText,
+			Content:        ReplyPlaceholderRecalled,
+			Status:         MessageStatusRecalled,
+			CreatedAt:      m.ReplyTo.CreatedAt,
+		}
+	}
+}
+
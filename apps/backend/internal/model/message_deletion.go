@@ -0,0 +1,18 @@
+package model
+
+import (
+	"time"
+)
+
+// MessageDeletion 用户"仅对自己删除"的消息，其他参与者不受影响
+type MessageDeletion struct {
+	ID             uint      `gorm:"primarykey" json:"id"`
+	UserID         uint      `gorm:"not null;uniqueIndex:uk_user_message,priority:1" json:"user_id"`
+	MessageID      uint      `gorm:"not null;uniqueIndex:uk_user_message,priority:2;index" json:"message_id"`
+	ConversationID uint      `gorm:"not null;index" json:"conversation_id"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+func (MessageDeletion) TableName() string {
+	return "message_deletions"
+}
@@ -0,0 +1,19 @@
+package model
+
+import (
+	"time"
+)
+
+// MessageEdit 消息编辑历史，每次编辑保存一行
+type MessageEdit struct {
+	ID              uint      `gorm:"primarykey" json:"id"`
+	MessageID       uint      `gorm:"not null;index" json:"message_id"`
+	EditorID        uint      `gorm:"not null" json:"editor_id"`
+	PreviousContent string    `gorm:"type:text;not null" json:"previous_content"` // 编辑前的内容
+	Content         string    `gorm:"type:text;not null" json:"content"`          // 编辑后的内容
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+func (MessageEdit) TableName() string {
+	return "message_edits"
+}
@@ -0,0 +1,68 @@
+package model
+
+// MessagePayloadVersion 当前的消息payload结构版本
+// payload结构发生不兼容变更时递增，客户端按payload_version解析
+const MessagePayloadVersion = 1
+
+// LocationPayload 位置消息
+type LocationPayload struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	Title     string  `json:"title"`
+	Address   string  `json:"address,omitempty"`
+}
+
+// 名片类型
+const (
+	CardTypeUser  = "user"
+	CardTypeGroup = "group"
+)
+
+// CardPayload 用户名片或群名片，名称和头像由服务端按target_id填充
+type CardPayload struct {
+	CardType string `json:"card_type"`
+	TargetID uint   `json:"target_id"`
+	Name     string `json:"name"`
+	Avatar   string `json:"avatar,omitempty"`
+}
+
+// StickerPayload 表情贴图
+type StickerPayload struct {
+	StickerID string `json:"sticker_id"`
+	PackageID string `json:"package_id,omitempty"`
+	URL       string `json:"url"`
+	Width     int    `json:"width,omitempty"`
+	Height    int    `json:"height,omitempty"`
+}
+
+// 富文本格式
+const (
+	RichTextFormatMarkdown = "markdown"
+)
+
+// RichTextPayload 富文本消息，content为去掉格式的纯文本
+type RichTextPayload struct {
+	Format string `json:"format"`
+	Text   string `json:"text"`
+}
+
+// 系统通知事件
+const (
+	SystemEventMemberAdded     = "group_member_added"   // target_ids为新成员
+	SystemEventMemberRemoved   = "group_member_removed" // target_ids为被移除的成员
+	SystemEventGroupUpdated    = "group_updated"        // extra中为修改后的name/avatar
+	SystemEventGroupDisbanded  = "group_disbanded"
+	SystemEventMessageRecalled = "message_recalled" // target_ids为原消息发送者，extra中为message_id和recalled_as
+
+	SystemEventDisappearingUpdated = "disappearing_updated" // extra中为修改后的mode和seconds
+	SystemEventMessagePinned       = "message_pinned"       // target_ids为被置顶消息的发送者，extra中为message_id
+)
+
+// SystemPayload 服务端生成的系统通知（如"X加入了群聊"、"Y撤回了一条消息"）
+// content为渲染好的通知文本，客户端也可以按event和ID自行渲染
+type SystemPayload struct {
+	Event      string                 `json:"event"`
+	OperatorID uint                   `json:"operator_id,omitempty"`
+	TargetIDs  []uint                 `json:"target_ids,omitempty"`
+	Extra      map[string]interface{} `json:"extra,omitempty"`
+}
@@ -0,0 +1,21 @@
+package model
+
+import (
+	"time"
+)
+
+// MessagePin 会话中置顶的消息，对会话所有参与者可见
+type MessagePin struct {
+	ID             uint      `gorm:"primarykey" json:"id"`
+	ConversationID uint      `gorm:"not null;uniqueIndex:uk_conversation_message,priority:1" json:"conversation_id"`
+	MessageID      uint      `gorm:"not null;uniqueIndex:uk_conversation_message,priority:2;index" json:"message_id"`
+	PinnedBy       uint      `gorm:"not null" json:"pinned_by"` // 置顶操作者
+	CreatedAt      time.Time `json:"created_at"`
+
+	// 关联
+	Message *Message `gorm:"foreignKey:MessageID" json:"message,omitempty"`
+}
+
+func (MessagePin) TableName() string {
+	return "message_pins"
+}
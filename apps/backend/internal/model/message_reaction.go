@@ -0,0 +1,25 @@
+package model
+
+import (
+	"time"
+)
+
+// MessageReaction 消息表情回应，同一用户对同一消息的同一表情只保留一条
+type MessageReaction struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	MessageID uint      `gorm:"not null;uniqueIndex:uk_message_user_emoji,priority:1" json:"message_id"`
+	UserID    uint      `gorm:"not null;uniqueIndex:uk_message_user_emoji,priority:2;index" json:"user_id"`
+	Emoji     string    `gorm:"size:32;not null;uniqueIndex:uk_message_user_emoji,priority:3" json:"emoji"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (MessageReaction) TableName() string {
+	return "message_reactions"
+}
+
+// ReactionSummary 单个表情在一条消息上的汇总
+type ReactionSummary struct {
+	Emoji   string `json:"emoji"`
+	Count   int    `json:"count"`
+	Reacted bool   `json:"reacted"` // 当前用户是否回应过该表情
+}
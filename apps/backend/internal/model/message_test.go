@@ -0,0 +1,57 @@
+package model
+
+import "testing"
+
+func TestMaskReplyPreview(t *testing.T) {
+	replyToID := uint(10)
+
+	t.Run("recalled message hides original content", func(t *testing.T) {
+		m := Message{ID: 11, ConversationID: 1, ReplyToMessageID: &replyToID, ReplyTo: &Message{
+			ID:             replyToID,
+			ConversationID: 1,
+			SenderID:       2,
+			Type:           MessageTypeImage,
+			Content:        "[图片]",
+			FileURL:        "https://example.com/a.jpg",
+			Status:         MessageStatusRecalled,
+		}}
+		m.MaskReplyPreview()
+
+		if m.ReplyTo.Content != ReplyPlaceholderRecalled || m.ReplyTo.Status != MessageStatusRecalled {
+			t.Errorf("ReplyTo = %+v, want recalled placeholder", m.ReplyTo)
+		}
+		if m.ReplyTo.FileURL != "" || m.ReplyTo.Type != MessageTypeText {
+			t.Errorf("recalled preview leaks original message: %+v", m.ReplyTo)
+		}
+		if m.ReplyTo.ID != replyToID || m.ReplyTo.SenderID != 2 {
+			t.Errorf("ReplyTo = %+v, want id and sender kept", m.ReplyTo)
+		}
+	})
+
+	t.Run("deleted message gets a placeholder", func(t *testing.T) {
+		m := Message{ID: 11, ConversationID: 1, ReplyToMessageID: &replyToID}
+		m.MaskReplyPreview()
+
+		if m.ReplyTo == nil || m.ReplyTo.ID != replyToID || m.ReplyTo.Status != MessageStatusDeleted || m.ReplyTo.Content != ReplyPlaceholderDeleted {
+			t.Errorf("ReplyTo = %+v, want deleted placeholder", m.ReplyTo)
+		}
+	})
+
+	t.Run("visible message unchanged", func(t *testing.T) {
+		original := &Message{ID: replyToID, Content: "hello", Status: MessageStatusRead}
+		m := Message{ID: 11, ReplyToMessageID: &replyToID, ReplyTo: original}
+		m.MaskReplyPreview()
+
+		if m.ReplyTo != original || m.ReplyTo.Content != "hello" {
+			t.Errorf("ReplyTo = %+v, want original message", m.ReplyTo)
+		}
+	})
+
+	t.Run("no reply", func(t *testing.T) {
+		m := Message{ID: 11}
+		m.MaskReplyPreview()
+		if m.ReplyTo != nil {
+			t.Errorf("ReplyTo = %+v, want nil", m.ReplyTo)
+		}
+	})
+}
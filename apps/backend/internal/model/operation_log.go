@@ -31,13 +31,17 @@ const (
 	ActionUserRegister       = "user_register"
 	ActionPasswordChange     = "password_change"
 	ActionUserProfileUpdate  = "user_profile_update"
+	ActionSessionRevoke      = "session_revoke"
 )
 
 // 消息操作
 const (
-	ActionMessageSend   = "message_send"
-	ActionMessageRecall = "message_recall"
-	ActionMessageDelete = "message_delete"
+	ActionMessageSend     = "message_send"
+	ActionMessageRecall   = "message_recall"
+	ActionMessageDelete   = "message_delete"
+	ActionMessageEdit     = "message_edit"
+	ActionMessageForward  = "message_forward"
+	ActionMessageSchedule = "message_schedule"
 )
 
 // 联系人操作
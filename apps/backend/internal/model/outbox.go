@@ -0,0 +1,37 @@
+package model
+
+import (
+	"time"
+)
+
+// OutboxEvent 事务发件箱事件
+// 与业务数据在同一事务中写入，由OutboxRelay异步投递到Kafka，保证数据库和事件不会不一致
+type OutboxEvent struct {
+	ID            uint       `gorm:"primarykey" json:"id"`
+	AggregateType string     `gorm:"size:50;not null" json:"aggregate_type"`                      // 业务对象类型，如message
+	AggregateID   uint       `gorm:"not null" json:"aggregate_id"`                                // 业务对象ID
+	Topic         string     `gorm:"size:100;not null" json:"topic"`                              // 目标Kafka Topic
+	MessageKey    string     `gorm:"size:100;index:idx_key_status,priority:1" json:"message_key"` // Kafka消息Key（决定分区），同Key事件按ID顺序投递
+	Payload       string     `gorm:"type:text;not null" json:"payload"`                           // JSON消息体
+	Status        string     `gorm:"type:enum('pending','sent');default:'pending';index:idx_status_next_attempt,priority:1;index:idx_key_status,priority:2" json:"status"`
+	Attempts      int        `gorm:"default:0" json:"attempts"`
+	LastError     string     `gorm:"size:500" json:"last_error,omitempty"`
+	NextAttemptAt time.Time  `gorm:"index:idx_status_next_attempt,priority:2" json:"next_attempt_at"`
+	CreatedAt     time.Time  `gorm:"index" json:"created_at"`
+	SentAt        *time.Time `json:"sent_at,omitempty"`
+}
+
+func (OutboxEvent) TableName() string {
+	return "outbox"
+}
+
+// OutboxStatus 常量
+const (
+	OutboxStatusPending = "pending"
+	OutboxStatusSent    = "sent"
+)
+
+// OutboxAggregate 常量
+const (
+	OutboxAggregateMessage = "message"
+)
@@ -0,0 +1,44 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// ScheduledMessage 定时消息
+// 到期后由ScheduledMessageDispatcher按普通消息发送，发送结果写回message_id或last_error
+type ScheduledMessage struct {
+	ID             uint            `gorm:"primarykey" json:"id"`
+	SenderID       uint            `gorm:"not null;index:idx_sender_status,priority:1" json:"sender_id"`
+	ReceiverID     *uint           `json:"receiver_id,omitempty"` // 单聊接收者，与group_id二选一
+	GroupID        *uint           `json:"group_id,omitempty"`    // 群聊
+	Content        string          `gorm:"type:text;not null" json:"content"`
+	Type           string          `gorm:"size:20;not null;default:'text'" json:"type"`
+	FileURL        string          `gorm:"size:500" json:"file_url,omitempty"`
+	FileSize       int64           `json:"file_size,omitempty"`
+	Duration       int             `json:"duration,omitempty"`
+	Payload        json.RawMessage `gorm:"type:json" json:"payload,omitempty"`
+	MentionUserIDs []uint          `gorm:"serializer:json;type:json" json:"mention_user_ids,omitempty"`
+	MentionAll     bool            `gorm:"default:false" json:"mention_all,omitempty"`
+	ScheduledAt    time.Time       `gorm:"not null;index:idx_status_scheduled,priority:2" json:"scheduled_at"` // 计划发送时间
+	Status         string          `gorm:"type:enum('pending','dispatching','sent','failed','canceled');default:'pending';index:idx_status_scheduled,priority:1;index:idx_sender_status,priority:2" json:"status"`
+	ClaimedAt      *time.Time      `json:"-"`                    // 调度器领取时间，超过租约未完成时重新领取
+	MessageID      *uint           `json:"message_id,omitempty"` // 发送成功后的消息ID
+	LastError      string          `gorm:"size:500" json:"last_error,omitempty"`
+	SentAt         *time.Time      `json:"sent_at,omitempty"`
+	CreatedAt      time.Time       `json:"created_at"`
+	UpdatedAt      time.Time       `json:"updated_at"`
+}
+
+func (ScheduledMessage) TableName() string {
+	return "scheduled_messages"
+}
+
+// ScheduledMessageStatus 常量
+const (
+	ScheduledStatusPending     = "pending"
+	ScheduledStatusDispatching = "dispatching" // 已被调度器领取，正在发送
+	ScheduledStatusSent        = "sent"
+	ScheduledStatusFailed      = "failed"
+	ScheduledStatusCanceled    = "canceled"
+)
@@ -25,6 +25,12 @@ func (User) TableName() string {
 	return "users"
 }
 
+// UserRole 常量
+const (
+	UserRoleUser  = "user"
+	UserRoleAdmin = "admin" // 平台管理员
+)
+
 // UserResponse 用于API响应的用户信息（不包含敏感信息）
 type UserResponse struct {
 	ID          uint       `json:"id"`
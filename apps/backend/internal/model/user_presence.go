@@ -0,0 +1,28 @@
+package model
+
+import (
+	"time"
+)
+
+// UserPresence 用户的在线相关设置和最后在线时间
+// 在线状态本身只存在于WebSocket连接和Redis中，这里保存需要持久化的部分
+type UserPresence struct {
+	UserID                uint       `gorm:"primarykey" json:"user_id"`
+	LastSeenAt            *time.Time `json:"last_seen_at"`                                 // 由Redis定期写回，可能落后于实际值
+	CustomStatus          string     `gorm:"size:100" json:"custom_status"`                // 自定义状态文本
+	CustomStatusExpiresAt *time.Time `json:"custom_status_expires_at"`                     // 为空表示不过期
+	HideLastSeen          bool       `gorm:"not null;default:false" json:"hide_last_seen"` // 对其他用户隐藏最后在线时间
+	UpdatedAt             time.Time  `json:"updated_at"`
+}
+
+func (UserPresence) TableName() string {
+	return "user_presence"
+}
+
+// ActiveCustomStatus 返回未过期的自定义状态文本
+func (p *UserPresence) ActiveCustomStatus(now time.Time) string {
+	if p.CustomStatusExpiresAt != nil && !p.CustomStatusExpiresAt.After(now) {
+		return ""
+	}
+	return p.CustomStatus
+}
@@ -15,19 +15,21 @@ var (
 )
 
 type Claims struct {
-	UserID   uint   `json:"user_id"`
-	Username string `json:"username"`
-	Role     string `json:"role"`
+	UserID    uint   `json:"user_id"`
+	SessionID uint   `json:"sid,omitempty"` // 设备会话ID，旧版Token没有
+	Username  string `json:"username"`
+	Role      string `json:"role"`
 	jwt.RegisteredClaims
 }
 
-// GenerateToken generates a JWT token
-func GenerateToken(userID uint, username, role, secret string, expireHours int) (string, error) {
+// GenerateToken generates a JWT token bound to a device session
+func GenerateToken(userID, sessionID uint, username, role, secret string, expireHours int) (string, error) {
 	now := time.Now()
 	claims := Claims{
-		UserID:   userID,
-		Username: username,
-		Role:     role,
+		UserID:    userID,
+		SessionID: sessionID,
+		Username:  username,
+		Role:      role,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(now.Add(time.Duration(expireHours) * time.Hour)),
 			IssuedAt:  jwt.NewNumericDate(now),
@@ -70,7 +72,7 @@ func RefreshToken(oldToken, secret string, expireHours int) (string, error) {
 		return "", err
 	}
 
-	// Generate new token with same user info but new expiration
-	return GenerateToken(claims.UserID, claims.Username, claims.Role, secret, expireHours)
+	// Generate new token with same user info and session but new expiration
+	return GenerateToken(claims.UserID, claims.SessionID, claims.Username, claims.Role, secret, expireHours)
 }
 
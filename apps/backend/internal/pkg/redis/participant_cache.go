@@ -0,0 +1,67 @@
+package redis
+
+import (
+	"strconv"
+	"time"
+)
+
+// 会话参与者缓存的过期时间
+const participantCacheTTL = time.Hour
+
+// CacheParticipants 缓存会话的参与者ID列表
+// 用途：输入状态等高频信号只查Redis确定转发对象，不访问数据库
+func CacheParticipants(conversationID uint, userIDs []uint) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+
+	key := getParticipantCacheKey(conversationID)
+	members := make([]interface{}, len(userIDs))
+	for i, id := range userIDs {
+		members[i] = strconv.FormatUint(uint64(id), 10)
+	}
+
+	pipe := Client.TxPipeline()
+	pipe.Del(ctx, key)
+	pipe.SAdd(ctx, key, members...)
+	pipe.Expire(ctx, key, participantCacheTTL)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// GetCachedParticipants 获取缓存的会话参与者
+// 返回：bool - 是否找到缓存
+func GetCachedParticipants(conversationID uint) ([]uint, bool, error) {
+	members, err := Client.SMembers(ctx, getParticipantCacheKey(conversationID)).Result()
+	if err != nil {
+		return nil, false, err
+	}
+	if len(members) == 0 {
+		return nil, false, nil
+	}
+
+	userIDs := make([]uint, 0, len(members))
+	for _, member := range members {
+		id, err := strconv.ParseUint(member, 10, 64)
+		if err != nil {
+			continue
+		}
+		userIDs = append(userIDs, uint(id))
+	}
+	return userIDs, true, nil
+}
+
+// RefreshParticipants 延长会话参与者缓存的有效期，活跃会话的缓存不会过期
+func RefreshParticipants(conversationID uint) error {
+	return Client.Expire(ctx, getParticipantCacheKey(conversationID), participantCacheTTL).Err()
+}
+
+// InvalidateParticipants 使会话参与者缓存失效，群成员变化时调用
+func InvalidateParticipants(conversationID uint) error {
+	return Client.Del(ctx, getParticipantCacheKey(conversationID)).Err()
+}
+
+// getParticipantCacheKey 生成会话参与者缓存key
+func getParticipantCacheKey(conversationID uint) string {
+	return "conversation:participants:" + strconv.FormatUint(uint64(conversationID), 10)
+}
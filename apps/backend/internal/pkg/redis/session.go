@@ -0,0 +1,51 @@
+package redis
+
+import (
+	"strconv"
+	"time"
+)
+
+const (
+	// 会话活跃时间的最短更新间隔
+	sessionTouchInterval = 5 * time.Minute
+	// 未撤销状态的缓存时间
+	sessionActiveCacheTTL = time.Minute
+)
+
+// RevokeSession 标记设备会话已撤销
+// 参数：sessionID - 会话ID
+//      expireTime - 标记保留时间（秒），不短于该会话签发的Token的有效期
+// 用途：会话被撤销后，该设备持有的所有Token（包括刷新得到的）立即失效
+func RevokeSession(sessionID uint, expireTime int) error {
+	return Client.Set(ctx, getSessionRevokedKey(sessionID), "1", time.Duration(expireTime)*time.Second).Err()
+}
+
+// SessionRevocation 查询Redis中缓存的会话状态
+// 返回值：revoked - 会话已撤销
+//        known - Redis中有该会话的记录；未命中或Redis出错时为false，调用方需要回源数据库
+func SessionRevocation(sessionID uint) (revoked, known bool) {
+	result, err := Client.Get(ctx, getSessionRevokedKey(sessionID)).Result()
+	if err != nil {
+		return false, false
+	}
+	return result == "1", true
+}
+
+// MarkSessionActive 缓存数据库确认过的未撤销状态，减少回源查询
+// 使用SetNX，不会覆盖同时写入的撤销标记；缓存时间很短，撤销标记写入失败时最多延迟这么久生效
+func MarkSessionActive(sessionID uint) {
+	Client.SetNX(ctx, getSessionRevokedKey(sessionID), "0", sessionActiveCacheTTL)
+}
+
+// ShouldTouchSession 判断是否需要更新会话的最近活跃时间
+// 同一会话每5分钟最多返回一次true，避免每个请求都写数据库
+func ShouldTouchSession(sessionID uint) bool {
+	key := "session:touched:" + strconv.FormatUint(uint64(sessionID), 10)
+	ok, err := Client.SetNX(ctx, key, "1", sessionTouchInterval).Result()
+	return err == nil && ok
+}
+
+// getSessionRevokedKey 生成会话撤销标记key
+func getSessionRevokedKey(sessionID uint) string {
+	return "session:revoked:" + strconv.FormatUint(uint64(sessionID), 10)
+}
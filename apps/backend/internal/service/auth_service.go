@@ -2,25 +2,29 @@ package service
 
 import (
 	"errors"
+	"strconv"
 	"time"
 
 	"github.com/lanxin/im-backend/config"
 	"github.com/lanxin/im-backend/internal/dao"
 	"github.com/lanxin/im-backend/internal/model"
 	"github.com/lanxin/im-backend/internal/pkg/jwt"
+	"github.com/lanxin/im-backend/internal/websocket"
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 )
 
 type AuthService struct {
-	userDAO *dao.UserDAO
-	cfg     *config.Config
+	userDAO        *dao.UserDAO
+	sessionService *SessionService
+	cfg            *config.Config
 }
 
-func NewAuthService(cfg *config.Config) *AuthService {
+func NewAuthService(cfg *config.Config, hub *websocket.Hub) *AuthService {
 	return &AuthService{
-		userDAO: dao.NewUserDAO(),
-		cfg:     cfg,
+		userDAO:        dao.NewUserDAO(),
+		sessionService: NewSessionService(cfg, hub),
+		cfg:            cfg,
 	}
 }
 
@@ -78,7 +82,8 @@ func (s *AuthService) Register(username, password, phone, email string) (*model.
 }
 
 // Login 用户登录
-func (s *AuthService) Login(identifier, password string) (string, *model.User, error) {
+// 每次登录创建一个设备会话，签发的Token携带会话ID
+func (s *AuthService) Login(identifier, password string, device DeviceInfo) (string, *model.User, *model.DeviceSession, error) {
 	var user *model.User
 	var err error
 
@@ -87,7 +92,7 @@ func (s *AuthService) Login(identifier, password string) (string, *model.User, e
 		if user, err = s.userDAO.GetByPhone(identifier); err != nil {
 			if user, err = s.userDAO.GetByEmail(identifier); err != nil {
 				if user, err = s.userDAO.GetByLanxinID(identifier); err != nil {
-					return "", nil, errors.New("user not found")
+					return "", nil, nil, errors.New("user not found")
 				}
 			}
 		}
@@ -95,15 +100,15 @@ func (s *AuthService) Login(identifier, password string) (string, *model.User, e
 
 	// 检查账号状态
 	if user.Status == "banned" {
-		return "", nil, errors.New("account is banned")
+		return "", nil, nil, errors.New("account is banned")
 	}
 	if user.Status == "deleted" {
-		return "", nil, errors.New("account is deleted")
+		return "", nil, nil, errors.New("account is deleted")
 	}
 
 	// 验证密码
 	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
-		return "", nil, errors.New("incorrect password")
+		return "", nil, nil, errors.New("incorrect password")
 	}
 
 	// 更新最后登录时间
@@ -111,24 +116,66 @@ func (s *AuthService) Login(identifier, password string) (string, *model.User, e
 		// 记录错误但不影响登录流程
 	}
 
+	// 创建设备会话（可能挤掉同类平台上较早登录的设备）
+	session, err := s.sessionService.Create(user.ID, device)
+	if err != nil {
+		return "", nil, nil, err
+	}
+
 	// 生成JWT Token
-	token, err := jwt.GenerateToken(user.ID, user.Username, user.Role, s.cfg.JWT.Secret, s.cfg.JWT.ExpireHours)
+	token, err := jwt.GenerateToken(user.ID, session.ID, user.Username, user.Role, s.cfg.JWT.Secret, s.cfg.JWT.ExpireHours)
 	if err != nil {
-		return "", nil, err
+		return "", nil, nil, err
 	}
 
-	return token, user, nil
+	return token, user, session, nil
 }
 
 // RefreshToken 刷新令牌
-func (s *AuthService) RefreshToken(oldToken string) (string, error) {
-	return jwt.RefreshToken(oldToken, s.cfg.JWT.Secret, s.cfg.JWT.ExpireHours)
+// 携带会话的Token只有在会话仍有效时才能刷新，刷新后会话有效期随之延长
+func (s *AuthService) RefreshToken(oldToken, ip string) (string, error) {
+	claims, err := jwt.ParseToken(oldToken, s.cfg.JWT.Secret)
+	if err != nil {
+		return "", err
+	}
+
+	if claims.SessionID != 0 {
+		active, err := s.sessionService.IsActive(claims.UserID, claims.SessionID)
+		if err != nil {
+			return "", err
+		}
+		if !active {
+			return "", errors.New("session has been revoked")
+		}
+	}
+
+	token, err := jwt.RefreshToken(oldToken, s.cfg.JWT.Secret, s.cfg.JWT.ExpireHours)
+	if err != nil {
+		return "", err
+	}
+
+	if claims.SessionID != 0 {
+		if err := s.sessionService.Refresh(claims.SessionID, ip); err != nil {
+			return "", err
+		}
+	}
+	return token, nil
+}
+
+// GetSessions 获取登录设备列表
+func (s *AuthService) GetSessions(userID, currentSessionID uint) ([]model.DeviceSession, error) {
+	return s.sessionService.List(userID, currentSessionID)
+}
+
+// RevokeSession 让指定设备下线
+func (s *AuthService) RevokeSession(userID, sessionID uint, reason, ip, userAgent string) error {
+	return s.sessionService.Revoke(userID, sessionID, reason, ip, userAgent)
 }
 
 // generateLanxinID 生成蓝信号
 func generateLanxinID() string {
 	// 使用时间戳作为基础
 	timestamp := time.Now().Unix()
-	return "lx" + string(timestamp%1000000000)
+	return "lx" + strconv.FormatInt(timestamp%1000000000, 10)
 }
 
@@ -0,0 +1,58 @@
+package service
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/lanxin/im-backend/internal/dao"
+)
+
+// 变更日志的清理间隔和每批删除的条数
+const (
+	changePruneInterval = time.Hour
+	changePruneBatch    = 5000
+)
+
+// ChangeLogPruner 会话变更日志清理器
+// 每小时删除超过保留期的变更，分批删除避免长事务；多节点同时运行时只是重复删除，不影响结果
+type ChangeLogPruner struct {
+	changeDAO *dao.ConversationChangeDAO
+}
+
+// NewChangeLogPruner 创建会话变更日志清理器
+func NewChangeLogPruner() *ChangeLogPruner {
+	return &ChangeLogPruner{
+		changeDAO: dao.NewConversationChangeDAO(),
+	}
+}
+
+// Run 定期清理，直到ctx取消
+func (p *ChangeLogPruner) Run(ctx context.Context) {
+	ticker := time.NewTicker(changePruneInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			p.prune(ctx)
+		}
+	}
+}
+
+// prune 删除超过保留期的会话变更日志
+func (p *ChangeLogPruner) prune(ctx context.Context) {
+	before := time.Now().Add(-changeRetention)
+	for ctx.Err() == nil {
+		n, err := p.changeDAO.PruneBefore(before, changePruneBatch)
+		if err != nil {
+			log.Printf("Failed to prune conversation changes: %v", err)
+			return
+		}
+		if n < changePruneBatch {
+			return
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/lanxin/im-backend/internal/pkg/redis"
+	"github.com/lanxin/im-backend/internal/websocket"
+)
+
+// 会话信号类型，信号只经Hub转发，不落库
+const (
+	SignalTyping         = "typing"          // 正在输入
+	SignalRecordingVoice = "recording_voice" // 正在录音
+)
+
+// 信号状态
+const (
+	SignalStateStart = "start"
+	SignalStateStop  = "stop"
+)
+
+const (
+	signalThrottle = 3 * time.Second // 同一用户在同一会话中同类信号的最短转发间隔
+	signalExpiry   = 6 * time.Second // 接收方在此时间内没有收到刷新时自动清除信号
+)
+
+// RelaySignal 把会话信号转发给会话中的其他参与者
+// 单聊转发给对方，群聊转发给在线的群成员；参与者取自会话参与者缓存，每次转发都延长缓存有效期，
+// 缓存未命中时从数据库加载并写回缓存，之后同一会话的信号只访问Redis
+// start在节流间隔内重复发送时只刷新有效期不转发，stop只在信号仍有效时转发
+func (s *MessageService) RelaySignal(userID, conversationID uint, signal, state string) error {
+	switch signal {
+	case SignalTyping, SignalRecordingVoice:
+	default:
+		return errors.New("unsupported signal")
+	}
+	if state == "" {
+		state = SignalStateStart
+	}
+	if state != SignalStateStart && state != SignalStateStop {
+		return errors.New("invalid signal state")
+	}
+
+	participants, err := s.signalParticipants(conversationID)
+	if err != nil {
+		return err
+	}
+	if !containsUser(participants, userID) {
+		return errors.New("not a conversation participant")
+	}
+
+	relay, err := s.throttleSignal(conversationID, userID, signal, state)
+	if err != nil || !relay {
+		return err
+	}
+
+	// SendToUser只投递给有连接的用户，群里离线的成员不会收到
+	for _, uid := range participants {
+		if uid == userID {
+			continue
+		}
+		s.hub.SendToUser(uid, websocket.WebSocketMessage{
+			Type: signal,
+			Data: map[string]interface{}{
+				"conversation_id": conversationID,
+				"user_id":         userID,
+				"state":           state,
+				"expires_in":      int(signalExpiry / time.Second),
+			},
+		})
+	}
+	return nil
+}
+
+// signalParticipants 获取信号的转发对象，优先读缓存，未命中时从数据库加载（getParticipants会写回缓存）
+func (s *MessageService) signalParticipants(conversationID uint) ([]uint, error) {
+	participants, found, err := redis.GetCachedParticipants(conversationID)
+	if err != nil {
+		return nil, err
+	}
+	if found {
+		if err := redis.RefreshParticipants(conversationID); err != nil {
+			log.Printf("Failed to refresh participants of conversation %d: %v", conversationID, err)
+		}
+		return participants, nil
+	}
+
+	conv, err := s.conversationDAO.GetByID(conversationID)
+	if err != nil {
+		return nil, errors.New("conversation not found")
+	}
+	return s.getParticipants(conv)
+}
+
+// throttleSignal 记录信号状态并判断是否需要转发
+// 有效期key记录信号是否仍在接收方显示，节流key限制start的转发频率，两者都由Redis自动过期
+func (s *MessageService) throttleSignal(conversationID, userID uint, signal, state string) (bool, error) {
+	ctx := context.Background()
+	activeKey := fmt.Sprintf("signal:%d:%d:%s", conversationID, userID, signal)
+	throttleKey := activeKey + ":throttle"
+
+	if state == SignalStateStop {
+		pipe := s.redisClient.TxPipeline()
+		active := pipe.Del(ctx, activeKey)
+		pipe.Del(ctx, throttleKey)
+		if _, err := pipe.Exec(ctx); err != nil {
+			return false, err
+		}
+		return active.Val() > 0, nil
+	}
+
+	pipe := s.redisClient.TxPipeline()
+	pipe.Set(ctx, activeKey, 1, signalExpiry)
+	acquired := pipe.SetNX(ctx, throttleKey, 1, signalThrottle)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return false, err
+	}
+	return acquired.Val(), nil
+}
+
+// cacheParticipants 写入会话参与者缓存，失败只记录日志（信号在缓存缺失时从数据库加载）
+func cacheParticipants(conversationID uint, participants []uint) {
+	if err := redis.CacheParticipants(conversationID, participants); err != nil {
+		log.Printf("Failed to cache participants of conversation %d: %v", conversationID, err)
+	}
+}
+
+// invalidateParticipants 群成员变化后清除参与者缓存，下次读取参与者时重新写入
+func invalidateParticipants(conversationID uint) {
+	if err := redis.InvalidateParticipants(conversationID); err != nil {
+		log.Printf("Failed to invalidate participants of conversation %d: %v", conversationID, err)
+	}
+}
@@ -0,0 +1,79 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lanxin/im-backend/internal/dao"
+	"github.com/lanxin/im-backend/internal/model"
+	"github.com/lanxin/im-backend/internal/pkg/redis"
+	"github.com/lanxin/im-backend/internal/testutil"
+	"github.com/lanxin/im-backend/internal/websocket"
+)
+
+func newSignalTestService() *MessageService {
+	return &MessageService{
+		conversationDAO: dao.NewConversationDAO(),
+		groupMemberDAO:  dao.NewGroupMemberDAO(),
+		hub:             websocket.NewHub(),
+		redisClient:     redis.GetClient(),
+	}
+}
+
+func TestRelaySignalLoadsParticipantsOnCacheMiss(t *testing.T) {
+	mock := testutil.NewMockDB(t)
+	mr := testutil.NewRedis(t)
+	s := newSignalTestService()
+
+	mock.ExpectQuery("SELECT \\* FROM `conversations` WHERE id = \\?").
+		WithArgs(12).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "type", "user1_id", "user2_id"}).AddRow(12, model.ConversationTypeSingle, 1, 2))
+
+	if err := s.RelaySignal(1, 12, SignalTyping, SignalStateStart); err != nil {
+		t.Fatalf("RelaySignal() error = %v", err)
+	}
+	if !mr.Exists("conversation:participants:12") {
+		t.Error("participants should be cached after loading from the database")
+	}
+	if !mr.Exists("signal:12:1:typing") {
+		t.Error("signal should be relayed on a cache miss")
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRelaySignalRejectsNonParticipant(t *testing.T) {
+	mock := testutil.NewMockDB(t)
+	testutil.NewRedis(t)
+	s := newSignalTestService()
+
+	mock.ExpectQuery("SELECT \\* FROM `conversations` WHERE id = \\?").
+		WithArgs(12).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "type", "user1_id", "user2_id"}).AddRow(12, model.ConversationTypeSingle, 1, 2))
+
+	err := s.RelaySignal(3, 12, SignalTyping, SignalStateStart)
+	if err == nil || err.Error() != "not a conversation participant" {
+		t.Fatalf("RelaySignal() error = %v, want not a conversation participant", err)
+	}
+}
+
+func TestRelaySignalRefreshesParticipantCache(t *testing.T) {
+	testutil.NewMockDB(t)
+	mr := testutil.NewRedis(t)
+	s := newSignalTestService()
+
+	if err := redis.CacheParticipants(12, []uint{1, 2}); err != nil {
+		t.Fatal(err)
+	}
+	mr.FastForward(50 * time.Minute)
+
+	// 缓存命中时不访问数据库，并把有效期延长到完整的TTL
+	if err := s.RelaySignal(1, 12, SignalTyping, SignalStateStart); err != nil {
+		t.Fatalf("RelaySignal() error = %v", err)
+	}
+	if ttl := mr.TTL("conversation:participants:12"); ttl != time.Hour {
+		t.Errorf("participant cache TTL = %s, want 1h", ttl)
+	}
+}
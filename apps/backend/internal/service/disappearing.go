@@ -0,0 +1,108 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lanxin/im-backend/internal/model"
+	"github.com/lanxin/im-backend/internal/pkg/mysql"
+	"gorm.io/gorm"
+)
+
+const (
+	minDisappearSeconds = 5                // 最短存活时长
+	maxDisappearSeconds = 7 * 24 * 60 * 60 // 最长存活时长（7天）
+)
+
+// applyDisappearing 按会话的阅后即焚设置写入消息的存活时长
+// 发送后计时的消息直接写入过期时间，已读后计时的消息在首次被读取时写入
+func applyDisappearing(conv *model.Conversation, message *model.Message) {
+	if conv.DisappearSeconds <= 0 {
+		return
+	}
+	switch conv.DisappearMode {
+	case model.DisappearModeAfterSend:
+		message.ExpireSeconds = conv.DisappearSeconds
+		expiresAt := time.Now().Add(time.Duration(conv.DisappearSeconds) * time.Second)
+		message.ExpiresAt = &expiresAt
+	case model.DisappearModeAfterRead:
+		message.ExpireSeconds = conv.DisappearSeconds
+	}
+}
+
+// SetDisappearing 修改会话的阅后即焚设置
+// 单聊双方都可以修改，群聊只有群主和管理员可以修改；修改后在会话中写入系统通知
+func (s *MessageService) SetDisappearing(conversationID, userID uint, mode string, seconds int) (*model.Conversation, error) {
+	switch mode {
+	case model.DisappearModeOff:
+		seconds = 0
+	case model.DisappearModeAfterRead, model.DisappearModeAfterSend:
+		if seconds < minDisappearSeconds || seconds > maxDisappearSeconds {
+			return nil, fmt.Errorf("seconds must be between %d and %d", minDisappearSeconds, maxDisappearSeconds)
+		}
+	default:
+		return nil, errors.New("invalid disappear mode")
+	}
+
+	conv, err := s.conversationDAO.GetByID(conversationID)
+	if err != nil {
+		return nil, errors.New("conversation not found")
+	}
+
+	if err := s.requireConversationManager(conv, userID, "change disappearing messages"); err != nil {
+		return nil, err
+	}
+
+	if conv.DisappearMode == mode && conv.DisappearSeconds == seconds {
+		return conv, nil
+	}
+	// 设置和变更日志在同一事务中写入，离线设备同步时拿到新设置
+	err = mysql.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := s.conversationDAO.WithTx(tx).UpdateDisappearing(conversationID, mode, seconds); err != nil {
+			return err
+		}
+		return s.changeDAO.WithTx(tx).Record(conversationID, 0, model.ChangeConversationUpdated, nil)
+	})
+	if err != nil {
+		return nil, err
+	}
+	conv.DisappearMode = mode
+	conv.DisappearSeconds = seconds
+
+	operatorName := s.systemMessages.userName(userID)
+	s.systemMessages.post(conv, userID, model.SystemPayload{
+		Event:      model.SystemEventDisappearingUpdated,
+		OperatorID: userID,
+		Extra: map[string]interface{}{
+			"mode":    mode,
+			"seconds": seconds,
+		},
+	}, disappearingNotice(operatorName, mode, seconds))
+
+	return conv, nil
+}
+
+// disappearingNotice 阅后即焚设置变更的通知文本
+func disappearingNotice(operatorName, mode string, seconds int) string {
+	switch mode {
+	case model.DisappearModeAfterRead:
+		return operatorName + " 开启了阅后即焚：消息在已读" + formatDuration(seconds) + "后消失"
+	case model.DisappearModeAfterSend:
+		return operatorName + " 开启了阅后即焚：消息在发送" + formatDuration(seconds) + "后消失"
+	}
+	return operatorName + " 关闭了阅后即焚"
+}
+
+// formatDuration 把秒数格式化为"30秒"、"5分钟"、"2小时"、"1天"
+func formatDuration(seconds int) string {
+	switch {
+	case seconds%86400 == 0:
+		return fmt.Sprintf("%d天", seconds/86400)
+	case seconds%3600 == 0:
+		return fmt.Sprintf("%d小时", seconds/3600)
+	case seconds%60 == 0:
+		return fmt.Sprintf("%d分钟", seconds/60)
+	}
+	return fmt.Sprintf("%d秒", seconds)
+}
@@ -0,0 +1,331 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/lanxin/im-backend/config"
+	"github.com/lanxin/im-backend/internal/dao"
+	"github.com/lanxin/im-backend/internal/model"
+	"github.com/lanxin/im-backend/internal/websocket"
+)
+
+// 转发方式
+const (
+	ForwardModeSingle = "single" // 逐条转发
+	ForwardModeMerged = "merged" // 合并为一条聊天记录
+)
+
+const (
+	maxForwardMessages = 100 // 单次最多转发的消息数
+	maxForwardTargets  = 20  // 单次最多转发的目标数（用户+群）
+)
+
+// ForwardTarget 转发目标类型
+const (
+	ForwardTargetUser  = "user"
+	ForwardTargetGroup = "group"
+)
+
+// ForwardResult 单个转发目标的结果
+// 目标和内容在发送前统一校验，校验不通过时整批拒绝；发送过程中的失败只影响该目标
+type ForwardResult struct {
+	TargetType string           `json:"target_type"`
+	TargetID   uint             `json:"target_id"`
+	Messages   []*model.Message `json:"messages,omitempty"`
+	Error      string           `json:"error,omitempty"`
+}
+
+// ForwardService 消息转发：逐条转发保留文件信息和原始发送者，合并转发生成聊天记录消息
+type ForwardService struct {
+	messageService  *MessageService
+	groupService    *GroupService
+	messageDAO      *dao.MessageDAO
+	conversationDAO *dao.ConversationDAO
+	groupDAO        *dao.GroupDAO
+	groupMemberDAO  *dao.GroupMemberDAO
+	userDAO         *dao.UserDAO
+	logDAO          *dao.OperationLogDAO
+}
+
+func NewForwardService(cfg *config.Config, hub *websocket.Hub) *ForwardService {
+	return &ForwardService{
+		messageService:  NewMessageService(cfg, hub),
+		groupService:    NewGroupService(cfg, hub),
+		messageDAO:      dao.NewMessageDAO(),
+		conversationDAO: dao.NewConversationDAO(),
+		groupDAO:        dao.NewGroupDAO(),
+		groupMemberDAO:  dao.NewGroupMemberDAO(),
+		userDAO:         dao.NewUserDAO(),
+		logDAO:          dao.NewOperationLogDAO(),
+	}
+}
+
+// ForwardMessages 把消息转发给用户和群
+// 转发者必须是源消息所在会话的参与者；合并转发要求源消息来自同一会话
+// 发送前校验所有目标和消息内容，任何一项不通过都不会发送，避免只转发给部分目标
+func (s *ForwardService) ForwardMessages(userID uint, messageIDs []uint, mode, title string, userIDs, groupIDs []uint, ip, userAgent string) ([]ForwardResult, error) {
+	if mode == "" {
+		mode = ForwardModeSingle
+	}
+	if mode != ForwardModeSingle && mode != ForwardModeMerged {
+		return nil, errors.New("invalid forward mode")
+	}
+	if len(messageIDs) == 0 || len(messageIDs) > maxForwardMessages {
+		return nil, fmt.Errorf("message_ids must contain 1 to %d messages", maxForwardMessages)
+	}
+	userIDs, groupIDs = dedupeIDs(userIDs), dedupeIDs(groupIDs)
+	if len(userIDs)+len(groupIDs) == 0 || len(userIDs)+len(groupIDs) > maxForwardTargets {
+		return nil, fmt.Errorf("targets must contain 1 to %d users or groups", maxForwardTargets)
+	}
+
+	sources, err := s.loadSources(userID, messageIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	var record *model.ChatRecord
+	if mode == ForwardModeMerged {
+		if record, err = s.buildChatRecord(sources, title); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.checkTargets(userID, userIDs, groupIDs); err != nil {
+		return nil, err
+	}
+	if err := s.checkContent(sources, record); err != nil {
+		return nil, err
+	}
+
+	results := make([]ForwardResult, 0, len(userIDs)+len(groupIDs))
+	for _, targetID := range userIDs {
+		results = append(results, s.forwardTo(userID, ForwardTargetUser, targetID, sources, record, ip, userAgent))
+	}
+	for _, targetID := range groupIDs {
+		results = append(results, s.forwardTo(userID, ForwardTargetGroup, targetID, sources, record, ip, userAgent))
+	}
+
+	failed := 0
+	for _, result := range results {
+		if result.Error != "" {
+			failed++
+		}
+	}
+	s.logDAO.CreateLog(dao.LogRequest{
+		Action:    model.ActionMessageForward,
+		UserID:    &userID,
+		IP:        ip,
+		UserAgent: userAgent,
+		Details: map[string]interface{}{
+			"message_ids":    messageIDs,
+			"mode":           mode,
+			"user_ids":       userIDs,
+			"group_ids":      groupIDs,
+			"failed_targets": failed,
+		},
+		Result: model.ResultSuccess,
+	})
+
+	return results, nil
+}
+
+// loadSources 加载源消息并校验转发者能看到这些消息
+// 重复的ID只转发一次
+func (s *ForwardService) loadSources(userID uint, messageIDs []uint) ([]model.Message, error) {
+	messageIDs = dedupeIDs(messageIDs)
+	// 用户删除或清空掉的消息视为不存在
+	sources, err := s.messageDAO.GetVisibleByIDs(userID, messageIDs)
+	if err != nil {
+		return nil, err
+	}
+	if len(sources) != len(messageIDs) {
+		return nil, errors.New("message not found")
+	}
+
+	// 同一会话只校验一次参与者身份
+	checked := make(map[uint]bool)
+	for _, src := range sources {
+		if src.Status == model.MessageStatusRecalled {
+			return nil, fmt.Errorf("message %d has been recalled", src.ID)
+		}
+		if src.Type == model.MessageTypeSystem {
+			return nil, errors.New("system messages cannot be forwarded")
+		}
+		if src.Disappearing() {
+			return nil, errors.New("disappearing messages cannot be forwarded")
+		}
+		if checked[src.ConversationID] {
+			continue
+		}
+
+		conv, err := s.conversationDAO.GetByID(src.ConversationID)
+		if err != nil {
+			return nil, errors.New("conversation not found")
+		}
+		participants, err := s.messageService.getParticipants(conv)
+		if err != nil {
+			return nil, err
+		}
+		if !containsUser(participants, userID) {
+			return nil, errors.New("not a conversation participant")
+		}
+		checked[src.ConversationID] = true
+	}
+
+	return sources, nil
+}
+
+// checkTargets 校验转发者可以向每个目标发送消息，规则与SendMessage、SendGroupMessage一致
+func (s *ForwardService) checkTargets(userID uint, userIDs, groupIDs []uint) error {
+	for _, targetID := range userIDs {
+		if _, err := s.userDAO.GetByID(targetID); err != nil {
+			return fmt.Errorf("user %d not found", targetID)
+		}
+	}
+	for _, groupID := range groupIDs {
+		if !s.groupMemberDAO.IsMember(groupID, userID) {
+			return fmt.Errorf("not a member of group %d", groupID)
+		}
+		if _, err := s.groupDAO.GetByID(groupID); err != nil {
+			return fmt.Errorf("group %d not found", groupID)
+		}
+	}
+	return nil
+}
+
+// checkContent 按发送时的规则校验要转发的内容，内容与目标无关，校验一次即可
+func (s *ForwardService) checkContent(sources []model.Message, record *model.ChatRecord) error {
+	if record != nil {
+		payload, err := json.Marshal(record)
+		if err != nil {
+			return err
+		}
+		scratch := &model.Message{Type: model.MessageTypeChatRecord}
+		return s.messageService.payloads.apply(scratch, SendOptions{Payload: payload, serverGenerated: true})
+	}
+
+	for i := range sources {
+		src := &sources[i]
+		scratch := &model.Message{
+			Content:  src.Content,
+			Type:     src.Type,
+			FileURL:  src.FileURL,
+			FileSize: src.FileSize,
+			Duration: src.Duration,
+		}
+		if err := s.messageService.payloads.apply(scratch, forwardOptions(src)); err != nil {
+			return fmt.Errorf("message %d cannot be forwarded: %v", src.ID, err)
+		}
+	}
+	return nil
+}
+
+// forwardOptions 逐条转发一条源消息的发送参数
+func forwardOptions(src *model.Message) SendOptions {
+	return SendOptions{
+		ForwardFrom: src,
+		Payload:     src.Payload,
+		// 聊天记录可以再次转发
+		serverGenerated: src.Type == model.MessageTypeChatRecord,
+	}
+}
+
+// buildChatRecord 生成合并转发的聊天记录快照
+func (s *ForwardService) buildChatRecord(sources []model.Message, title string) (*model.ChatRecord, error) {
+	conversationID := sources[0].ConversationID
+	for _, src := range sources {
+		if src.ConversationID != conversationID {
+			return nil, errors.New("merged forward requires messages from the same conversation")
+		}
+	}
+
+	if title == "" {
+		title = s.defaultRecordTitle(conversationID)
+	}
+
+	record := &model.ChatRecord{
+		Title:    title,
+		Messages: make([]model.ChatRecordItem, len(sources)),
+	}
+	for i, src := range sources {
+		record.Messages[i] = model.ChatRecordItem{
+			MessageID:    src.ID,
+			SenderID:     src.SenderID,
+			SenderName:   src.Sender.Username,
+			SenderAvatar: src.Sender.Avatar,
+			Content:      src.Content,
+			Type:         src.Type,
+			FileURL:      src.FileURL,
+			FileSize:     src.FileSize,
+			Duration:     src.Duration,
+			Payload:      src.Payload,
+			CreatedAt:    src.CreatedAt,
+		}
+	}
+	return record, nil
+}
+
+// defaultRecordTitle 聊天记录的默认标题：群聊为"群名的聊天记录"，单聊为"A和B的聊天记录"
+func (s *ForwardService) defaultRecordTitle(conversationID uint) string {
+	conv, err := s.conversationDAO.GetByID(conversationID)
+	if err != nil {
+		return "聊天记录"
+	}
+
+	if conv.Type == model.ConversationTypeGroup && conv.GroupID != nil {
+		if group, err := s.groupDAO.GetByID(*conv.GroupID); err == nil {
+			return group.Name + "的聊天记录"
+		}
+		return "群聊的聊天记录"
+	}
+
+	if conv.User1ID != nil && conv.User2ID != nil {
+		user1, err1 := s.userDAO.GetByID(*conv.User1ID)
+		user2, err2 := s.userDAO.GetByID(*conv.User2ID)
+		if err1 == nil && err2 == nil {
+			return user1.Username + "和" + user2.Username + "的聊天记录"
+		}
+	}
+	return "聊天记录"
+}
+
+// forwardTo 把源消息（或聊天记录）发送给一个目标
+func (s *ForwardService) forwardTo(userID uint, targetType string, targetID uint, sources []model.Message, record *model.ChatRecord, ip, userAgent string) ForwardResult {
+	result := ForwardResult{TargetType: targetType, TargetID: targetID}
+
+	send := func(content, msgType string, fileURL *string, fileSize *int64, duration *int, opts SendOptions) (*model.Message, error) {
+		if targetType == ForwardTargetGroup {
+			return s.groupService.SendGroupMessage(targetID, userID, content, msgType, fileURL, fileSize, duration, "", opts)
+		}
+		return s.messageService.SendMessage(userID, targetID, content, msgType, fileURL, fileSize, duration, "", opts, ip, userAgent)
+	}
+
+	if record != nil {
+		payload, err := json.Marshal(record)
+		if err != nil {
+			result.Error = err.Error()
+			return result
+		}
+		message, err := send("", model.MessageTypeChatRecord, nil, nil, nil, SendOptions{Payload: payload, serverGenerated: true})
+		if err != nil {
+			result.Error = err.Error()
+			return result
+		}
+		result.Messages = append(result.Messages, message)
+		return result
+	}
+
+	for i := range sources {
+		src := &sources[i]
+		message, err := send(src.Content, src.Type, &src.FileURL, &src.FileSize, &src.Duration, forwardOptions(src))
+		if err != nil {
+			// 已转发成功的消息保留在结果中
+			result.Error = err.Error()
+			return result
+		}
+		result.Messages = append(result.Messages, message)
+	}
+	return result
+}
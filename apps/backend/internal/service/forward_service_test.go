@@ -0,0 +1,97 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lanxin/im-backend/internal/dao"
+	"github.com/lanxin/im-backend/internal/model"
+	"github.com/lanxin/im-backend/internal/testutil"
+)
+
+func TestForwardLoadSourcesIgnoresDuplicateIDs(t *testing.T) {
+	mock := testutil.NewMockDB(t)
+	testutil.NewRedis(t)
+	s := &ForwardService{
+		messageService:  &MessageService{},
+		messageDAO:      dao.NewMessageDAO(),
+		conversationDAO: dao.NewConversationDAO(),
+	}
+
+	mock.ExpectQuery("SELECT \\* FROM `messages` WHERE id IN \\(\\?,\\?\\)").
+		WithArgs(100, 101, 1, 1, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "conversation_id", "seq", "sender_id", "receiver_id", "type", "status"}).
+			AddRow(100, 12, 1, 2, 1, model.MessageTypeText, model.MessageStatusSent).
+			AddRow(101, 12, 2, 1, 2, model.MessageTypeText, model.MessageStatusSent))
+	mock.ExpectQuery("SELECT \\* FROM `users`").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectQuery("SELECT \\* FROM `users`").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectQuery("SELECT \\* FROM `conversations` WHERE id = \\?").
+		WithArgs(12).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "type", "user1_id", "user2_id"}).AddRow(12, model.ConversationTypeSingle, 1, 2))
+
+	sources, err := s.loadSources(1, []uint{100, 101, 100})
+	if err != nil {
+		t.Fatalf("loadSources() error = %v", err)
+	}
+	if len(sources) != 2 {
+		t.Errorf("loadSources() returned %d messages, want 2", len(sources))
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestForwardCheckTargetsRejectsWholeBatch(t *testing.T) {
+	mock := testutil.NewMockDB(t)
+	s := &ForwardService{
+		userDAO:        dao.NewUserDAO(),
+		groupDAO:       dao.NewGroupDAO(),
+		groupMemberDAO: dao.NewGroupMemberDAO(),
+	}
+
+	mock.ExpectQuery("SELECT \\* FROM `users` WHERE id = \\?").
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username"}).AddRow(2, "lisi"))
+	mock.ExpectQuery("SELECT count\\(\\*\\) FROM `group_members` WHERE group_id = \\? AND user_id = \\?").
+		WithArgs(5, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	mock.ExpectQuery("SELECT \\* FROM `groups` WHERE").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "status"}).AddRow(5, "项目组", model.GroupStatusActive))
+	mock.ExpectQuery("SELECT \\* FROM `group_members` WHERE `group_members`.`group_id` = \\?").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "group_id", "user_id"}))
+	mock.ExpectQuery("SELECT count\\(\\*\\) FROM `group_members` WHERE group_id = \\? AND user_id = \\?").
+		WithArgs(6, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+
+	err := s.checkTargets(1, []uint{2}, []uint{5, 6})
+	if err == nil || err.Error() != "not a member of group 6" {
+		t.Fatalf("checkTargets() error = %v, want not a member of group 6", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestForwardCheckContent(t *testing.T) {
+	s := &ForwardService{messageService: &MessageService{payloads: &messagePayloadValidator{}}}
+
+	valid := []model.Message{
+		{ID: 1, Type: model.MessageTypeText, Content: "hi"},
+		{ID: 2, Type: model.MessageTypeImage, FileURL: "https://cdn/a.jpg", Content: "[图片]"},
+	}
+	if err := s.checkContent(valid, nil); err != nil {
+		t.Fatalf("checkContent() error = %v", err)
+	}
+
+	// 早期写入的没有文件地址的图片消息，转发时会被拒绝，整批都不发送
+	invalid := append(valid, model.Message{ID: 3, Type: model.MessageTypeImage})
+	err := s.checkContent(invalid, nil)
+	if err == nil || err.Error() != "message 3 cannot be forwarded: file_url required for image message" {
+		t.Fatalf("checkContent() error = %v", err)
+	}
+
+	record := &model.ChatRecord{Title: "聊天记录", Messages: []model.ChatRecordItem{{MessageID: 1, Content: "hi", Type: model.MessageTypeText}}}
+	if err := s.checkContent(valid, record); err != nil {
+		t.Fatalf("checkContent() with record error = %v", err)
+	}
+}
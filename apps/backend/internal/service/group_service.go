@@ -2,30 +2,54 @@ package service
 
 import (
 	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/lanxin/im-backend/config"
 	"github.com/lanxin/im-backend/internal/dao"
 	"github.com/lanxin/im-backend/internal/model"
+	"github.com/lanxin/im-backend/internal/pkg/mysql"
 	"github.com/lanxin/im-backend/internal/websocket"
+	"gorm.io/gorm"
 )
 
 type GroupService struct {
-	groupDAO       *dao.GroupDAO
-	groupMemberDAO *dao.GroupMemberDAO
-	conversationDAO *dao.ConversationDAO
-	userDAO        *dao.UserDAO
-	messageDAO     *dao.MessageDAO
-	logDAO         *dao.OperationLogDAO
-	hub            *websocket.Hub
+	groupDAO              *dao.GroupDAO
+	groupMemberDAO        *dao.GroupMemberDAO
+	conversationDAO       *dao.ConversationDAO
+	conversationMemberDAO *dao.ConversationMemberDAO
+	userDAO               *dao.UserDAO
+	messageDAO            *dao.MessageDAO
+	logDAO                *dao.OperationLogDAO
+	outboxDAO             *dao.OutboxDAO
+	changeDAO             *dao.ConversationChangeDAO
+	messageService        *MessageService // 群消息的投递与单聊共用
+	payloads              *messagePayloadValidator
+	systemMessages        *systemMessenger
+	hub                   *websocket.Hub
+	messageTopic          string
+	asyncDelivery         bool // 投递由Kafka消费者(cmd/worker)完成
 }
 
-func NewGroupService(hub *websocket.Hub) *GroupService {
+func NewGroupService(cfg *config.Config, hub *websocket.Hub) *GroupService {
+	messageService := NewMessageService(cfg, hub)
 	return &GroupService{
-		groupDAO:        dao.NewGroupDAO(),
-		groupMemberDAO:  dao.NewGroupMemberDAO(),
-		conversationDAO: dao.NewConversationDAO(),
-		userDAO:         dao.NewUserDAO(),
-		messageDAO:      dao.NewMessageDAO(),
-		logDAO:          dao.NewOperationLogDAO(),
-		hub:             hub,
+		groupDAO:              dao.NewGroupDAO(),
+		groupMemberDAO:        dao.NewGroupMemberDAO(),
+		conversationDAO:       dao.NewConversationDAO(),
+		conversationMemberDAO: dao.NewConversationMemberDAO(),
+		userDAO:               dao.NewUserDAO(),
+		messageDAO:            dao.NewMessageDAO(),
+		logDAO:                dao.NewOperationLogDAO(),
+		outboxDAO:             dao.NewOutboxDAO(),
+		changeDAO:             dao.NewConversationChangeDAO(),
+		messageService:        messageService,
+		payloads:              newMessagePayloadValidator(),
+		systemMessages:        messageService.systemMessages,
+		hub:                   hub,
+		messageTopic:          cfg.Kafka.Topic.Message,
+		asyncDelivery:         cfg.Kafka.AsyncDelivery,
 	}
 }
 
@@ -68,11 +92,18 @@ func (s *GroupService) CreateGroup(ownerID uint, name, avatar string, memberIDs
 	}
 
 	// 添加其他成员
+	joinedIDs := []uint{ownerID}
 	for _, memberID := range memberIDs {
 		if err := s.addMemberInternal(group.ID, memberID, model.GroupRoleMember); err != nil {
 			// 记录错误但继续
 			continue
 		}
+		joinedIDs = append(joinedIDs, memberID)
+	}
+
+	// 创建群会话，群聊出现在所有成员的会话列表中
+	if err := s.joinConversation(group.ID, joinedIDs...); err != nil {
+		return nil, err
 	}
 
 	// 记录操作日志
@@ -133,6 +164,7 @@ func (s *GroupService) AddMembers(groupID, operatorID uint, memberIDs []uint, ip
 
 	// 添加成员
 	successCount := 0
+	var joinedIDs []uint
 	for _, memberID := range memberIDs {
 		// 检查是否已是成员
 		if s.groupMemberDAO.IsMember(groupID, memberID) {
@@ -144,6 +176,7 @@ func (s *GroupService) AddMembers(groupID, operatorID uint, memberIDs []uint, ip
 		}
 
 		successCount++
+		joinedIDs = append(joinedIDs, memberID)
 
 		// 通知新成员
 		if s.hub.IsUserOnline(memberID) {
@@ -161,6 +194,20 @@ func (s *GroupService) AddMembers(groupID, operatorID uint, memberIDs []uint, ip
 	group.MemberCount += successCount
 	s.groupDAO.Update(group)
 
+	// 新成员加入群会话，入群前的消息不计未读
+	if err := s.joinConversation(groupID, joinedIDs...); err != nil {
+		return err
+	}
+
+	// 写入群历史，离线成员同步时也能看到
+	if len(joinedIDs) > 0 {
+		s.postSystemMessage(groupID, operatorID, model.SystemPayload{
+			Event:      model.SystemEventMemberAdded,
+			OperatorID: operatorID,
+			TargetIDs:  joinedIDs,
+		}, s.systemMessages.userName(operatorID)+" 邀请 "+s.systemMessages.userNames(joinedIDs)+" 加入了群聊")
+	}
+
 	// 记录日志
 	s.logDAO.CreateLog(dao.LogRequest{
 		Action:    "group_add_member",
@@ -205,6 +252,18 @@ func (s *GroupService) RemoveMember(groupID, operatorID, memberID uint, ip, user
 	count, _ := s.groupMemberDAO.GetMemberCount(groupID)
 	s.groupDAO.UpdateMemberCount(groupID, int(count))
 
+	// 移除通知在离开群会话之前写入，被移除的成员同步时也能看到
+	s.postSystemMessage(groupID, operatorID, model.SystemPayload{
+		Event:      model.SystemEventMemberRemoved,
+		OperatorID: operatorID,
+		TargetIDs:  []uint{memberID},
+	}, s.systemMessages.userName(operatorID)+" 将 "+s.systemMessages.userName(memberID)+" 移出了群聊")
+
+	// 群会话从被移除成员的会话列表中移除
+	if err := s.leaveConversation(groupID, memberID); err != nil {
+		return err
+	}
+
 	// 通知被移除的成员
 	if s.hub.IsUserOnline(memberID) {
 		s.hub.SendToUser(memberID, map[string]interface{}{
@@ -232,27 +291,24 @@ func (s *GroupService) RemoveMember(groupID, operatorID, memberID uint, ip, user
 }
 
 // SendGroupMessage 发送群消息
-func (s *GroupService) SendGroupMessage(groupID, senderID uint, content, msgType string, fileURL *string, fileSize *int64, duration *int) (*model.Message, error) {
-	// 验证发送者是否是群成员
+// clientMsgID 为客户端生成的去重ID，重试时返回已存在的消息而不是重复创建
+func (s *GroupService) SendGroupMessage(groupID, senderID uint, content, msgType string, fileURL *string, fileSize *int64, duration *int, clientMsgID string, opts SendOptions) (*model.Message, error) {
+	// 验证发送者是否是群成员，已解散的群不能再发消息
 	if !s.groupMemberDAO.IsMember(groupID, senderID) {
 		return nil, errors.New("not a group member")
 	}
-
-	// 获取或创建群会话
-	conversationID, err := s.conversationDAO.GetOrCreateGroupConversation(groupID)
-	if err != nil {
-		return nil, errors.New("failed to get or create group conversation")
+	if _, err := s.groupDAO.GetByID(groupID); err != nil {
+		return nil, errors.New("group not found")
 	}
 
 	// 创建消息
 	groupIDPtr := &groupID
 	message := &model.Message{
-		ConversationID: conversationID,
-		SenderID:       senderID,
-		GroupID:        groupIDPtr,
-		Content:        content,
-		Type:           msgType,
-		Status:         model.MessageStatusSent,
+		SenderID: senderID,
+		GroupID:  groupIDPtr,
+		Content:  content,
+		Type:     msgType,
+		Status:   model.MessageStatusSent,
 	}
 
 	if fileURL != nil {
@@ -264,23 +320,95 @@ func (s *GroupService) SendGroupMessage(groupID, senderID uint, content, msgType
 	if duration != nil {
 		message.Duration = *duration
 	}
+	if clientMsgID != "" {
+		message.ClientMsgID = &clientMsgID
+	}
+	if err := s.payloads.apply(message, opts); err != nil {
+		return nil, err
+	}
+
+	// 客户端重试：返回已创建的消息
+	if existing, err := findRetry(s.messageDAO, message); existing != nil || err != nil {
+		return existing, err
+	}
 
-	// 保存消息到数据库
-	if err := s.messageDAO.Create(message); err != nil {
+	// 校验@的成员和@所有人的权限
+	mentionIDs, err := s.resolveMentions(groupID, senderID, opts)
+	if err != nil {
 		return nil, err
 	}
 
-	// 获取所有群成员
-	members, err := s.groupMemberDAO.GetMembers(groupID)
+	// 获取或创建群会话
+	conversationID, err := s.conversationDAO.GetOrCreateGroupConversation(groupID)
 	if err != nil {
-		return message, nil
+		return nil, errors.New("failed to get or create group conversation")
+	}
+	conv, err := s.conversationDAO.GetByID(conversationID)
+	if err != nil {
+		return nil, errors.New("failed to get or create group conversation")
 	}
+	message.ConversationID = conversationID
+	if err := resolveMessageRefs(s.messageDAO, message, opts); err != nil {
+		return nil, err
+	}
+	applyDisappearing(conv, message)
+	message.MentionUserIDs = mentionIDs
+	message.MentionAll = opts.MentionAll
 
-	// 推送给在线成员（除了发送者自己）
-	for _, member := range members {
-		if member.UserID != senderID && s.hub.IsUserOnline(member.UserID) {
-			s.hub.SendMessageNotification(member.UserID, message)
+	// 消息（含会话内序号）、会话最后一条消息、各成员未读数和发件箱事件在同一事务中写入，与单聊一致
+	err = mysql.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := s.messageDAO.WithTx(tx).CreateWithSeq(message); err != nil {
+			return err
+		}
+
+		now := time.Now()
+		if err := s.conversationDAO.WithTx(tx).UpdateLastMessage(conversationID, message.ID, &now); err != nil {
+			return err
+		}
+		memberDAO := s.conversationMemberDAO.WithTx(tx)
+		if err := memberDAO.OnNewMessage(conversationID, senderID, message.Seq); err != nil {
+			return err
+		}
+		if message.MentionAll {
+			if err := memberDAO.MarkMentioned(conversationID, senderID, message.ID, nil); err != nil {
+				return err
+			}
+		} else if len(mentionIDs) > 0 {
+			if err := memberDAO.MarkMentioned(conversationID, senderID, message.ID, mentionIDs); err != nil {
+				return err
+			}
+		}
+		if message.ThreadRootID != nil {
+			if err := s.messageDAO.WithTx(tx).IncrThreadReply(*message.ThreadRootID, message.ID, message.CreatedAt); err != nil {
+				return err
+			}
+			if err := s.changeDAO.WithTx(tx).Record(conversationID, 0, model.ChangeThreadUpdated, message.ThreadRootID); err != nil {
+				return err
+			}
+		}
+
+		event, err := newMessageEvent(s.messageTopic, message)
+		if err != nil {
+			return err
+		}
+		return s.outboxDAO.WithTx(tx).Create(event)
+	})
+	if err != nil {
+		// 并发重试撞上唯一索引：返回先写入的那条
+		if existing, retryErr := findRetry(s.messageDAO, message); existing != nil || retryErr != nil {
+			return existing, retryErr
 		}
+		return nil, err
+	}
+
+	// 推送给除发送者外的成员：与单聊一样等待ACK，离线成员进入离线队列（异步投递模式下由worker消费Kafka完成）
+	if !s.asyncDelivery {
+		go s.messageService.DeliverMessage(message)
+	}
+
+	// 话题回复：通知成员更新根消息上的回复数
+	if message.ThreadRootID != nil {
+		go s.messageService.notifyThreadUpdated(*message.ThreadRootID)
 	}
 
 	return message, nil
@@ -304,12 +432,15 @@ func (s *GroupService) UpdateGroup(groupID, operatorID uint, name, avatar string
 		return err
 	}
 
-	// 更新字段
-	if name != "" {
+	// 更新字段，记录实际发生变化的内容
+	changes := map[string]interface{}{}
+	if name != "" && name != group.Name {
 		group.Name = name
+		changes["name"] = name
 	}
-	if avatar != "" {
+	if avatar != "" && avatar != group.Avatar {
 		group.Avatar = avatar
+		changes["avatar"] = avatar
 	}
 
 	// 保存更新
@@ -317,6 +448,15 @@ func (s *GroupService) UpdateGroup(groupID, operatorID uint, name, avatar string
 		return err
 	}
 
+	if len(changes) > 0 {
+		content := s.systemMessages.userName(operatorID) + " " + groupUpdatedNotice(changes)
+		s.postSystemMessage(groupID, operatorID, model.SystemPayload{
+			Event:      model.SystemEventGroupUpdated,
+			OperatorID: operatorID,
+			Extra:      changes,
+		}, content)
+	}
+
 	// 记录日志
 	s.logDAO.CreateLog(dao.LogRequest{
 		Action:    "group_update",
@@ -345,11 +485,26 @@ func (s *GroupService) DisbandGroup(groupID, operatorID uint, ip, userAgent stri
 	// 获取所有成员
 	members, _ := s.groupMemberDAO.GetMembers(groupID)
 
+	// 解散通知写入群历史，成员记录保留，离线成员同步时仍能收到
+	s.postSystemMessage(groupID, operatorID, model.SystemPayload{
+		Event:      model.SystemEventGroupDisbanded,
+		OperatorID: operatorID,
+	}, "群主 "+s.systemMessages.userName(operatorID)+" 解散了群聊")
+
 	// 删除群组（软删除）
 	if err := s.groupDAO.Delete(groupID); err != nil {
 		return err
 	}
 
+	// 群会话从所有成员的会话列表中移除
+	memberIDs := make([]uint, len(members))
+	for i, member := range members {
+		memberIDs[i] = member.UserID
+	}
+	if err := s.leaveConversation(groupID, memberIDs...); err != nil {
+		return err
+	}
+
 	// 通知所有成员
 	for _, member := range members {
 		if s.hub.IsUserOnline(member.UserID) {
@@ -387,3 +542,96 @@ func (s *GroupService) addMemberInternal(groupID, userID uint, role string) erro
 	return s.groupMemberDAO.Create(member)
 }
 
+// resolveMentions 校验@提醒：@所有人仅限群主和管理员，@的成员去重并过滤掉非群成员和发送者自己
+func (s *GroupService) resolveMentions(groupID, senderID uint, opts SendOptions) ([]uint, error) {
+	if opts.MentionAll {
+		role, err := s.groupMemberDAO.GetMemberRole(groupID, senderID)
+		if err != nil || (role != model.GroupRoleOwner && role != model.GroupRoleAdmin) {
+			return nil, errors.New("only owner or admin can mention all")
+		}
+	}
+	if len(opts.MentionUserIDs) == 0 {
+		return nil, nil
+	}
+
+	memberIDs, err := s.groupMemberDAO.GetMemberIDs(groupID)
+	if err != nil {
+		return nil, err
+	}
+
+	var mentionIDs []uint
+	for _, userID := range opts.MentionUserIDs {
+		if userID == senderID || !containsUser(memberIDs, userID) || containsUser(mentionIDs, userID) {
+			continue
+		}
+		mentionIDs = append(mentionIDs, userID)
+	}
+	return mentionIDs, nil
+}
+
+// joinConversation 把用户加入群会话（会话不存在时创建），已读位置从当前最新消息开始
+func (s *GroupService) joinConversation(groupID uint, userIDs ...uint) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+
+	conversationID, err := s.conversationDAO.GetOrCreateGroupConversation(groupID)
+	if err != nil {
+		return err
+	}
+	conv, err := s.conversationDAO.GetByID(conversationID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.conversationMemberDAO.JoinMembers(conversationID, conv.MaxSeq, userIDs...); err != nil {
+		return err
+	}
+	invalidateParticipants(conversationID)
+	return nil
+}
+
+// leaveConversation 删除用户在群会话中的状态
+func (s *GroupService) leaveConversation(groupID uint, userIDs ...uint) error {
+	conv, err := s.conversationDAO.GetByGroupID(groupID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	if err := s.conversationMemberDAO.RemoveMembers(conv.ID, userIDs...); err != nil {
+		return err
+	}
+	invalidateParticipants(conv.ID)
+	return nil
+}
+
+// groupUpdatedNotice 群信息修改通知中描述修改内容的部分，群名和头像同时修改时都列出
+func groupUpdatedNotice(changes map[string]interface{}) string {
+	var parts []string
+	if name, ok := changes["name"]; ok {
+		parts = append(parts, "修改群名为“"+name.(string)+"”")
+	}
+	if _, ok := changes["avatar"]; ok {
+		parts = append(parts, "更换了群头像")
+	}
+	return strings.Join(parts, "，并")
+}
+
+// postSystemMessage 在群会话中写入一条系统通知
+func (s *GroupService) postSystemMessage(groupID, operatorID uint, notice model.SystemPayload, content string) *model.Message {
+	conversationID, err := s.conversationDAO.GetOrCreateGroupConversation(groupID)
+	if err != nil {
+		log.Printf("Failed to get conversation of group %d for %s: %v", groupID, notice.Event, err)
+		return nil
+	}
+	conv, err := s.conversationDAO.GetByID(conversationID)
+	if err != nil {
+		log.Printf("Failed to load conversation %d for %s: %v", conversationID, notice.Event, err)
+		return nil
+	}
+
+	return s.systemMessages.post(conv, operatorID, notice, content)
+}
@@ -0,0 +1,24 @@
+package service
+
+import (
+	"testing"
+)
+
+func TestGroupUpdatedNotice(t *testing.T) {
+	tests := []struct {
+		name    string
+		changes map[string]interface{}
+		want    string
+	}{
+		{"name only", map[string]interface{}{"name": "新群名"}, "修改群名为“新群名”"},
+		{"avatar only", map[string]interface{}{"avatar": "https://cdn/a.png"}, "更换了群头像"},
+		{"both", map[string]interface{}{"name": "新群名", "avatar": "https://cdn/a.png"}, "修改群名为“新群名”，并更换了群头像"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := groupUpdatedNotice(tt.changes); got != tt.want {
+				t.Errorf("groupUpdatedNotice() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,215 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/lanxin/im-backend/internal/dao"
+	"github.com/lanxin/im-backend/internal/model"
+)
+
+const (
+	maxLocationTitleLength = 100
+	maxRichTextLength      = 20000
+)
+
+// 媒体消息没有文字内容时使用的摘要
+var mediaSummaries = map[string]string{
+	model.MessageTypeImage: "[图片]",
+	model.MessageTypeVoice: "[语音]",
+	model.MessageTypeVideo: "[视频]",
+	model.MessageTypeFile:  "[文件]",
+}
+
+// messagePayloadValidator 按消息类型校验内容，规范化payload并生成content摘要
+// MessageService和GroupService发送消息前都经过这里，单聊和群聊的规则一致
+type messagePayloadValidator struct {
+	userDAO  *dao.UserDAO
+	groupDAO *dao.GroupDAO
+}
+
+func newMessagePayloadValidator() *messagePayloadValidator {
+	return &messagePayloadValidator{
+		userDAO:  dao.NewUserDAO(),
+		groupDAO: dao.NewGroupDAO(),
+	}
+}
+
+// apply 校验message的类型和内容，写入规范化后的payload、payload版本和content摘要
+// 聊天记录和系统通知只能由服务端生成（opts.serverGenerated）
+func (v *messagePayloadValidator) apply(message *model.Message, opts SendOptions) error {
+	message.Content = strings.TrimSpace(message.Content)
+
+	var payload interface{}
+	switch message.Type {
+	case model.MessageTypeText:
+		if message.Content == "" {
+			return errors.New("content required")
+		}
+		// 纯文本不带payload
+		message.Payload = nil
+		message.PayloadVersion = 0
+		return nil
+
+	case model.MessageTypeImage, model.MessageTypeVoice, model.MessageTypeVideo, model.MessageTypeFile:
+		if message.FileURL == "" {
+			return errors.New("file_url required for " + message.Type + " message")
+		}
+		if message.FileSize < 0 || message.Duration < 0 {
+			return errors.New("invalid file_size or duration")
+		}
+		if message.Content == "" {
+			message.Content = mediaSummaries[message.Type]
+		}
+		message.Payload = nil
+		message.PayloadVersion = 0
+		return nil
+
+	case model.MessageTypeLocation:
+		var location model.LocationPayload
+		if err := decodePayload(opts.Payload, &location); err != nil {
+			return err
+		}
+		location.Title = strings.TrimSpace(location.Title)
+		if location.Latitude < -90 || location.Latitude > 90 || location.Longitude < -180 || location.Longitude > 180 {
+			return errors.New("invalid latitude or longitude")
+		}
+		if location.Title == "" || len([]rune(location.Title)) > maxLocationTitleLength {
+			return fmt.Errorf("location title must be 1 to %d characters", maxLocationTitleLength)
+		}
+		message.Content = "[位置] " + location.Title
+		payload = location
+
+	case model.MessageTypeCard:
+		var card model.CardPayload
+		if err := decodePayload(opts.Payload, &card); err != nil {
+			return err
+		}
+		if err := v.fillCard(&card); err != nil {
+			return err
+		}
+		if card.CardType == model.CardTypeGroup {
+			message.Content = "[群名片] " + card.Name
+		} else {
+			message.Content = "[个人名片] " + card.Name
+		}
+		payload = card
+
+	case model.MessageTypeSticker:
+		var sticker model.StickerPayload
+		if err := decodePayload(opts.Payload, &sticker); err != nil {
+			return err
+		}
+		if sticker.StickerID == "" || sticker.URL == "" {
+			return errors.New("sticker_id and url required")
+		}
+		if sticker.Width < 0 || sticker.Height < 0 {
+			return errors.New("invalid sticker size")
+		}
+		// content可以是表情的文字描述，例如"[捂脸]"
+		if message.Content == "" {
+			message.Content = "[表情]"
+		}
+		payload = sticker
+
+	case model.MessageTypeRichText:
+		var richText model.RichTextPayload
+		if err := decodePayload(opts.Payload, &richText); err != nil {
+			return err
+		}
+		if richText.Format == "" {
+			richText.Format = model.RichTextFormatMarkdown
+		}
+		if richText.Format != model.RichTextFormatMarkdown {
+			return errors.New("unsupported rich text format")
+		}
+		if strings.TrimSpace(richText.Text) == "" || len([]rune(richText.Text)) > maxRichTextLength {
+			return fmt.Errorf("rich text must be 1 to %d characters", maxRichTextLength)
+		}
+		// content为客户端提供的纯文本，未提供时使用原文
+		if message.Content == "" {
+			message.Content = richText.Text
+		}
+		payload = richText
+
+	case model.MessageTypeChatRecord:
+		if !opts.serverGenerated {
+			return errors.New("chat_record messages can only be created by forwarding")
+		}
+		var record model.ChatRecord
+		if err := decodePayload(opts.Payload, &record); err != nil {
+			return err
+		}
+		if len(record.Messages) == 0 {
+			return errors.New("chat record is empty")
+		}
+		message.Content = "[聊天记录] " + record.Title
+		payload = record
+
+	case model.MessageTypeSystem:
+		if !opts.serverGenerated {
+			return errors.New("system messages can only be created by the server")
+		}
+		var notice model.SystemPayload
+		if err := decodePayload(opts.Payload, &notice); err != nil {
+			return err
+		}
+		if notice.Event == "" || message.Content == "" {
+			return errors.New("system message requires event and content")
+		}
+		payload = notice
+
+	default:
+		return errors.New("unsupported message type")
+	}
+
+	normalized, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	message.Payload = normalized
+	message.PayloadVersion = model.MessagePayloadVersion
+	return nil
+}
+
+// fillCard 校验名片指向的用户或群存在，并用服务端数据填充名称和头像
+func (v *messagePayloadValidator) fillCard(card *model.CardPayload) error {
+	if card.TargetID == 0 {
+		return errors.New("card target_id required")
+	}
+
+	switch card.CardType {
+	case model.CardTypeUser:
+		user, err := v.userDAO.GetByID(card.TargetID)
+		if err != nil {
+			return errors.New("card user not found")
+		}
+		card.Name = user.Username
+		card.Avatar = user.Avatar
+
+	case model.CardTypeGroup:
+		group, err := v.groupDAO.GetByID(card.TargetID)
+		if err != nil || group.Status != model.GroupStatusActive {
+			return errors.New("card group not found")
+		}
+		card.Name = group.Name
+		card.Avatar = group.Avatar
+
+	default:
+		return errors.New("invalid card_type")
+	}
+	return nil
+}
+
+// decodePayload 解析消息payload
+func decodePayload(payload json.RawMessage, v interface{}) error {
+	if len(payload) == 0 || string(payload) == "null" {
+		return errors.New("payload required")
+	}
+	if err := json.Unmarshal(payload, v); err != nil {
+		return errors.New("invalid payload")
+	}
+	return nil
+}
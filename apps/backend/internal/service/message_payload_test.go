@@ -0,0 +1,193 @@
+package service
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lanxin/im-backend/internal/model"
+	"github.com/lanxin/im-backend/internal/testutil"
+)
+
+func TestPayloadValidatorApply(t *testing.T) {
+	v := &messagePayloadValidator{}
+
+	tests := []struct {
+		name        string
+		message     model.Message
+		opts        SendOptions
+		wantErr     string
+		wantContent string
+		wantPayload string
+	}{
+		{
+			name:        "text is trimmed and drops payload",
+			message:     model.Message{Type: model.MessageTypeText, Content: "  你好  "},
+			opts:        SendOptions{Payload: json.RawMessage(`{"x":1}`)},
+			wantContent: "你好",
+		},
+		{
+			name:    "empty text",
+			message: model.Message{Type: model.MessageTypeText, Content: "   "},
+			wantErr: "content required",
+		},
+		{
+			name:        "image gets default summary",
+			message:     model.Message{Type: model.MessageTypeImage, FileURL: "https://cdn/a.jpg"},
+			wantContent: "[图片]",
+		},
+		{
+			name:    "file without url",
+			message: model.Message{Type: model.MessageTypeFile},
+			wantErr: "file_url required for file message",
+		},
+		{
+			name:    "negative duration",
+			message: model.Message{Type: model.MessageTypeVoice, FileURL: "https://cdn/a.amr", Duration: -1},
+			wantErr: "invalid file_size or duration",
+		},
+		{
+			name:        "location is normalized",
+			message:     model.Message{Type: model.MessageTypeLocation, Content: "ignored"},
+			opts:        SendOptions{Payload: json.RawMessage(`{"latitude":31.23,"longitude":121.47,"title":" 人民广场 ","extra":true}`)},
+			wantContent: "[位置] 人民广场",
+			wantPayload: `{"latitude":31.23,"longitude":121.47,"title":"人民广场"}`,
+		},
+		{
+			name:    "location out of range",
+			message: model.Message{Type: model.MessageTypeLocation},
+			opts:    SendOptions{Payload: json.RawMessage(`{"latitude":91,"longitude":0,"title":"北极"}`)},
+			wantErr: "invalid latitude or longitude",
+		},
+		{
+			name:    "location without title",
+			message: model.Message{Type: model.MessageTypeLocation},
+			opts:    SendOptions{Payload: json.RawMessage(`{"latitude":1,"longitude":1}`)},
+			wantErr: "location title must be 1 to 100 characters",
+		},
+		{
+			name:    "missing payload",
+			message: model.Message{Type: model.MessageTypeSticker},
+			wantErr: "payload required",
+		},
+		{
+			name:    "malformed payload",
+			message: model.Message{Type: model.MessageTypeSticker},
+			opts:    SendOptions{Payload: json.RawMessage(`{"sticker_id":`)},
+			wantErr: "invalid payload",
+		},
+		{
+			name:        "sticker",
+			message:     model.Message{Type: model.MessageTypeSticker},
+			opts:        SendOptions{Payload: json.RawMessage(`{"sticker_id":"s1","url":"https://cdn/s1.png"}`)},
+			wantContent: "[表情]",
+			wantPayload: `{"sticker_id":"s1","url":"https://cdn/s1.png"}`,
+		},
+		{
+			name:    "sticker without url",
+			message: model.Message{Type: model.MessageTypeSticker},
+			opts:    SendOptions{Payload: json.RawMessage(`{"sticker_id":"s1"}`)},
+			wantErr: "sticker_id and url required",
+		},
+		{
+			name:        "rich text defaults to markdown",
+			message:     model.Message{Type: model.MessageTypeRichText},
+			opts:        SendOptions{Payload: json.RawMessage(`{"text":"**加粗**"}`)},
+			wantContent: "**加粗**",
+			wantPayload: `{"format":"markdown","text":"**加粗**"}`,
+		},
+		{
+			name:    "rich text unsupported format",
+			message: model.Message{Type: model.MessageTypeRichText},
+			opts:    SendOptions{Payload: json.RawMessage(`{"format":"html","text":"<b>x</b>"}`)},
+			wantErr: "unsupported rich text format",
+		},
+		{
+			name:    "rich text too long",
+			message: model.Message{Type: model.MessageTypeRichText},
+			opts:    SendOptions{Payload: json.RawMessage(`{"text":"` + strings.Repeat("字", maxRichTextLength+1) + `"}`)},
+			wantErr: "rich text must be 1 to 20000 characters",
+		},
+		{
+			name:    "client cannot send chat record",
+			message: model.Message{Type: model.MessageTypeChatRecord},
+			opts:    SendOptions{Payload: json.RawMessage(`{"title":"t","messages":[{"message_id":1}]}`)},
+			wantErr: "chat_record messages can only be created by forwarding",
+		},
+		{
+			name:        "forwarded chat record",
+			message:     model.Message{Type: model.MessageTypeChatRecord},
+			opts:        SendOptions{Payload: json.RawMessage(`{"title":"群聊的聊天记录","messages":[{"message_id":1,"content":"hi","type":"text"}]}`), serverGenerated: true},
+			wantContent: "[聊天记录] 群聊的聊天记录",
+		},
+		{
+			name:    "empty chat record",
+			message: model.Message{Type: model.MessageTypeChatRecord},
+			opts:    SendOptions{Payload: json.RawMessage(`{"title":"t","messages":[]}`), serverGenerated: true},
+			wantErr: "chat record is empty",
+		},
+		{
+			name:    "client cannot send system message",
+			message: model.Message{Type: model.MessageTypeSystem, Content: "x"},
+			opts:    SendOptions{Payload: json.RawMessage(`{"event":"group_updated"}`)},
+			wantErr: "system messages can only be created by the server",
+		},
+		{
+			name:    "unsupported type",
+			message: model.Message{Type: "poll", Content: "x"},
+			wantErr: "unsupported message type",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			message := tt.message
+			err := v.apply(&message, tt.opts)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("apply() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("apply() error = %v", err)
+			}
+			if message.Content != tt.wantContent {
+				t.Errorf("Content = %q, want %q", message.Content, tt.wantContent)
+			}
+			if tt.wantPayload != "" && string(message.Payload) != tt.wantPayload {
+				t.Errorf("Payload = %s, want %s", message.Payload, tt.wantPayload)
+			}
+			if message.Payload == nil && message.PayloadVersion != 0 || message.Payload != nil && message.PayloadVersion != model.MessagePayloadVersion {
+				t.Errorf("PayloadVersion = %d with payload %s", message.PayloadVersion, message.Payload)
+			}
+		})
+	}
+}
+
+func TestPayloadValidatorFillsCardFromServer(t *testing.T) {
+	mock := testutil.NewMockDB(t)
+	v := newMessagePayloadValidator()
+
+	mock.ExpectQuery("SELECT \\* FROM `users` WHERE id = \\?").
+		WithArgs(5).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "avatar"}).AddRow(5, "lisi", "https://cdn/lisi.png"))
+
+	message := model.Message{Type: model.MessageTypeCard}
+	payload := json.RawMessage(`{"card_type":"user","target_id":5,"name":"伪造的名字"}`)
+	if err := v.apply(&message, SendOptions{Payload: payload}); err != nil {
+		t.Fatalf("apply() error = %v", err)
+	}
+
+	if message.Content != "[个人名片] lisi" {
+		t.Errorf("Content = %q", message.Content)
+	}
+	var card model.CardPayload
+	if err := json.Unmarshal(message.Payload, &card); err != nil {
+		t.Fatal(err)
+	}
+	if card.Name != "lisi" || card.Avatar != "https://cdn/lisi.png" {
+		t.Errorf("card = %+v, want name and avatar from the server", card)
+	}
+}
@@ -0,0 +1,204 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"time"
+	"unicode/utf8"
+
+	"github.com/lanxin/im-backend/internal/model"
+	"github.com/lanxin/im-backend/internal/pkg/mysql"
+	"gorm.io/gorm"
+)
+
+// 置顶变更动作
+const (
+	pinActionPin   = "pin"
+	pinActionUnpin = "unpin"
+)
+
+// 置顶通知中消息摘要的最大长度
+const pinNoticeSummaryLength = 20
+
+// PinMessage 置顶消息
+// 单聊双方都可以置顶，群聊只有群主和管理员可以置顶；置顶后在会话中写入系统通知
+func (s *MessageService) PinMessage(messageID, userID uint) (*model.MessagePin, error) {
+	message, conv, err := s.pinTarget(messageID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if message.Status == model.MessageStatusRecalled {
+		return nil, errors.New("recalled messages cannot be pinned")
+	}
+	if message.Type == model.MessageTypeSystem {
+		return nil, errors.New("system messages cannot be pinned")
+	}
+	// 置顶者自己删除、清空掉或已过期的消息视为不存在
+	visible, err := s.messageDAO.IsVisible(userID, messageID)
+	if err != nil {
+		return nil, err
+	}
+	if !visible {
+		return nil, errors.New("message not found")
+	}
+
+	pin := &model.MessagePin{
+		ConversationID: conv.ID,
+		MessageID:      messageID,
+		PinnedBy:       userID,
+	}
+	err = mysql.GetDB().Transaction(func(tx *gorm.DB) error {
+		pinDAO := s.pinDAO.WithTx(tx)
+
+		// 锁定会话行，同一会话的并发置顶按顺序检查数量上限
+		if err := s.conversationDAO.WithTx(tx).Lock(conv.ID); err != nil {
+			return err
+		}
+		if pinDAO.Exists(conv.ID, messageID) {
+			return errors.New("message already pinned")
+		}
+		count, err := pinDAO.Count(conv.ID)
+		if err != nil {
+			return err
+		}
+		if count >= int64(s.maxPins) {
+			return fmt.Errorf("at most %d messages can be pinned in a conversation", s.maxPins)
+		}
+
+		if err := pinDAO.Create(pin); err != nil {
+			return err
+		}
+		return s.changeDAO.WithTx(tx).Record(conv.ID, 0, model.ChangePinUpdated, &messageID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	pin.Message = message
+
+	go s.notifyPinUpdated(conv.ID, messageID, userID, pinActionPin)
+
+	s.systemMessages.post(conv, userID, model.SystemPayload{
+		Event:      model.SystemEventMessagePinned,
+		OperatorID: userID,
+		TargetIDs:  []uint{message.SenderID},
+		Extra: map[string]interface{}{
+			"message_id": messageID,
+		},
+	}, pinNotice(s.systemMessages.userName(userID), message))
+
+	return pin, nil
+}
+
+// UnpinMessage 取消置顶，权限与置顶相同
+func (s *MessageService) UnpinMessage(messageID, userID uint) error {
+	_, conv, err := s.pinTarget(messageID, userID)
+	if err != nil {
+		return err
+	}
+
+	err = mysql.GetDB().Transaction(func(tx *gorm.DB) error {
+		unpinned, err := s.pinDAO.WithTx(tx).Delete(conv.ID, messageID)
+		if err != nil {
+			return err
+		}
+		if !unpinned {
+			return errors.New("message not pinned")
+		}
+		return s.changeDAO.WithTx(tx).Record(conv.ID, 0, model.ChangePinUpdated, &messageID)
+	})
+	if err != nil {
+		return err
+	}
+
+	go s.notifyPinUpdated(conv.ID, messageID, userID, pinActionUnpin)
+	return nil
+}
+
+// GetPins 获取会话中用户可见的置顶消息
+func (s *MessageService) GetPins(conversationID, userID uint) ([]model.MessagePin, error) {
+	conv, err := s.conversationDAO.GetByID(conversationID)
+	if err != nil {
+		return nil, errors.New("conversation not found")
+	}
+	participants, err := s.getParticipants(conv)
+	if err != nil {
+		return nil, err
+	}
+	if !containsUser(participants, userID) {
+		return nil, errors.New("not a conversation participant")
+	}
+
+	return s.pinDAO.ListVisible(userID, conversationID)
+}
+
+// pinTarget 加载要置顶的消息和会话，并校验用户有权修改该会话的置顶
+func (s *MessageService) pinTarget(messageID, userID uint) (*model.Message, *model.Conversation, error) {
+	message, err := s.messageDAO.GetByID(messageID)
+	if err != nil {
+		return nil, nil, errors.New("message not found")
+	}
+	conv, err := s.conversationDAO.GetByID(message.ConversationID)
+	if err != nil {
+		return nil, nil, errors.New("conversation not found")
+	}
+	if err := s.requireConversationManager(conv, userID, "pin messages"); err != nil {
+		return nil, nil, err
+	}
+	return message, conv, nil
+}
+
+// requireConversationManager 校验用户可以修改会话的共享设置（置顶、阅后即焚）
+// 单聊双方都可以修改，群聊只有群主和管理员可以修改
+func (s *MessageService) requireConversationManager(conv *model.Conversation, userID uint, action string) error {
+	if conv.Type == model.ConversationTypeGroup {
+		if conv.GroupID == nil {
+			return errors.New("group conversation without group")
+		}
+		role, err := s.groupMemberDAO.GetMemberRole(*conv.GroupID, userID)
+		if err != nil {
+			return errors.New("not a group member")
+		}
+		if role != model.GroupRoleOwner && role != model.GroupRoleAdmin {
+			return errors.New("only group owner or admin can " + action)
+		}
+		return nil
+	}
+
+	participants, err := s.getParticipants(conv)
+	if err != nil {
+		return err
+	}
+	if !containsUser(participants, userID) {
+		return errors.New("not a conversation participant")
+	}
+	return nil
+}
+
+// notifyPinUpdated 通知会话所有参与者置顶列表发生变化
+func (s *MessageService) notifyPinUpdated(conversationID, messageID, operatorID uint, action string) {
+	s.notifyParticipants(conversationID, "pin_updated", map[string]interface{}{
+		"conversation_id": conversationID,
+		"message_id":      messageID,
+		"action":          action,
+		"operator_id":     operatorID,
+		"timestamp":       time.Now().Format(time.RFC3339),
+	})
+}
+
+// pinNotice 置顶系统通知的文本
+// 阅后即焚消息不带内容摘要：系统通知不会过期，不能保留原消息的内容
+func pinNotice(operatorName string, message *model.Message) string {
+	if message.Disappearing() {
+		return operatorName + " 置顶了一条消息"
+	}
+	return operatorName + " 置顶了一条消息：" + pinSummary(message.Content)
+}
+
+// pinSummary 置顶通知中的消息摘要，过长时截断
+func pinSummary(content string) string {
+	if utf8.RuneCountInString(content) <= pinNoticeSummaryLength {
+		return content
+	}
+	runes := []rune(content)
+	return string(runes[:pinNoticeSummaryLength]) + "…"
+}
@@ -0,0 +1,67 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lanxin/im-backend/internal/dao"
+	"github.com/lanxin/im-backend/internal/model"
+	"github.com/lanxin/im-backend/internal/testutil"
+)
+
+func TestPinMessageLimitCheckedUnderLock(t *testing.T) {
+	mock := testutil.NewMockDB(t)
+	testutil.NewRedis(t)
+	s := &MessageService{
+		messageDAO:      dao.NewMessageDAO(),
+		conversationDAO: dao.NewConversationDAO(),
+		pinDAO:          dao.NewMessagePinDAO(),
+		changeDAO:       dao.NewConversationChangeDAO(),
+		maxPins:         10,
+	}
+
+	mock.ExpectQuery("SELECT \\* FROM `messages` WHERE id = \\?").
+		WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "conversation_id", "seq", "sender_id", "receiver_id", "type", "status"}).
+			AddRow(7, 12, 1, 2, 1, model.MessageTypeText, model.MessageStatusSent))
+	mock.ExpectQuery("SELECT \\* FROM `users`").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectQuery("SELECT \\* FROM `users`").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectQuery("SELECT \\* FROM `conversations`").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "type", "user1_id", "user2_id"}).AddRow(12, model.ConversationTypeSingle, 1, 2))
+	mock.ExpectQuery("SELECT count\\(\\*\\) FROM `messages` WHERE id = \\?"+visibilitySQL).
+		WithArgs(7, 1, 1, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+
+	// 检查和写入在同一事务中，先锁定会话行
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT `id` FROM `conversations` WHERE id = \\? ORDER BY `conversations`.`id` LIMIT 1 FOR UPDATE").
+		WithArgs(12).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(12))
+	mock.ExpectQuery("SELECT count\\(\\*\\) FROM `message_pins` WHERE conversation_id = \\? AND message_id = \\?").
+		WithArgs(12, 7).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectQuery("SELECT count\\(\\*\\) FROM `message_pins` WHERE conversation_id = \\?").
+		WithArgs(12).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(10))
+	mock.ExpectRollback()
+
+	_, err := s.PinMessage(7, 1)
+	if err == nil || err.Error() != "at most 10 messages can be pinned in a conversation" {
+		t.Fatalf("PinMessage() error = %v, want pin limit", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestPinNoticeOmitsDisappearingContent(t *testing.T) {
+	message := &model.Message{Content: "今晚的密码是1234"}
+	if got, want := pinNotice("张三", message), "张三 置顶了一条消息：今晚的密码是1234"; got != want {
+		t.Errorf("pinNotice() = %q, want %q", got, want)
+	}
+
+	message.ExpireSeconds = 30
+	if got, want := pinNotice("张三", message), "张三 置顶了一条消息"; got != want {
+		t.Errorf("pinNotice() for disappearing message = %q, want %q", got, want)
+	}
+}
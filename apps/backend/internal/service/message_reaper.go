@@ -0,0 +1,197 @@
+package service
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/lanxin/im-backend/config"
+	"github.com/lanxin/im-backend/internal/dao"
+	"github.com/lanxin/im-backend/internal/model"
+	"github.com/lanxin/im-backend/internal/pkg/mysql"
+	"github.com/lanxin/im-backend/internal/websocket"
+	"github.com/lanxin/im-backend/pkg/cos"
+	"gorm.io/gorm"
+)
+
+const messageReapBatch = 200
+
+// MessageReaper 阅后即焚消息清理器
+// 轮询已过期的消息，物理删除消息及引用它的收藏和置顶，删除存储中的文件，并向会话参与者推送message_expired（被置顶的还推送pin_updated），离线设备通过变更日志同步
+// 领取使用 FOR UPDATE SKIP LOCKED，多节点同时运行时每条消息只由一个节点清理
+type MessageReaper struct {
+	messageDAO      *dao.MessageDAO
+	conversationDAO *dao.ConversationDAO
+	favoriteDAO     *dao.FavoriteDAO
+	pinDAO          *dao.MessagePinDAO
+	changeDAO       *dao.ConversationChangeDAO
+	messageService  *MessageService
+	cosClient       *cos.Client
+
+	interval time.Duration
+}
+
+// NewMessageReaper 创建阅后即焚消息清理器
+func NewMessageReaper(cfg *config.Config, hub *websocket.Hub) *MessageReaper {
+	r := &MessageReaper{
+		messageDAO:      dao.NewMessageDAO(),
+		conversationDAO: dao.NewConversationDAO(),
+		favoriteDAO:     dao.NewFavoriteDAO(),
+		pinDAO:          dao.NewMessagePinDAO(),
+		changeDAO:       dao.NewConversationChangeDAO(),
+		messageService:  NewMessageService(cfg, hub),
+		interval:        time.Duration(cfg.Message.ReaperIntervalMs) * time.Millisecond,
+	}
+
+	if r.interval <= 0 {
+		r.interval = time.Second
+	}
+
+	cosClient, err := cos.NewClient(cos.Config{
+		SecretID:  cfg.Storage.COS.SecretID,
+		SecretKey: cfg.Storage.COS.SecretKey,
+		Bucket:    cfg.Storage.COS.Bucket,
+		Region:    cfg.Storage.COS.Region,
+		BaseURL:   cfg.Storage.COS.BaseURL,
+	})
+	if err != nil {
+		log.Printf("Message reaper: object storage unavailable, expired files will be kept: %v", err)
+	} else {
+		r.cosClient = cosClient
+	}
+
+	return r
+}
+
+// Run 启动清理循环，直到ctx取消
+func (r *MessageReaper) Run(ctx context.Context) {
+	log.Printf("Message reaper started: interval=%s", r.interval)
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("Message reaper stopped")
+			return
+
+		case <-ticker.C:
+			// 一批满载说明还有过期消息，立即继续处理
+			for {
+				n, err := r.reapBatch(ctx)
+				if err != nil {
+					log.Printf("Message reaper batch failed: %v", err)
+					break
+				}
+				if n < messageReapBatch || ctx.Err() != nil {
+					break
+				}
+			}
+		}
+	}
+}
+
+// reapBatch 在一个事务中锁定并删除一批过期消息，提交后清理文件并推送事件，返回处理的条数
+func (r *MessageReaper) reapBatch(ctx context.Context) (int, error) {
+	var expired []model.Message
+	var unpinned []model.MessagePin
+
+	err := mysql.GetDB().Transaction(func(tx *gorm.DB) error {
+		messageDAO := r.messageDAO.WithTx(tx)
+
+		var err error
+		expired, err = messageDAO.LockExpired(messageReapBatch)
+		if err != nil || len(expired) == 0 {
+			return err
+		}
+
+		ids := make([]uint, len(expired))
+		byConversation := make(map[uint][]uint)
+		for i, msg := range expired {
+			ids[i] = msg.ID
+			byConversation[msg.ConversationID] = append(byConversation[msg.ConversationID], msg.ID)
+		}
+
+		if err := r.favoriteDAO.WithTx(tx).DeleteByMessageIDs(ids); err != nil {
+			return err
+		}
+		// 置顶随消息删除，在线设备通过pin_updated、离线设备通过变更日志刷新置顶列表
+		unpinned, err = r.pinDAO.WithTx(tx).DeleteByMessageIDs(ids)
+		if err != nil {
+			return err
+		}
+		for i := range unpinned {
+			if err := r.changeDAO.WithTx(tx).Record(unpinned[i].ConversationID, 0, model.ChangePinUpdated, &unpinned[i].MessageID); err != nil {
+				return err
+			}
+		}
+		if err := messageDAO.Purge(ids); err != nil {
+			return err
+		}
+		// 会话列表的最后一条消息被清理时，改为指向剩余的最新消息；离线设备通过变更日志得知消息已过期
+		for conversationID, messageIDs := range byConversation {
+			if err := r.conversationDAO.WithTx(tx).ResetLastMessage(conversationID, messageIDs); err != nil {
+				return err
+			}
+			if err := r.changeDAO.WithTx(tx).RecordMessages(conversationID, model.ChangeMessageExpired, messageIDs); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil || len(expired) == 0 {
+		return len(expired), err
+	}
+
+	r.deleteFiles(ctx, expired)
+	r.notify(expired, unpinned)
+
+	return len(expired), nil
+}
+
+// deleteFiles 删除过期消息在对象存储中的文件，仍被其他消息引用的文件保留
+// 消息已经删除，文件删除失败只记录日志
+func (r *MessageReaper) deleteFiles(ctx context.Context, expired []model.Message) {
+	if r.cosClient == nil {
+		return
+	}
+
+	seen := make(map[string]bool)
+	for _, msg := range expired {
+		if msg.FileURL == "" || seen[msg.FileURL] {
+			continue
+		}
+		seen[msg.FileURL] = true
+
+		key, ok := r.cosClient.ObjectKey(msg.FileURL)
+		if !ok {
+			continue
+		}
+		if refs, err := r.messageDAO.CountFileRefs(msg.FileURL, nil); err != nil || refs > 0 {
+			continue
+		}
+		if err := r.cosClient.DeleteFile(ctx, key); err != nil {
+			log.Printf("Message reaper: failed to delete file %s of message %d: %v", key, msg.ID, err)
+		}
+	}
+}
+
+// notify 按会话向参与者推送已过期的消息ID，客户端据此删除本地副本；被取消的置顶推送pin_updated
+func (r *MessageReaper) notify(expired []model.Message, unpinned []model.MessagePin) {
+	byConversation := make(map[uint][]uint)
+	for _, msg := range expired {
+		byConversation[msg.ConversationID] = append(byConversation[msg.ConversationID], msg.ID)
+	}
+
+	for conversationID, messageIDs := range byConversation {
+		r.messageService.notifyParticipants(conversationID, "message_expired", map[string]interface{}{
+			"conversation_id": conversationID,
+			"message_ids":     messageIDs,
+		})
+	}
+
+	for _, pin := range unpinned {
+		r.messageService.notifyPinUpdated(pin.ConversationID, pin.MessageID, 0, pinActionUnpin)
+	}
+}
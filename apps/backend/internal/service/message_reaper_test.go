@@ -0,0 +1,99 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lanxin/im-backend/internal/dao"
+	"github.com/lanxin/im-backend/internal/model"
+	"github.com/lanxin/im-backend/internal/testutil"
+	"github.com/lanxin/im-backend/internal/websocket"
+)
+
+func newTestReaper() *MessageReaper {
+	return &MessageReaper{
+		messageDAO:      dao.NewMessageDAO(),
+		conversationDAO: dao.NewConversationDAO(),
+		favoriteDAO:     dao.NewFavoriteDAO(),
+		pinDAO:          dao.NewMessagePinDAO(),
+		changeDAO:       dao.NewConversationChangeDAO(),
+		messageService: &MessageService{
+			conversationDAO: dao.NewConversationDAO(),
+			groupMemberDAO:  dao.NewGroupMemberDAO(),
+			hub:             websocket.NewHub(),
+		},
+	}
+}
+
+func TestReapBatchPurgesExpiredAndUnpins(t *testing.T) {
+	mock := testutil.NewMockDB(t)
+	testutil.NewRedis(t)
+	r := newTestReaper()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT `id`,`conversation_id`,`type`,`file_url` FROM `messages` WHERE expires_at IS NOT NULL AND expires_at <= \\? ORDER BY expires_at ASC LIMIT 200 FOR UPDATE SKIP LOCKED").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "conversation_id", "type", "file_url"}).
+			AddRow(10, 1, model.MessageTypeText, "").
+			AddRow(11, 1, model.MessageTypeText, ""))
+	mock.ExpectExec("`favorites`").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	// 消息11被置顶：置顶随消息删除并写入置顶变更
+	mock.ExpectQuery("SELECT \\* FROM `message_pins` WHERE message_id IN \\(\\?,\\?\\)").
+		WithArgs(10, 11).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "conversation_id", "message_id", "pinned_by"}).AddRow(3, 1, 11, 2))
+	mock.ExpectExec("DELETE FROM `message_pins` WHERE id IN \\(\\?\\)").
+		WithArgs(3).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO `conversation_changes`").
+		WithArgs(1, 0, model.ChangePinUpdated, 11, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("DELETE FROM `messages` WHERE id IN \\(\\?,\\?\\)").
+		WithArgs(10, 11).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	// 最后一条消息和最后消息时间一起回退到剩余的最新消息
+	mock.ExpectExec("UPDATE `conversations` SET `last_message_at`=\\(SELECT m.created_at FROM messages m .*\\),`last_message_id`=\\(SELECT m.id FROM messages m .*\\).* WHERE id = \\? AND last_message_id IN \\(\\?,\\?\\)").
+		WithArgs(1, 1, sqlmock.AnyArg(), 1, 10, 11).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	// 离线设备通过变更日志得知消息已过期
+	mock.ExpectExec("INSERT INTO `conversation_changes`").
+		WithArgs(1, 0, model.ChangeMessageExpired, 10, sqlmock.AnyArg(), 1, 0, model.ChangeMessageExpired, 11, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(2, 2))
+	mock.ExpectCommit()
+
+	// 提交后推送message_expired和pin_updated
+	conversation := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"id", "type", "user1_id", "user2_id"}).AddRow(1, model.ConversationTypeSingle, 1, 2)
+	}
+	mock.ExpectQuery("SELECT \\* FROM `conversations`").WithArgs(1).WillReturnRows(conversation())
+	mock.ExpectQuery("SELECT \\* FROM `conversations`").WithArgs(1).WillReturnRows(conversation())
+
+	n, err := r.reapBatch(context.Background())
+	if err != nil {
+		t.Fatalf("reapBatch() error = %v", err)
+	}
+	if n != 2 {
+		t.Errorf("reapBatch() = %d, want 2", n)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestReapBatchNothingExpired(t *testing.T) {
+	mock := testutil.NewMockDB(t)
+	r := newTestReaper()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT `id`,`conversation_id`,`type`,`file_url` FROM `messages`").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "conversation_id", "type", "file_url"}))
+	mock.ExpectCommit()
+
+	n, err := r.reapBatch(context.Background())
+	if err != nil || n != 0 {
+		t.Fatalf("reapBatch() = %d, %v, want 0", n, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
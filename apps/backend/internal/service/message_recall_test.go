@@ -0,0 +1,106 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lanxin/im-backend/internal/dao"
+	"github.com/lanxin/im-backend/internal/model"
+	"github.com/lanxin/im-backend/internal/testutil"
+)
+
+func TestRecallRole(t *testing.T) {
+	groupID := uint(5)
+	group := &model.Conversation{ID: 12, Type: model.ConversationTypeGroup, GroupID: &groupID}
+	single := &model.Conversation{ID: 13, Type: model.ConversationTypeSingle}
+	recent := time.Now().Add(-time.Minute)
+	old := time.Now().Add(-time.Hour)
+
+	tests := []struct {
+		name         string
+		conv         *model.Conversation
+		senderID     uint
+		createdAt    time.Time
+		operatorRole string // 操作者的群角色，空表示不在群中
+		senderRole   string // 发送者的群角色，只在操作者是群管理员时查询
+		userRole     string // 操作者的平台角色，空表示不会查询
+		want         string
+		wantErr      string
+	}{
+		{name: "sender within window", conv: single, senderID: 1, createdAt: recent, want: recallBySender},
+		{name: "sender after window", conv: single, senderID: 1, createdAt: old, userRole: model.UserRoleUser,
+			wantErr: "can only recall messages within 120 seconds"},
+		{name: "other user in single chat", conv: single, senderID: 2, createdAt: recent, userRole: model.UserRoleUser,
+			wantErr: "no permission to recall this message"},
+		{name: "group owner recalls admin", conv: group, senderID: 2, createdAt: old,
+			operatorRole: model.GroupRoleOwner, want: recallByGroupOwner},
+		{name: "group owner recalls own old message", conv: group, senderID: 1, createdAt: old,
+			operatorRole: model.GroupRoleOwner, want: recallByGroupOwner},
+		{name: "group admin recalls member", conv: group, senderID: 2, createdAt: old,
+			operatorRole: model.GroupRoleAdmin, senderRole: model.GroupRoleMember, want: recallByGroupAdmin},
+		{name: "group admin recalls member who left", conv: group, senderID: 2, createdAt: old,
+			operatorRole: model.GroupRoleAdmin, want: recallByGroupAdmin},
+		{name: "group admin recalls own old message", conv: group, senderID: 1, createdAt: old,
+			operatorRole: model.GroupRoleAdmin, senderRole: model.GroupRoleAdmin, want: recallByGroupAdmin},
+		{name: "group admin cannot recall owner", conv: group, senderID: 2, createdAt: recent,
+			operatorRole: model.GroupRoleAdmin, senderRole: model.GroupRoleOwner, userRole: model.UserRoleUser,
+			wantErr: "no permission to recall this message"},
+		{name: "group admin cannot recall another admin", conv: group, senderID: 2, createdAt: recent,
+			operatorRole: model.GroupRoleAdmin, senderRole: model.GroupRoleAdmin, userRole: model.UserRoleUser,
+			wantErr: "no permission to recall this message"},
+		{name: "group member cannot recall others", conv: group, senderID: 2, createdAt: recent,
+			operatorRole: model.GroupRoleMember, userRole: model.UserRoleUser,
+			wantErr: "no permission to recall this message"},
+		{name: "platform admin outside the group", conv: group, senderID: 2, createdAt: old,
+			userRole: model.UserRoleAdmin, want: recallByPlatformAdmin},
+		{name: "platform admin in single chat", conv: single, senderID: 2, createdAt: old,
+			userRole: model.UserRoleAdmin, want: recallByPlatformAdmin},
+	}
+
+	memberRole := func(mock sqlmock.Sqlmock, userID uint, role string) {
+		rows := sqlmock.NewRows([]string{"id", "group_id", "user_id", "role"})
+		if role != "" {
+			rows.AddRow(1, groupID, userID, role)
+		}
+		mock.ExpectQuery("SELECT \\* FROM `group_members` WHERE group_id = \\? AND user_id = \\?").
+			WithArgs(groupID, userID).
+			WillReturnRows(rows)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := testutil.NewMockDB(t)
+			s := &MessageService{
+				groupMemberDAO: dao.NewGroupMemberDAO(),
+				userDAO:        dao.NewUserDAO(),
+				recallWindow:   2 * time.Minute,
+			}
+
+			if tt.conv.Type == model.ConversationTypeGroup && !(tt.senderID == 1 && tt.createdAt == recent) {
+				memberRole(mock, 1, tt.operatorRole)
+				if tt.operatorRole == model.GroupRoleAdmin {
+					memberRole(mock, tt.senderID, tt.senderRole)
+				}
+			}
+			if tt.userRole != "" {
+				mock.ExpectQuery("SELECT \\* FROM `users` WHERE id = \\?").
+					WithArgs(1).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "username", "role"}).AddRow(1, "zhangsan", tt.userRole))
+			}
+
+			message := &model.Message{ID: 100, SenderID: tt.senderID, ConversationID: tt.conv.ID, CreatedAt: tt.createdAt}
+			got, err := s.recallRole(message, tt.conv, 1)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("recallRole() error = %v, want %q", err, tt.wantErr)
+				}
+			} else if err != nil || got != tt.want {
+				t.Fatalf("recallRole() = %q, %v, want %q", got, err, tt.want)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
@@ -2,63 +2,117 @@ package service
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"log"
+	"reflect"
 	"strconv"
 	"time"
 
 	goredis "github.com/go-redis/redis/v8"
+	"github.com/lanxin/im-backend/config"
 	"github.com/lanxin/im-backend/internal/dao"
 	"github.com/lanxin/im-backend/internal/model"
+	"github.com/lanxin/im-backend/internal/pkg/mysql"
 	"github.com/lanxin/im-backend/internal/pkg/redis"
 	"github.com/lanxin/im-backend/internal/websocket"
 	"github.com/lanxin/im-backend/pkg/kafka"
+	"github.com/lanxin/im-backend/pkg/push"
+	"gorm.io/gorm"
 )
 
 type MessageService struct {
 	messageDAO      *dao.MessageDAO
 	conversationDAO *dao.ConversationDAO
+	memberDAO       *dao.ConversationMemberDAO
+	groupMemberDAO  *dao.GroupMemberDAO
 	userDAO         *dao.UserDAO
 	logDAO          *dao.OperationLogDAO
+	outboxDAO       *dao.OutboxDAO
+	changeDAO       *dao.ConversationChangeDAO
+	editDAO         *dao.MessageEditDAO
+	reactionDAO     *dao.MessageReactionDAO
+	deletionDAO     *dao.MessageDeletionDAO
+	pinDAO          *dao.MessagePinDAO
+	payloads        *messagePayloadValidator
 	hub             *websocket.Hub
-	producer        *kafka.Producer
 	redisClient     *goredis.Client
+	messageTopic    string
+	asyncDelivery   bool         // 投递由Kafka消费者(cmd/worker)完成
+	pusher          *push.Client // 离线推送，未配置网关时为nil
+	editWindow      time.Duration
+	recallWindow    time.Duration
+	maxPins         int
+	systemMessages  *systemMessenger
 }
 
-func NewMessageService(hub *websocket.Hub, producer *kafka.Producer) *MessageService {
-	return &MessageService{
+func NewMessageService(cfg *config.Config, hub *websocket.Hub) *MessageService {
+	editWindow := time.Duration(cfg.Message.EditWindowSeconds) * time.Second
+	if editWindow <= 0 {
+		editWindow = 15 * time.Minute
+	}
+	recallWindow := time.Duration(cfg.Message.RecallWindowSeconds) * time.Second
+	if recallWindow <= 0 {
+		recallWindow = 2 * time.Minute
+	}
+	maxPins := cfg.Message.MaxPinsPerConversation
+	if maxPins <= 0 {
+		maxPins = 10
+	}
+
+	s := &MessageService{
 		messageDAO:      dao.NewMessageDAO(),
 		conversationDAO: dao.NewConversationDAO(),
+		memberDAO:       dao.NewConversationMemberDAO(),
+		groupMemberDAO:  dao.NewGroupMemberDAO(),
 		userDAO:         dao.NewUserDAO(),
 		logDAO:          dao.NewOperationLogDAO(),
+		outboxDAO:       dao.NewOutboxDAO(),
+		changeDAO:       dao.NewConversationChangeDAO(),
+		editDAO:         dao.NewMessageEditDAO(),
+		reactionDAO:     dao.NewMessageReactionDAO(),
+		deletionDAO:     dao.NewMessageDeletionDAO(),
+		pinDAO:          dao.NewMessagePinDAO(),
+		payloads:        newMessagePayloadValidator(),
 		hub:             hub,
-		producer:        producer,
 		redisClient:     redis.GetClient(),
+		messageTopic:    cfg.Kafka.Topic.Message,
+		asyncDelivery:   cfg.Kafka.AsyncDelivery,
+		pusher:          newPushClient(cfg),
+		editWindow:      editWindow,
+		recallWindow:    recallWindow,
+		maxPins:         maxPins,
 	}
+	s.systemMessages = newSystemMessenger(s)
+	return s
 }
 
-// SendMessage 发送消息
-func (s *MessageService) SendMessage(senderID, receiverID uint, content, msgType string, fileURL *string, fileSize *int64, duration *int, ip, userAgent string) (*model.Message, error) {
-	// 验证接收者存在
-	_, err := s.userDAO.GetByID(receiverID)
-	if err != nil {
-		return nil, errors.New("receiver not found")
-	}
+// ErrClientMsgIDConflict 客户端消息ID已被一条目标或内容不同的消息使用
+var ErrClientMsgIDConflict = errors.New("client_msg_id already used for a different message")
 
-	// 获取或创建会话
-	conversationID, err := s.conversationDAO.GetOrCreateSingleConversation(senderID, receiverID)
-	if err != nil {
-		return nil, errors.New("failed to get or create conversation")
-	}
+// SendOptions 发送消息的可选参数
+type SendOptions struct {
+	ReplyToMessageID *uint           // 引用回复的消息，须在同一会话
+	ThreadRootID     *uint           // 所属话题的根消息，须在同一会话
+	MentionUserIDs   []uint          // 被@的群成员（仅群消息）
+	MentionAll       bool            // @所有人（仅群主/管理员）
+	ForwardFrom      *model.Message  // 转发的源消息，调用方负责校验可见性
+	Payload          json.RawMessage // 结构化内容（位置、名片、表情贴图、富文本等）
 
+	serverGenerated bool // 服务端生成的消息（聊天记录、系统通知），客户端不能发送这些类型
+}
+
+// SendMessage 发送消息
+// clientMsgID 为客户端生成的去重ID，重试时返回已存在的消息而不是重复创建
+func (s *MessageService) SendMessage(senderID, receiverID uint, content, msgType string, fileURL *string, fileSize *int64, duration *int, clientMsgID string, opts SendOptions, ip, userAgent string) (*model.Message, error) {
 	// 创建消息
 	message := &model.Message{
-		ConversationID: conversationID,
-		SenderID:       senderID,
-		ReceiverID:     receiverID,
-		Content:        content,
-		Type:           msgType,
-		Status:         model.MessageStatusSent,
+		SenderID:   senderID,
+		ReceiverID: receiverID,
+		Content:    content,
+		Type:       msgType,
+		Status:     model.MessageStatusSent,
 	}
 
 	if fileURL != nil {
@@ -70,9 +124,76 @@ func (s *MessageService) SendMessage(senderID, receiverID uint, content, msgType
 	if duration != nil {
 		message.Duration = *duration
 	}
+	if clientMsgID != "" {
+		message.ClientMsgID = &clientMsgID
+	}
+	if err := s.payloads.apply(message, opts); err != nil {
+		return nil, err
+	}
+
+	// 客户端重试：返回已创建的消息
+	if existing, err := findRetry(s.messageDAO, message); existing != nil || err != nil {
+		return existing, err
+	}
+
+	// 验证接收者存在
+	_, err := s.userDAO.GetByID(receiverID)
+	if err != nil {
+		return nil, errors.New("receiver not found")
+	}
+
+	// 获取或创建会话
+	conversationID, err := s.conversationDAO.GetOrCreateSingleConversation(senderID, receiverID)
+	if err != nil {
+		return nil, errors.New("failed to get or create conversation")
+	}
+	conv, err := s.conversationDAO.GetByID(conversationID)
+	if err != nil {
+		return nil, errors.New("failed to get or create conversation")
+	}
+	message.ConversationID = conversationID
+	if err := resolveMessageRefs(s.messageDAO, message, opts); err != nil {
+		return nil, err
+	}
+	applyDisappearing(conv, message)
+
+	// 消息（含会话内序号）、会话最后一条消息、双方会话状态和发件箱事件在同一事务中写入
+	// Kafka事件由OutboxRelay异步投递，数据库和事件不会不一致
+	err = mysql.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := s.messageDAO.WithTx(tx).CreateWithSeq(message); err != nil {
+			return err
+		}
+
+		now := time.Now()
+		if err := s.conversationDAO.WithTx(tx).UpdateLastMessage(conversationID, message.ID, &now); err != nil {
+			return err
+		}
+
+		memberDAO := s.memberDAO.WithTx(tx)
+		if err := memberDAO.EnsureMembers(conversationID, senderID, receiverID); err != nil {
+			return err
+		}
+		if err := memberDAO.OnNewMessage(conversationID, senderID, message.Seq); err != nil {
+			return err
+		}
+
+		if message.ThreadRootID != nil {
+			if err := s.messageDAO.WithTx(tx).IncrThreadReply(*message.ThreadRootID, message.ID, message.CreatedAt); err != nil {
+				return err
+			}
+			if err := s.changeDAO.WithTx(tx).Record(conversationID, 0, model.ChangeThreadUpdated, message.ThreadRootID); err != nil {
+				return err
+			}
+		}
+
+		return s.enqueueMessageEvent(tx, message)
+	})
+	if err != nil {
+		// 并发重试撞上唯一索引：返回先写入的那条
+		if existing, retryErr := findRetry(s.messageDAO, message); existing != nil || retryErr != nil {
+			return existing, retryErr
+		}
 
-	// 保存到数据库
-	if err := s.messageDAO.Create(message); err != nil {
 		// 记录失败日志
 		s.logDAO.CreateLog(dao.LogRequest{
 			Action:       model.ActionMessageSend,
@@ -86,154 +207,829 @@ func (s *MessageService) SendMessage(senderID, receiverID uint, content, msgType
 		return nil, err
 	}
 
-	// 更新会话的最后一条消息
+	// 通过WebSocket实时推送给接收者（异步投递模式下由worker消费Kafka完成）
+	if !s.asyncDelivery {
+		go s.DeliverMessage(message)
+	}
+
+	// 话题回复：通知参与者更新根消息上的回复数
+	if message.ThreadRootID != nil {
+		go s.notifyThreadUpdated(*message.ThreadRootID)
+	}
+
+	// 记录成功日志
+	s.logDAO.CreateLog(dao.LogRequest{
+		Action:    model.ActionMessageSend,
+		UserID:    &senderID,
+		IP:        ip,
+		UserAgent: userAgent,
+		Details: map[string]interface{}{
+			"message_id":  message.ID,
+			"receiver_id": receiverID,
+			"type":        msgType,
+		},
+		Result: model.ResultSuccess,
+	})
+
+	return message, nil
+}
+
+// resolveMessageRefs 校验引用回复和话题根消息属于消息所在会话，并写入message
+func resolveMessageRefs(messageDAO *dao.MessageDAO, message *model.Message, opts SendOptions) error {
+	if opts.ReplyToMessageID != nil {
+		replyTo, err := messageDAO.GetByID(*opts.ReplyToMessageID)
+		if err != nil || replyTo.ConversationID != message.ConversationID {
+			return errors.New("reply_to message not found in this conversation")
+		}
+		message.ReplyToMessageID = &replyTo.ID
+	}
+
+	if opts.ThreadRootID != nil {
+		root, err := messageDAO.GetByID(*opts.ThreadRootID)
+		if err != nil || root.ConversationID != message.ConversationID {
+			return errors.New("thread root not found in this conversation")
+		}
+		// 话题只有一层，回复话题内的消息时归入同一个根消息
+		rootID := root.ID
+		if root.ThreadRootID != nil {
+			rootID = *root.ThreadRootID
+		}
+		message.ThreadRootID = &rootID
+	}
+
+	if src := opts.ForwardFrom; src != nil {
+		// 多次转发时保留最初的发送者
+		senderID := src.SenderID
+		if src.ForwardSenderID != nil {
+			senderID = *src.ForwardSenderID
+		}
+		message.ForwardFromID = &src.ID
+		message.ForwardSenderID = &senderID
+	}
+
+	return nil
+}
+
+// threadUpdatedEvent 话题更新事件，携带根消息上的最新回复信息
+func threadUpdatedEvent(root *model.Message) websocket.WebSocketMessage {
+	return websocket.WebSocketMessage{
+		Type: "thread_updated",
+		Data: map[string]interface{}{
+			"root_message_id":      root.ID,
+			"conversation_id":      root.ConversationID,
+			"thread_reply_count":   root.ThreadReplyCount,
+			"last_thread_reply_id": root.LastThreadReplyID,
+			"last_thread_reply_at": root.LastThreadReplyAt,
+		},
+	}
+}
+
+// notifyThreadUpdated 把话题的最新回复信息推送给会话参与者
+func (s *MessageService) notifyThreadUpdated(rootID uint) {
+	root, err := s.messageDAO.GetByID(rootID)
+	if err != nil {
+		log.Printf("Failed to load thread root %d: %v", rootID, err)
+		return
+	}
+
+	event := threadUpdatedEvent(root)
+	s.notifyParticipants(root.ConversationID, event.Type, event.Data)
+}
+
+// GetThread 获取话题根消息和序号大于afterSeq的回复，仅会话参与者可查看
+func (s *MessageService) GetThread(rootID, userID uint, afterSeq uint64, limit int) (*model.Message, []model.Message, bool, error) {
+	root, err := s.messageDAO.GetByID(rootID)
+	if err != nil {
+		return nil, nil, false, errors.New("message not found")
+	}
+	if root.ThreadRootID != nil {
+		return nil, nil, false, errors.New("message is a thread reply, not a thread root")
+	}
+
+	conv, err := s.conversationDAO.GetByID(root.ConversationID)
+	if err != nil {
+		return nil, nil, false, errors.New("conversation not found")
+	}
+	participants, err := s.getParticipants(conv)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	if !containsUser(participants, userID) {
+		return nil, nil, false, errors.New("not a conversation participant")
+	}
+
+	if limit <= 0 || limit > 100 {
+		limit = 50
+	}
+
+	// 多取一条判断是否还有更多
+	replies, err := s.messageDAO.GetThreadReplies(userID, rootID, afterSeq, limit+1)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	hasMore := len(replies) > limit
+	if hasMore {
+		replies = replies[:limit]
+	}
+	s.attachReactions(replies, userID)
+
+	return root, replies, hasMore, nil
+}
+
+// findRetry 查找发送者已用同一客户端ID发送过的消息
+// 重试请求的目标、类型和内容须与已存在的消息一致，否则返回ErrClientMsgIDConflict
+func findRetry(messageDAO *dao.MessageDAO, request *model.Message) (*model.Message, error) {
+	if request.ClientMsgID == nil {
+		return nil, nil
+	}
+	existing, err := messageDAO.GetByClientMsgID(request.SenderID, *request.ClientMsgID)
+	if err != nil {
+		return nil, nil
+	}
+	if !sameSendRequest(existing, request) {
+		return nil, ErrClientMsgIDConflict
+	}
+	return existing, nil
+}
+
+// sameSendRequest 比较已存在的消息和发送请求
+// 消息被编辑后内容已变化，只比较目标和类型
+func sameSendRequest(existing, request *model.Message) bool {
+	if existing.ReceiverID != request.ReceiverID || !sameUintPtr(existing.GroupID, request.GroupID) || existing.Type != request.Type {
+		return false
+	}
+	if existing.EditCount > 0 {
+		return true
+	}
+	return existing.Content == request.Content && existing.FileURL == request.FileURL && samePayload(existing.Payload, request.Payload)
+}
+
+func sameUintPtr(a, b *uint) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// samePayload 按JSON语义比较payload，忽略字段顺序和空白（数据库JSON列会重新格式化）
+func samePayload(a, b json.RawMessage) bool {
+	if len(a) == 0 || len(b) == 0 {
+		return len(a) == len(b)
+	}
+	var va, vb interface{}
+	if json.Unmarshal(a, &va) != nil || json.Unmarshal(b, &vb) != nil {
+		return false
+	}
+	return reflect.DeepEqual(va, vb)
+}
+
+// enqueueMessageEvent 在事务中写入消息的发件箱事件
+func (s *MessageService) enqueueMessageEvent(tx *gorm.DB, message *model.Message) error {
+	event, err := newMessageEvent(s.messageTopic, message)
+	if err != nil {
+		return err
+	}
+	return s.outboxDAO.WithTx(tx).Create(event)
+}
+
+// newMessageEvent 构造消息的发件箱事件，单聊和群聊共用
+// 以会话ID作为Kafka消息Key，同一会话的消息按顺序投递
+func newMessageEvent(topic string, message *model.Message) (*model.OutboxEvent, error) {
+	data := kafka.MessageData{
+		ID:             message.ID,
+		ConversationID: message.ConversationID,
+		SenderID:       message.SenderID,
+		ReceiverID:     message.ReceiverID,
+		Content:        message.Content,
+		Type:           message.Type,
+		FileURL:        message.FileURL,
+		CreatedAt:      message.CreatedAt.Unix(),
+	}
+	if message.ClientMsgID != nil {
+		data.ClientMsgID = *message.ClientMsgID
+	}
+	if message.GroupID != nil {
+		data.GroupID = *message.GroupID
+	}
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.OutboxEvent{
+		AggregateType: model.OutboxAggregateMessage,
+		AggregateID:   message.ID,
+		Topic:         topic,
+		MessageKey:    strconv.FormatUint(uint64(message.ConversationID), 10),
+		Payload:       string(payload),
+		Status:        model.OutboxStatusPending,
+	}, nil
+}
+
+// DeliverMessage 投递消息给接收者
+// 在线则推送并等待客户端ACK（收到ACK后才标记为已送达），离线或推送失败则存入离线队列
+// 群消息逐个投递给除发送者外的群成员，走同样的确认和离线队列流程
+func (s *MessageService) DeliverMessage(message *model.Message) error {
+	if message.GroupID == nil {
+		// 接收者设置了免打扰时静默推送
+		silent := false
+		if member, err := s.memberDAO.Get(message.ConversationID, message.ReceiverID); err == nil {
+			silent = member.IsMuted
+		}
+		return s.deliverTo(message, message.ReceiverID, silent)
+	}
+
+	memberIDs, err := s.groupMemberDAO.GetMemberIDs(*message.GroupID)
+	if err != nil {
+		return err
+	}
+	// 成员各自的免打扰设置，被@的成员不受免打扰影响
+	states, err := s.memberDAO.GetByConversation(message.ConversationID)
+	if err != nil {
+		states = map[uint]*model.ConversationMember{}
+	}
+
+	var firstErr error
+	for _, memberID := range memberIDs {
+		if memberID == message.SenderID {
+			continue
+		}
+		silent := false
+		if state, ok := states[memberID]; ok && state.IsMuted {
+			silent = !message.MentionAll && !containsUser(message.MentionUserIDs, memberID)
+		}
+		// 单个成员失败不影响其他成员，返回第一个错误供上层重试（重复推送由客户端按消息ID去重）
+		if err := s.deliverTo(message, memberID, silent); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// deliverTo 把消息投递给一个接收者，silent为true时静默推送且不发送通知栏推送
+func (s *MessageService) deliverTo(message *model.Message, receiverID uint, silent bool) error {
+	pushErr := s.hub.PushMessage(receiverID, message.ID, message, silent)
+	if pushErr == nil {
+		return nil
+	}
+
+	// 离线或推送失败: 存入离线消息队列
+	if err := s.saveToOfflineQueue(receiverID, message.ID); err != nil {
+		return err
+	}
+	// 没有任何在线连接时另外发送通知栏推送
+	if errors.Is(pushErr, websocket.ErrUserOffline) && !silent {
+		s.sendOfflinePush(message, receiverID)
+	}
+	return nil
+}
+
+// RequeueUnacked 推送后连接关闭或确认超时的消息，仍未送达的放回离线队列
+func (s *MessageService) RequeueUnacked(userID uint, messageIDs []uint) {
+	undelivered, err := s.messageDAO.GetUndelivered(messageIDs, userID)
+	if err != nil {
+		log.Printf("Failed to load unacked messages for user %d: %v", userID, err)
+		return
+	}
+
+	for _, id := range undelivered {
+		if err := s.saveToOfflineQueue(userID, id); err != nil {
+			log.Printf("Failed to requeue message %d for user %d: %v", id, userID, err)
+		}
+	}
+}
+
+// 撤回者身份
+const (
+	recallBySender        = "sender"
+	recallByGroupOwner    = "group_owner"
+	recallByGroupAdmin    = "group_admin"
+	recallByPlatformAdmin = "platform_admin"
+)
+
+// RecallMessage 撤回消息
+// 发送者在撤回时限内可撤回自己的消息；群主可撤回任何成员的消息，群管理员可撤回普通成员的消息；平台管理员可撤回任何消息
+// 撤回后通知会话所有参与者，并在会话中写入一条系统通知
+func (s *MessageService) RecallMessage(messageID, userID uint, ip, userAgent string) error {
+	message, err := s.messageDAO.GetByID(messageID)
+	if err != nil {
+		return err
+	}
+	if message.Status == model.MessageStatusRecalled {
+		return errors.New("message already recalled")
+	}
+	if message.Type == model.MessageTypeSystem {
+		return errors.New("system messages cannot be recalled")
+	}
+
+	conv, err := s.conversationDAO.GetByID(message.ConversationID)
+	if err != nil {
+		return errors.New("conversation not found")
+	}
+
+	recalledAs, err := s.recallRole(message, conv, userID)
+	if err != nil {
+		return err
+	}
+
+	// 更新消息状态，同时写入变更日志供离线设备同步；撤回的消息自动取消置顶
+	unpinned := false
+	err = mysql.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := s.messageDAO.WithTx(tx).RecallMessage(messageID); err != nil {
+			return err
+		}
+		changeDAO := s.changeDAO.WithTx(tx)
+		if err := changeDAO.Record(conv.ID, 0, model.ChangeMessageRecalled, &messageID); err != nil {
+			return err
+		}
+		var err error
+		if unpinned, err = s.pinDAO.WithTx(tx).Delete(conv.ID, messageID); err != nil || !unpinned {
+			return err
+		}
+		return changeDAO.Record(conv.ID, 0, model.ChangePinUpdated, &messageID)
+	})
+	if err == nil {
+		// 通知会话所有参与者（群消息的ReceiverID为0，不能只通知接收者）
+		go s.notifyParticipants(conv.ID, "message_status", map[string]interface{}{
+			"message_id":      messageID,
+			"conversation_id": conv.ID,
+			"status":          model.MessageStatusRecalled,
+			"recalled_by":     userID,
+			"timestamp":       time.Now().Format(time.RFC3339),
+		})
+
+		if unpinned {
+			go s.notifyPinUpdated(conv.ID, messageID, userID, pinActionUnpin)
+		}
+
+		s.systemMessages.post(conv, userID, model.SystemPayload{
+			Event:      model.SystemEventMessageRecalled,
+			OperatorID: userID,
+			TargetIDs:  []uint{message.SenderID},
+			Extra: map[string]interface{}{
+				"message_id":  messageID,
+				"recalled_as": recalledAs,
+			},
+		}, s.recallNotice(message, userID, recalledAs))
+	}
+
+	// 记录操作日志
+	s.logDAO.CreateLog(dao.LogRequest{
+		Action:    model.ActionMessageRecall,
+		UserID:    &userID,
+		IP:        ip,
+		UserAgent: userAgent,
+		Details: map[string]interface{}{
+			"message_id":       messageID,
+			"conversation_id":  message.ConversationID,
+			"sender_id":        message.SenderID,
+			"receiver_id":      message.ReceiverID,
+			"original_type":    message.Type,
+			"recalled_as":      recalledAs,
+			"recall_time_diff": time.Since(message.CreatedAt).Seconds(),
+		},
+		Result:       logResult(err),
+		ErrorMessage: logError(err),
+	})
+
+	return err
+}
+
+// recallRole 判断用户以什么身份撤回消息，没有权限时返回错误
+func (s *MessageService) recallRole(message *model.Message, conv *model.Conversation, userID uint) (string, error) {
+	if message.SenderID == userID && time.Since(message.CreatedAt) <= s.recallWindow {
+		return recallBySender, nil
+	}
+
+	// 群主、群管理员（包括撤回自己超过时限的消息）
+	if conv.Type == model.ConversationTypeGroup && conv.GroupID != nil {
+		if role, err := s.groupMemberDAO.GetMemberRole(*conv.GroupID, userID); err == nil {
+			switch role {
+			case model.GroupRoleOwner:
+				return recallByGroupOwner, nil
+			case model.GroupRoleAdmin:
+				// 已退群的成员视为普通成员
+				senderRole, _ := s.groupMemberDAO.GetMemberRole(*conv.GroupID, message.SenderID)
+				if message.SenderID == userID || (senderRole != model.GroupRoleOwner && senderRole != model.GroupRoleAdmin) {
+					return recallByGroupAdmin, nil
+				}
+			}
+		}
+	}
+
+	if user, err := s.userDAO.GetByID(userID); err == nil && user.Role == model.UserRoleAdmin {
+		return recallByPlatformAdmin, nil
+	}
+
+	if message.SenderID == userID {
+		return "", fmt.Errorf("can only recall messages within %d seconds", int(s.recallWindow.Seconds()))
+	}
+	return "", errors.New("no permission to recall this message")
+}
+
+// recallNotice 撤回系统通知的文本
+func (s *MessageService) recallNotice(message *model.Message, userID uint, recalledAs string) string {
+	senderName := s.systemMessages.userName(message.SenderID)
+	if recalledAs == recallBySender {
+		return senderName + " 撤回了一条消息"
+	}
+	if recalledAs == recallByPlatformAdmin {
+		return "管理员撤回了 " + senderName + " 的一条消息"
+	}
+
+	if message.SenderID == userID {
+		return senderName + " 撤回了一条消息"
+	}
+	return s.systemMessages.userName(userID) + " 撤回了 " + senderName + " 的一条消息"
+}
+
+// DeleteMessageForMe 仅对自己删除消息，其他参与者仍能看到
+// 通知用户的其他设备同步删除
+func (s *MessageService) DeleteMessageForMe(messageID, userID uint, ip, userAgent string) error {
+	message, err := s.messageDAO.GetByID(messageID)
+	if err != nil {
+		return errors.New("message not found")
+	}
+
+	conv, err := s.conversationDAO.GetByID(message.ConversationID)
+	if err != nil {
+		return errors.New("conversation not found")
+	}
+	participants, err := s.getParticipants(conv)
+	if err != nil {
+		return err
+	}
+	if !containsUser(participants, userID) {
+		return errors.New("not a conversation participant")
+	}
+
+	// 用户的其他设备通过 /sync 获知删除
+	err = mysql.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := s.deletionDAO.WithTx(tx).Add(userID, messageID, message.ConversationID); err != nil {
+			return err
+		}
+		return s.changeDAO.WithTx(tx).Record(message.ConversationID, userID, model.ChangeMessageDeleted, &messageID)
+	})
+
+	s.logDAO.CreateLog(dao.LogRequest{
+		Action:    model.ActionMessageDelete,
+		UserID:    &userID,
+		IP:        ip,
+		UserAgent: userAgent,
+		Details: map[string]interface{}{
+			"message_id":      messageID,
+			"conversation_id": message.ConversationID,
+			"scope":           "me",
+		},
+		Result:       logResult(err),
+		ErrorMessage: logError(err),
+	})
+	if err != nil {
+		return err
+	}
+
+	s.hub.SendToUser(userID, websocket.WebSocketMessage{
+		Type: "message_deleted",
+		Data: map[string]interface{}{
+			"message_id":      messageID,
+			"conversation_id": message.ConversationID,
+			"scope":           "me",
+		},
+	})
+	return nil
+}
+
+// ClearConversation 清空用户在会话中的聊天记录，当前最新消息及之前的消息对本人不可见
+func (s *MessageService) ClearConversation(conversationID, userID uint) error {
+	conv, err := s.conversationDAO.GetByID(conversationID)
+	if err != nil {
+		return errors.New("conversation not found")
+	}
+
+	err = mysql.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := s.memberDAO.WithTx(tx).Clear(conversationID, userID, conv.MaxSeq); err != nil {
+			return err
+		}
+		return s.changeDAO.WithTx(tx).Record(conversationID, userID, model.ChangeConversationCleared, nil)
+	})
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("conversation not found")
+		}
+		return err
+	}
+
+	s.hub.SendToUser(userID, websocket.WebSocketMessage{
+		Type: "conversation_cleared",
+		Data: map[string]interface{}{
+			"conversation_id": conversationID,
+			"cleared_seq":     conv.MaxSeq,
+		},
+	})
+	return nil
+}
+
+// HideConversation 把会话从用户的会话列表中移除，聊天记录保留，收到新消息后重新出现
+func (s *MessageService) HideConversation(conversationID, userID uint) error {
+	err := mysql.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := s.memberDAO.WithTx(tx).Hide(conversationID, userID); err != nil {
+			return err
+		}
+		return s.changeDAO.WithTx(tx).Record(conversationID, userID, model.ChangeConversationHidden, nil)
+	})
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("conversation not found")
+		}
+		return err
+	}
+
+	s.hub.SendToUser(userID, websocket.WebSocketMessage{
+		Type: "conversation_hidden",
+		Data: map[string]interface{}{
+			"conversation_id": conversationID,
+		},
+	})
+	return nil
+}
+
+// EditMessage 编辑文本消息
+// 只有发送者可以在编辑时限内编辑，编辑前的内容写入message_edits
+func (s *MessageService) EditMessage(messageID, userID uint, content, ip, userAgent string) (*model.Message, error) {
+	message, err := s.messageDAO.GetByID(messageID)
+	if err != nil {
+		return nil, errors.New("message not found")
+	}
+
+	if message.SenderID != userID {
+		return nil, errors.New("can only edit your own messages")
+	}
+	if message.Type != model.MessageTypeText {
+		return nil, errors.New("only text messages can be edited")
+	}
+	if message.Status == model.MessageStatusRecalled {
+		return nil, errors.New("message has been recalled")
+	}
+	if time.Since(message.CreatedAt) > s.editWindow {
+		return nil, fmt.Errorf("can only edit messages within %s", s.editWindow)
+	}
+	if content == message.Content {
+		return message, nil
+	}
+
 	now := time.Now()
-	s.conversationDAO.UpdateLastMessage(conversationID, message.ID, &now)
-
-	// 发送到Kafka（异步持久化和处理）
-	go func() {
-		ctx := context.Background()
-		messageData := kafka.MessageData{
-			ID:             message.ID,
-			ConversationID: message.ConversationID,
-			SenderID:       senderID,
-			ReceiverID:     receiverID,
-			Content:        content,
-			Type:           msgType,
-			FileURL:        message.FileURL,
-			CreatedAt:      message.CreatedAt.Unix(),
-		}
-
-		// ✅ Kafka发送失败处理（最多重试3次）
-		maxRetries := 3
-		for i := 0; i < maxRetries; i++ {
-			if err := s.producer.SendJSON(ctx, string(message.ID), messageData); err != nil {
-				if i == maxRetries-1 {
-					// 最后一次失败，记录错误日志
-					s.logDAO.CreateLog(dao.LogRequest{
-						Action: "kafka_send_failed",
-						UserID: &senderID,
-						Details: map[string]interface{}{
-							"message_id":  message.ID,
-							"retry_count": maxRetries,
-							"error":       err.Error(),
-						},
-						Result:       model.ResultFailure,
-						ErrorMessage: err.Error(),
-					})
-				} else {
-					// 等待后重试
-					time.Sleep(time.Duration(i+1) * 100 * time.Millisecond)
-					continue
-				}
-			} else {
-				// 发送成功
-				break
-			}
+	err = mysql.GetDB().Transaction(func(tx *gorm.DB) error {
+		updated, err := s.messageDAO.WithTx(tx).UpdateContent(messageID, message.Content, content, now)
+		if err != nil {
+			return err
+		}
+		if !updated {
+			return errors.New("message was modified concurrently, please retry")
 		}
-	}()
 
-	// 通过WebSocket实时推送给接收者
-	go func() {
-		if s.hub.IsUserOnline(receiverID) {
-			// 在线: 尝试推送
-			err := s.hub.SendMessageNotification(receiverID, message)
-			if err == nil {
-				// 推送成功,更新状态为已送达
-				s.messageDAO.UpdateStatus(message.ID, model.MessageStatusDelivered)
-			} else {
-				// 推送失败,存入离线队列
-				s.saveToOfflineQueue(receiverID, message.ID)
-			}
-		} else {
-			// 离线: 存入离线消息队列
-			s.saveToOfflineQueue(receiverID, message.ID)
+		if err := s.editDAO.WithTx(tx).Create(&model.MessageEdit{
+			MessageID:       messageID,
+			EditorID:        userID,
+			PreviousContent: message.Content,
+			Content:         content,
+		}); err != nil {
+			return err
 		}
-	}()
 
-	// 记录成功日志
+		return s.changeDAO.WithTx(tx).Record(message.ConversationID, 0, model.ChangeMessageEdited, &messageID)
+	})
+
 	s.logDAO.CreateLog(dao.LogRequest{
-		Action:    model.ActionMessageSend,
-		UserID:    &senderID,
+		Action:    model.ActionMessageEdit,
+		UserID:    &userID,
 		IP:        ip,
 		UserAgent: userAgent,
 		Details: map[string]interface{}{
-			"message_id":  message.ID,
-			"receiver_id": receiverID,
-			"type":        msgType,
+			"message_id":      messageID,
+			"conversation_id": message.ConversationID,
 		},
-		Result: model.ResultSuccess,
+		Result:       logResult(err),
+		ErrorMessage: logError(err),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	message.Content = content
+	message.EditedAt = &now
+	message.EditCount++
+
+	// 推送给会话所有参与者（含发送者的其他设备）
+	go s.notifyParticipants(message.ConversationID, "message_edited", map[string]interface{}{
+		"message_id":      message.ID,
+		"conversation_id": message.ConversationID,
+		"content":         message.Content,
+		"edited_at":       now,
+		"edit_count":      message.EditCount,
 	})
 
 	return message, nil
 }
 
-// RecallMessage 撤回消息
-func (s *MessageService) RecallMessage(messageID, userID uint, ip, userAgent string) error {
+// GetMessageEdits 获取消息的编辑历史，仅会话参与者可查看
+func (s *MessageService) GetMessageEdits(messageID, userID uint) ([]model.MessageEdit, error) {
 	message, err := s.messageDAO.GetByID(messageID)
 	if err != nil {
-		return err
+		return nil, errors.New("message not found")
 	}
 
-	// 只能撤回自己发送的消息
-	if message.SenderID != userID {
-		return errors.New("can only recall your own messages")
+	conv, err := s.conversationDAO.GetByID(message.ConversationID)
+	if err != nil {
+		return nil, errors.New("conversation not found")
+	}
+	participants, err := s.getParticipants(conv)
+	if err != nil {
+		return nil, err
+	}
+	if !containsUser(participants, userID) {
+		return nil, errors.New("not a conversation participant")
+	}
+
+	return s.editDAO.GetByMessageID(messageID)
+}
+
+// 每个用户在一条消息上最多回应的不同表情数
+const maxReactionsPerUser = 20
+
+// AddReaction 对消息添加表情回应
+func (s *MessageService) AddReaction(messageID, userID uint, emoji string) error {
+	if !isReactionEmoji(emoji) {
+		return errors.New("invalid emoji")
 	}
 
-	// 检查是否在2分钟内
-	if time.Since(message.CreatedAt) > 2*time.Minute {
-		return errors.New("can only recall messages within 2 minutes")
+	message, err := s.reactionTarget(messageID, userID, emoji)
+	if err != nil {
+		return err
 	}
 
-	// 更新消息状态
-	err = s.messageDAO.RecallMessage(messageID)
+	added := false
+	err = mysql.GetDB().Transaction(func(tx *gorm.DB) error {
+		reactionDAO := s.reactionDAO.WithTx(tx)
 
-	// 通知接收者
-	go func() {
-		if s.hub.IsUserOnline(message.ReceiverID) {
-			s.hub.SendMessageStatusUpdate(message.ReceiverID, messageID, model.MessageStatusRecalled)
+		// 锁定消息行，同一用户的并发回应按顺序检查数量上限
+		if err := s.messageDAO.WithTx(tx).Lock(messageID); err != nil {
+			return err
+		}
+		count, err := reactionDAO.CountUserEmojis(messageID, userID)
+		if err != nil {
+			return err
+		}
+		if count >= maxReactionsPerUser {
+			return fmt.Errorf("at most %d reactions per message", maxReactionsPerUser)
 		}
-	}()
 
-	// 记录操作日志
-	details := map[string]interface{}{
-		"message_id":       messageID,
-		"conversation_id":  message.ConversationID,
-		"receiver_id":      message.ReceiverID,
-		"original_type":    message.Type,
-		"recall_time_diff": time.Since(message.CreatedAt).Seconds(),
+		added, err = reactionDAO.Add(messageID, userID, emoji)
+		if err != nil || !added {
+			return err
+		}
+		return s.changeDAO.WithTx(tx).Record(message.ConversationID, 0, model.ChangeReactionUpdated, &messageID)
+	})
+	if err != nil {
+		return err
+	}
+	if added {
+		go s.notifyReactionUpdated(message, userID, emoji, "add")
 	}
+	return nil
+}
 
-	result := model.ResultSuccess
-	errorMsg := ""
+// RemoveReaction 移除自己的表情回应
+func (s *MessageService) RemoveReaction(messageID, userID uint, emoji string) error {
+	message, err := s.reactionTarget(messageID, userID, emoji)
 	if err != nil {
-		result = model.ResultFailure
-		errorMsg = err.Error()
+		return err
 	}
 
-	s.logDAO.CreateLog(dao.LogRequest{
-		Action:       model.ActionMessageRecall,
-		UserID:       &userID,
-		IP:           ip,
-		UserAgent:    userAgent,
-		Details:      details,
-		Result:       result,
-		ErrorMessage: errorMsg,
+	removed := false
+	err = mysql.GetDB().Transaction(func(tx *gorm.DB) error {
+		var err error
+		removed, err = s.reactionDAO.WithTx(tx).Remove(messageID, userID, emoji)
+		if err != nil || !removed {
+			return err
+		}
+		return s.changeDAO.WithTx(tx).Record(message.ConversationID, 0, model.ChangeReactionUpdated, &messageID)
+	})
+	if err != nil {
+		return err
+	}
+	if removed {
+		go s.notifyReactionUpdated(message, userID, emoji, "remove")
+	}
+	return nil
+}
+
+// reactionTarget 校验表情和回应权限（会话参与者、消息未撤回），返回被回应的消息
+// 移除回应只校验长度，早先写入的不合法回应仍可以移除
+func (s *MessageService) reactionTarget(messageID, userID uint, emoji string) (*model.Message, error) {
+	if emoji == "" || len(emoji) > maxEmojiBytes {
+		return nil, errors.New("invalid emoji")
+	}
+
+	message, err := s.messageDAO.GetByID(messageID)
+	if err != nil {
+		return nil, errors.New("message not found")
+	}
+	if message.Status == model.MessageStatusRecalled {
+		return nil, errors.New("message has been recalled")
+	}
+
+	conv, err := s.conversationDAO.GetByID(message.ConversationID)
+	if err != nil {
+		return nil, errors.New("conversation not found")
+	}
+	participants, err := s.getParticipants(conv)
+	if err != nil {
+		return nil, err
+	}
+	if !containsUser(participants, userID) {
+		return nil, errors.New("not a conversation participant")
+	}
+
+	return message, nil
+}
+
+// notifyReactionUpdated 把消息最新的表情汇总推送给会话所有参与者
+// 汇总中的reacted字段不针对接收者，客户端根据user_id和action维护自己的状态
+func (s *MessageService) notifyReactionUpdated(message *model.Message, userID uint, emoji, action string) {
+	summaries, err := s.reactionDAO.GetSummaries([]uint{message.ID}, 0)
+	if err != nil {
+		log.Printf("Failed to load reactions of message %d: %v", message.ID, err)
+		return
+	}
+
+	reactions := summaries[message.ID]
+	if reactions == nil {
+		reactions = []model.ReactionSummary{}
+	}
+
+	s.notifyParticipants(message.ConversationID, "reaction_updated", map[string]interface{}{
+		"message_id":      message.ID,
+		"conversation_id": message.ConversationID,
+		"user_id":         userID,
+		"emoji":           emoji,
+		"action":          action,
+		"reactions":       reactions,
 	})
+}
 
-	return err
+// attachReactions 为消息列表填充表情回应汇总
+func (s *MessageService) attachReactions(messages []model.Message, userID uint) {
+	if len(messages) == 0 {
+		return
+	}
+
+	ids := make([]uint, len(messages))
+	for i := range messages {
+		ids[i] = messages[i].ID
+	}
+
+	summaries, err := s.reactionDAO.GetSummaries(ids, userID)
+	if err != nil {
+		log.Printf("Failed to load reactions: %v", err)
+		return
+	}
+	for i := range messages {
+		messages[i].Reactions = summaries[messages[i].ID]
+	}
 }
 
 // MarkAsRead 标记消息为已读并发送已读回执
 func (s *MessageService) MarkAsRead(conversationID, userID uint) error {
+	conv, err := s.conversationDAO.GetByID(conversationID)
+	if err != nil {
+		return errors.New("conversation not found")
+	}
+	participants, err := s.getParticipants(conv)
+	if err != nil {
+		return err
+	}
+	if !containsUser(participants, userID) {
+		return errors.New("not a conversation participant")
+	}
+
 	// 标记会话中所有未读消息为已读
-	err := s.messageDAO.MarkAsRead(conversationID, userID)
+	err = s.messageDAO.MarkAsRead(conversationID, userID)
 	if err != nil {
 		return err
 	}
 
+	// 清零本人未读数，已读位置推进到会话最新消息
+	if err := s.memberDAO.MarkRead(conversationID, userID, conv.MaxSeq); err != nil {
+		return err
+	}
+
+	// 已读后计时的阅后即焚消息从现在开始计时
+	if err := s.messageDAO.StartReadTimers(conversationID, userID, conv.MaxSeq); err != nil {
+		return err
+	}
+
 	// 获取该会话中userID作为接收者的所有消息，找到发送者
-	messages, _, err := s.messageDAO.GetByConversationID(conversationID, 1, 100)
+	messages, _, err := s.messageDAO.GetByConversationID(userID, conversationID, 1, 100)
 	if err != nil || len(messages) == 0 {
 		return err
 	}
@@ -270,14 +1066,113 @@ func (s *MessageService) MarkAsRead(conversationID, userID uint) error {
 	return nil
 }
 
+// AckDelivered 接收者确认收到消息，标记为已送达并通知发送者
+func (s *MessageService) AckDelivered(userID uint, messageIDs []uint) error {
+	if len(messageIDs) == 0 {
+		return nil
+	}
+
+	messages, err := s.messageDAO.MarkDelivered(messageIDs, userID)
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		for _, msg := range messages {
+			s.hub.SendMessageStatusUpdate(msg.SenderID, msg.ID, model.MessageStatusDelivered)
+		}
+	}()
+
+	return nil
+}
+
+// notifyParticipants 把事件推送给会话的所有参与者（含操作者本人的其他设备）
+func (s *MessageService) notifyParticipants(conversationID uint, eventType string, data interface{}) {
+	conv, err := s.conversationDAO.GetByID(conversationID)
+	if err != nil {
+		log.Printf("Failed to load conversation %d for %s: %v", conversationID, eventType, err)
+		return
+	}
+
+	participants, err := s.getParticipants(conv)
+	if err != nil {
+		log.Printf("Failed to load participants of conversation %d for %s: %v", conversationID, eventType, err)
+		return
+	}
+
+	for _, uid := range participants {
+		s.hub.SendToUser(uid, websocket.WebSocketMessage{
+			Type: eventType,
+			Data: data,
+		})
+	}
+}
+
+// getParticipants 获取会话的所有参与者（单聊双方或群成员），同时刷新参与者缓存
+func (s *MessageService) getParticipants(conv *model.Conversation) ([]uint, error) {
+	if conv.Type == model.ConversationTypeGroup {
+		if conv.GroupID == nil {
+			return nil, errors.New("group conversation without group")
+		}
+		participants, err := s.groupMemberDAO.GetMemberIDs(*conv.GroupID)
+		if err != nil {
+			return nil, err
+		}
+		cacheParticipants(conv.ID, participants)
+		return participants, nil
+	}
+
+	participants := make([]uint, 0, 2)
+	if conv.User1ID != nil {
+		participants = append(participants, *conv.User1ID)
+	}
+	if conv.User2ID != nil {
+		participants = append(participants, *conv.User2ID)
+	}
+	cacheParticipants(conv.ID, participants)
+	return participants, nil
+}
+
+// logResult 根据错误返回操作日志结果
+func logResult(err error) string {
+	if err != nil {
+		return model.ResultFailure
+	}
+	return model.ResultSuccess
+}
+
+// logError 返回操作日志的错误信息
+func logError(err error) string {
+	if err != nil {
+		return err.Error()
+	}
+	return ""
+}
+
+// containsUser 判断用户ID列表中是否包含指定用户
+func containsUser(userIDs []uint, userID uint) bool {
+	for _, id := range userIDs {
+		if id == userID {
+			return true
+		}
+	}
+	return false
+}
+
 // GetMessages 获取消息列表
-func (s *MessageService) GetMessages(conversationID uint, page, pageSize int) ([]model.Message, int64, error) {
-	return s.messageDAO.GetByConversationID(conversationID, page, pageSize)
+func (s *MessageService) GetMessages(userID, conversationID uint, page, pageSize int) ([]model.Message, int64, error) {
+	messages, total, err := s.messageDAO.GetByConversationID(userID, conversationID, page, pageSize)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	s.attachReactions(messages, userID)
+	return messages, total, nil
 }
 
 // GetHistoryMessages 获取历史消息（业务层）
 // 直接调用DAO层，未来可在此添加业务逻辑（如权限验证、敏感词过滤等）
-func (s *MessageService) GetHistoryMessages(conversationID, beforeMessageID uint, limit int) ([]model.Message, error) {
+func (s *MessageService) GetHistoryMessages(userID, conversationID, beforeMessageID uint, limit int) ([]model.Message, error) {
 	// 限制每次最多查询100条，防止数据量过大
 	if limit > 100 {
 		limit = 100
@@ -287,7 +1182,235 @@ func (s *MessageService) GetHistoryMessages(conversationID, beforeMessageID uint
 		limit = 20 // 默认20条
 	}
 
-	return s.messageDAO.GetHistoryMessages(conversationID, beforeMessageID, limit)
+	messages, err := s.messageDAO.GetHistoryMessages(userID, conversationID, beforeMessageID, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	s.attachReactions(messages, userID)
+	return messages, nil
+}
+
+// SyncConversation 单个会话的增量同步结果
+type SyncConversation struct {
+	Conversation model.Conversation `json:"conversation"`
+	Messages     []model.Message    `json:"messages"`
+	HasMore      bool               `json:"has_more"` // 还有更多消息，客户端应以返回的最大seq继续同步
+}
+
+// SyncResult 增量同步结果
+type SyncResult struct {
+	Conversations  []SyncConversation         `json:"conversations"`
+	Changes        []model.ConversationChange `json:"changes"`          // 游标之后的编辑、撤回、设置等变更
+	ChangeCursor   uint64                     `json:"change_cursor"`    // 下次同步时传入的变更游标
+	HasMoreChanges bool                       `json:"has_more_changes"` // 还有更多变更，客户端应以change_cursor继续同步
+	ChangesExpired bool                       `json:"changes_expired"`  // 游标之后的变更已被清理，客户端应重新加载本地消息
+}
+
+const (
+	// 每次同步最多返回的变更数
+	syncChangeLimit = 1000
+	// 变更写入后等待一段时间才参与同步：自增ID按写入顺序分配但事务提交有先后，
+	// 只返回足够早的变更，保证游标之前不会再出现晚提交的变更
+	changeSettleDelay = 5 * time.Second
+	// 变更日志的保留时长
+	changeRetention = 30 * 24 * time.Hour
+)
+
+// Sync 增量同步
+//
+// 参数说明:
+//   - since: 客户端已有的每个会话的最大seq（conversationID -> seq）
+//   - changeCursor: 上次同步返回的变更游标，首次同步为0
+//   - limit: 每个会话最多返回的消息数
+//
+// 返回说明:
+//   - 只返回有新消息的会话：客户端未知的会话，或max_seq大于客户端seq的会话
+//   - 客户端已知的会话返回seq之后的消息（正序），超出limit时HasMore为true
+//   - 客户端未知的会话只返回最新的limit条消息，更早的消息通过历史接口加载
+//   - 已有消息的变更通过变更日志返回，消息类变更附带消息的当前状态，个人设置类变更附带当前设置
+//   - 首次同步不返回变更，只返回当前游标
+func (s *MessageService) Sync(userID uint, since map[uint]uint64, changeCursor uint64, limit int) (*SyncResult, error) {
+	if limit <= 0 || limit > 500 {
+		limit = 200
+	}
+
+	conversations, err := s.conversationDAO.GetSyncConversations(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &SyncResult{Conversations: []SyncConversation{}, Changes: []model.ConversationChange{}}
+	byID := make(map[uint]*model.Conversation, len(conversations))
+	for i := range conversations {
+		byID[conversations[i].ID] = &conversations[i]
+	}
+
+	for _, conv := range conversations {
+		knownSeq, known := since[conv.ID]
+		if known && conv.MaxSeq <= knownSeq {
+			continue
+		}
+
+		item := SyncConversation{Conversation: conv}
+		if known {
+			// 多取一条判断是否还有更多
+			messages, err := s.messageDAO.GetAfterSeq(userID, conv.ID, knownSeq, limit+1)
+			if err != nil {
+				return nil, err
+			}
+			if len(messages) > limit {
+				messages = messages[:limit]
+				item.HasMore = true
+			}
+			item.Messages = messages
+		} else {
+			messages, err := s.messageDAO.GetLatestBySeq(userID, conv.ID, limit)
+			if err != nil {
+				return nil, err
+			}
+			item.Messages = messages
+		}
+
+		result.Conversations = append(result.Conversations, item)
+	}
+
+	if err := s.syncChanges(result, userID, byID, changeCursor); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// syncChanges 填充用户参与的会话在游标之后的变更
+func (s *MessageService) syncChanges(result *SyncResult, userID uint, conversations map[uint]*model.Conversation, cursor uint64) error {
+	minID, maxID, err := s.changeDAO.Bounds()
+	if err != nil {
+		return err
+	}
+
+	// 首次同步：客户端刚拿到全部最新状态，从当前位置开始记录
+	if cursor == 0 {
+		result.ChangeCursor = maxID
+		return nil
+	}
+	// 游标之后的变更已被清理（日志为空说明游标之前的记录也已清理）
+	if minID == 0 || cursor+1 < minID {
+		result.ChangesExpired = true
+		result.ChangeCursor = maxID
+		return nil
+	}
+
+	conversationIDs := make([]uint, 0, len(conversations))
+	for id := range conversations {
+		conversationIDs = append(conversationIDs, id)
+	}
+	changes, err := s.changeDAO.ListSince(userID, conversationIDs, cursor, time.Now().Add(-changeSettleDelay), syncChangeLimit+1)
+	if err != nil {
+		return err
+	}
+	result.ChangeCursor = cursor
+	if len(changes) > syncChangeLimit {
+		changes = changes[:syncChangeLimit]
+		result.HasMoreChanges = true
+	}
+	if len(changes) == 0 {
+		return nil
+	}
+	result.ChangeCursor = changes[len(changes)-1].ID
+
+	changes = compactChanges(changes)
+	if err := s.attachChangeState(changes, userID, conversations); err != nil {
+		return err
+	}
+	result.Changes = changes
+	return nil
+}
+
+// compactChanges 同一对象的同类变更只保留最后一条，返回的状态都是当前状态，前面的记录没有意义
+func compactChanges(changes []model.ConversationChange) []model.ConversationChange {
+	type changeKey struct {
+		conversationID uint
+		kind           string
+		messageID      uint
+	}
+
+	last := make(map[changeKey]int, len(changes))
+	for i, change := range changes {
+		key := changeKey{conversationID: change.ConversationID, kind: change.Kind}
+		if change.MessageID != nil {
+			key.messageID = *change.MessageID
+		}
+		last[key] = i
+	}
+
+	compacted := make([]model.ConversationChange, 0, len(last))
+	for i, change := range changes {
+		key := changeKey{conversationID: change.ConversationID, kind: change.Kind}
+		if change.MessageID != nil {
+			key.messageID = *change.MessageID
+		}
+		if last[key] == i {
+			compacted = append(compacted, change)
+		}
+	}
+	return compacted
+}
+
+// attachChangeState 为变更填充消息的当前状态、用户的会话设置和会话的共享设置
+// 消息已删除或已过期时message为空，客户端应删除本地副本
+func (s *MessageService) attachChangeState(changes []model.ConversationChange, userID uint, conversations map[uint]*model.Conversation) error {
+	var messageIDs, conversationIDs []uint
+	for _, change := range changes {
+		if change.MessageID != nil {
+			messageIDs = append(messageIDs, *change.MessageID)
+		}
+		if model.PersonalChange(change.Kind) {
+			conversationIDs = append(conversationIDs, change.ConversationID)
+		}
+	}
+
+	// 用户删除、清空掉或已过期的消息不返回内容
+	messages, err := s.messageDAO.GetVisibleByIDs(userID, dedupeIDs(messageIDs))
+	if err != nil {
+		return err
+	}
+	s.attachReactions(messages, userID)
+	byID := make(map[uint]*model.Message, len(messages))
+	for i := range messages {
+		byID[messages[i].ID] = &messages[i]
+	}
+
+	settings, err := s.memberDAO.GetByConversations(userID, dedupeIDs(conversationIDs))
+	if err != nil {
+		return err
+	}
+
+	for i := range changes {
+		if changes[i].MessageID != nil {
+			changes[i].Message = byID[*changes[i].MessageID]
+		}
+		if model.PersonalChange(changes[i].Kind) {
+			changes[i].Settings = settings[changes[i].ConversationID]
+		}
+		if changes[i].Kind == model.ChangeConversationUpdated {
+			changes[i].Conversation = conversations[changes[i].ConversationID]
+		}
+	}
+	return nil
+}
+
+// dedupeIDs 去重并保持原有顺序
+func dedupeIDs(ids []uint) []uint {
+	seen := make(map[uint]bool, len(ids))
+	result := make([]uint, 0, len(ids))
+	for _, id := range ids {
+		if id == 0 || seen[id] {
+			continue
+		}
+		seen[id] = true
+		result = append(result, id)
+	}
+	return result
 }
 
 // SearchMessages 搜索消息
@@ -330,17 +1453,30 @@ func (s *MessageService) GetOfflineMessages(userID uint) ([]model.Message, error
 		return []model.Message{}, nil
 	}
 	
-	// 从数据库加载完整消息
-	messages := []model.Message{}
+	ids := make([]uint, 0, len(messageIDs))
 	for _, idStr := range messageIDs {
 		id, err := strconv.ParseUint(idStr, 10, 32)
 		if err != nil {
 			continue
 		}
-		
-		msg, err := s.messageDAO.GetByID(uint(id))
-		if err == nil {
-			messages = append(messages, *msg)
+		ids = append(ids, uint(id))
+	}
+
+	// 从数据库加载完整消息，跳过用户已删除、清空前和已过期的消息
+	visible, err := s.messageDAO.GetVisibleByIDs(userID, ids)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[uint]model.Message, len(visible))
+	for _, msg := range visible {
+		byID[msg.ID] = msg
+	}
+
+	// 按离线队列的顺序返回
+	messages := []model.Message{}
+	for _, id := range ids {
+		if msg, ok := byID[id]; ok {
+			messages = append(messages, msg)
 		}
 	}
 	
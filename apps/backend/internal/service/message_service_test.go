@@ -0,0 +1,208 @@
+package service
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lanxin/im-backend/internal/dao"
+	"github.com/lanxin/im-backend/internal/model"
+	"github.com/lanxin/im-backend/internal/testutil"
+)
+
+func TestSameSendRequest(t *testing.T) {
+	groupID, otherGroupID := uint(7), uint(8)
+	existing := model.Message{
+		ReceiverID: 2,
+		Type:       model.MessageTypeImage,
+		Content:    "[图片]",
+		FileURL:    "https://cdn.example.com/a.png",
+	}
+
+	tests := []struct {
+		name   string
+		modify func(m *model.Message)
+		want   bool
+	}{
+		{"identical", func(m *model.Message) {}, true},
+		{"different receiver", func(m *model.Message) { m.ReceiverID = 3 }, false},
+		{"group instead of receiver", func(m *model.Message) { m.ReceiverID = 0; m.GroupID = &groupID }, false},
+		{"different type", func(m *model.Message) { m.Type = model.MessageTypeText }, false},
+		{"different content", func(m *model.Message) { m.Content = "看这张" }, false},
+		{"different file", func(m *model.Message) { m.FileURL = "https://cdn.example.com/b.png" }, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := existing
+			tt.modify(&request)
+			if got := sameSendRequest(&existing, &request); got != tt.want {
+				t.Errorf("sameSendRequest() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	location := model.Message{
+		ReceiverID: 2,
+		Type:       model.MessageTypeLocation,
+		Content:    "[位置] 公司",
+		Payload:    json.RawMessage(`{"latitude": 30.5, "longitude": 114.3, "title": "公司"}`),
+	}
+	payloadTests := []struct {
+		name    string
+		payload json.RawMessage
+		want    bool
+	}{
+		{"payload reformatted", json.RawMessage(`{"title":"公司","longitude":114.3,"latitude":30.5}`), true},
+		{"different payload", json.RawMessage(`{"latitude": 31, "longitude": 114.3, "title": "公司"}`), false},
+		{"missing payload", nil, false},
+	}
+	for _, tt := range payloadTests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := location
+			request.Payload = tt.payload
+			if got := sameSendRequest(&location, &request); got != tt.want {
+				t.Errorf("sameSendRequest() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("edited message compares target only", func(t *testing.T) {
+		edited := existing
+		edited.EditCount = 1
+		edited.Content = "改过的说明"
+		if !sameSendRequest(&edited, &existing) {
+			t.Error("retry of an edited message should match")
+		}
+	})
+
+	t.Run("different group", func(t *testing.T) {
+		a := model.Message{GroupID: &groupID, Type: model.MessageTypeText, Content: "hi"}
+		b := a
+		b.GroupID = &otherGroupID
+		if sameSendRequest(&a, &b) {
+			t.Error("retry to a different group should conflict")
+		}
+	})
+}
+
+func TestCompactChangesKeepsLastPerObject(t *testing.T) {
+	id := func(v uint) *uint { return &v }
+	changes := []model.ConversationChange{
+		{ID: 1, ConversationID: 1, Kind: model.ChangeMessageRecalled, MessageID: id(10)},
+		{ID: 2, ConversationID: 1, Kind: model.ChangeMessageRecalled, MessageID: id(11)},
+		{ID: 3, ConversationID: 1, Kind: model.ChangeMessageRecalled, MessageID: id(10)},
+		{ID: 4, ConversationID: 2, Kind: model.ChangeMessageRecalled, MessageID: id(10)},
+		{ID: 5, ConversationID: 2, Kind: model.ChangeSettingsUpdated},
+		{ID: 6, ConversationID: 1, Kind: model.ChangeMessageEdited, MessageID: id(11)},
+		{ID: 7, ConversationID: 2, Kind: model.ChangeSettingsUpdated},
+	}
+
+	got := compactChanges(changes)
+	var ids []uint64
+	for _, change := range got {
+		ids = append(ids, change.ID)
+	}
+	want := []uint64{2, 3, 4, 6, 7}
+	if len(ids) != len(want) {
+		t.Fatalf("compactChanges ids = %v, want %v", ids, want)
+	}
+	for i := range want {
+		if ids[i] != want[i] {
+			t.Fatalf("compactChanges ids = %v, want %v", ids, want)
+		}
+	}
+}
+
+func TestSyncChangesCursor(t *testing.T) {
+	boundsSQL := "SELECT COALESCE\\(MIN\\(id\\), 0\\) AS min_id, COALESCE\\(MAX\\(id\\), 0\\) AS max_id FROM `conversation_changes`"
+	bounds := func(minID, maxID uint64) *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"min_id", "max_id"}).AddRow(minID, maxID)
+	}
+	conversations := map[uint]*model.Conversation{12: {ID: 12}}
+
+	t.Run("first sync starts at the current position", func(t *testing.T) {
+		mock := testutil.NewMockDB(t)
+		s := &MessageService{changeDAO: dao.NewConversationChangeDAO()}
+		mock.ExpectQuery(boundsSQL).WillReturnRows(bounds(5, 40))
+
+		result := &SyncResult{}
+		if err := s.syncChanges(result, 1, conversations, 0); err != nil {
+			t.Fatal(err)
+		}
+		if result.ChangeCursor != 40 || len(result.Changes) != 0 || result.ChangesExpired {
+			t.Errorf("result = %+v, want cursor 40 without changes", result)
+		}
+	})
+
+	t.Run("cursor older than retention", func(t *testing.T) {
+		mock := testutil.NewMockDB(t)
+		s := &MessageService{changeDAO: dao.NewConversationChangeDAO()}
+		mock.ExpectQuery(boundsSQL).WillReturnRows(bounds(100, 140))
+
+		result := &SyncResult{}
+		if err := s.syncChanges(result, 1, conversations, 20); err != nil {
+			t.Fatal(err)
+		}
+		if !result.ChangesExpired || result.ChangeCursor != 140 {
+			t.Errorf("result = %+v, want expired with cursor 140", result)
+		}
+	})
+
+	t.Run("no new changes keeps the cursor", func(t *testing.T) {
+		mock := testutil.NewMockDB(t)
+		s := &MessageService{changeDAO: dao.NewConversationChangeDAO()}
+		mock.ExpectQuery(boundsSQL).WillReturnRows(bounds(1, 40))
+		mock.ExpectQuery("SELECT \\* FROM `conversation_changes` WHERE id > \\? AND created_at < \\? AND conversation_id IN \\(\\?\\) AND user_id IN \\(\\?,\\?\\) ORDER BY id ASC LIMIT 1001").
+			WithArgs(40, sqlmock.AnyArg(), 12, 0, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+		result := &SyncResult{}
+		if err := s.syncChanges(result, 1, conversations, 40); err != nil {
+			t.Fatal(err)
+		}
+		if result.ChangesExpired || result.ChangeCursor != 40 || len(result.Changes) != 0 {
+			t.Errorf("result = %+v, want cursor 40 without changes", result)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+	})
+}
+
+func TestAddReactionLimitCheckedUnderLock(t *testing.T) {
+	mock := testutil.NewMockDB(t)
+	testutil.NewRedis(t)
+	s := &MessageService{
+		messageDAO:      dao.NewMessageDAO(),
+		conversationDAO: dao.NewConversationDAO(),
+		reactionDAO:     dao.NewMessageReactionDAO(),
+		changeDAO:       dao.NewConversationChangeDAO(),
+	}
+
+	mock.ExpectQuery("SELECT \\* FROM `messages` WHERE id = \\?").
+		WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "conversation_id", "seq", "sender_id", "receiver_id", "type", "status"}).
+			AddRow(7, 12, 1, 2, 1, model.MessageTypeText, model.MessageStatusSent))
+	mock.ExpectQuery("SELECT \\* FROM `users`").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectQuery("SELECT \\* FROM `users`").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectQuery("SELECT \\* FROM `conversations`").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "type", "user1_id", "user2_id"}).AddRow(12, model.ConversationTypeSingle, 1, 2))
+
+	// 检查和写入在同一事务中，先锁定消息行
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT `id` FROM `messages` WHERE id = \\? .*FOR UPDATE").
+		WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+	mock.ExpectQuery("SELECT count\\(\\*\\) FROM `message_reactions` WHERE message_id = \\? AND user_id = \\?").
+		WithArgs(7, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(maxReactionsPerUser))
+	mock.ExpectRollback()
+
+	err := s.AddReaction(7, 1, "👍")
+	if err == nil || err.Error() != "at most 20 reactions per message" {
+		t.Fatalf("AddReaction() error = %v, want reaction limit", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,96 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lanxin/im-backend/internal/dao"
+	"github.com/lanxin/im-backend/internal/model"
+	"github.com/lanxin/im-backend/internal/pkg/redis"
+	"github.com/lanxin/im-backend/internal/testutil"
+)
+
+const visibilitySQL = " AND \\(messages.seq > COALESCE\\(\\(SELECT cm.cleared_seq .* AND \\(NOT EXISTS \\(SELECT 1 FROM message_deletions md "
+
+func TestGetOfflineMessagesSkipsInvisible(t *testing.T) {
+	mock := testutil.NewMockDB(t)
+	mock.MatchExpectationsInOrder(false)
+	testutil.NewRedis(t)
+	s := &MessageService{messageDAO: dao.NewMessageDAO(), redisClient: redis.GetClient()}
+
+	ctx := context.Background()
+	redis.GetClient().RPush(ctx, "offline_msg:1", 3, 1, 2)
+
+	// 消息2已被用户删除，可见性条件过滤后只返回1和3
+	mock.ExpectQuery("SELECT \\* FROM `messages` WHERE id IN \\(\\?,\\?,\\?\\)"+visibilitySQL).
+		WithArgs(3, 1, 2, 1, 1, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "conversation_id", "seq", "sender_id", "receiver_id"}).
+			AddRow(1, 12, 1, 2, 1).
+			AddRow(3, 12, 3, 2, 1))
+	mock.ExpectQuery("SELECT \\* FROM `users`").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectQuery("SELECT \\* FROM `users`").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	messages, err := s.GetOfflineMessages(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(messages) != 2 || messages[0].ID != 3 || messages[1].ID != 1 {
+		t.Fatalf("messages = %+v, want 3 and 1 in queue order", messages)
+	}
+	if n, _ := redis.GetClient().Exists(ctx, "offline_msg:1").Result(); n != 0 {
+		t.Error("offline queue should be cleared")
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestForwardLoadSourcesRejectsInvisible(t *testing.T) {
+	mock := testutil.NewMockDB(t)
+	mock.MatchExpectationsInOrder(false)
+	s := &ForwardService{messageDAO: dao.NewMessageDAO()}
+
+	// 消息8已被转发者删除，查询只返回消息7
+	mock.ExpectQuery("SELECT \\* FROM `messages` WHERE id IN \\(\\?,\\?\\)"+visibilitySQL).
+		WithArgs(7, 8, 1, 1, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "conversation_id", "seq", "sender_id", "receiver_id"}).
+			AddRow(7, 12, 1, 2, 1))
+	mock.ExpectQuery("SELECT \\* FROM `users`").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectQuery("SELECT \\* FROM `users`").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	_, err := s.loadSources(1, []uint{7, 8})
+	if err == nil || err.Error() != "message not found" {
+		t.Fatalf("loadSources() error = %v, want message not found", err)
+	}
+}
+
+func TestPinMessageRejectsInvisible(t *testing.T) {
+	mock := testutil.NewMockDB(t)
+	testutil.NewRedis(t)
+	s := &MessageService{
+		messageDAO:      dao.NewMessageDAO(),
+		conversationDAO: dao.NewConversationDAO(),
+	}
+
+	mock.ExpectQuery("SELECT \\* FROM `messages` WHERE id = \\?").
+		WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "conversation_id", "seq", "sender_id", "receiver_id", "type", "status"}).
+			AddRow(7, 12, 1, 2, 1, model.MessageTypeText, model.MessageStatusSent))
+	mock.ExpectQuery("SELECT \\* FROM `users`").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectQuery("SELECT \\* FROM `users`").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectQuery("SELECT \\* FROM `conversations`").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "type", "user1_id", "user2_id"}).AddRow(12, model.ConversationTypeSingle, 1, 2))
+	// 消息7已被置顶者删除
+	mock.ExpectQuery("SELECT count\\(\\*\\) FROM `messages` WHERE id = \\?"+visibilitySQL).
+		WithArgs(7, 1, 1, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+
+	_, err := s.PinMessage(7, 1)
+	if err == nil || err.Error() != "message not found" {
+		t.Fatalf("PinMessage() error = %v, want message not found", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,64 @@
+package service
+
+import (
+	"context"
+	"log"
+	"time"
+	"unicode/utf8"
+
+	"github.com/lanxin/im-backend/config"
+	"github.com/lanxin/im-backend/internal/model"
+	"github.com/lanxin/im-backend/pkg/push"
+)
+
+// 通知栏推送中消息摘要的最大长度
+const offlinePushBodyLength = 60
+
+// newPushClient 按配置创建推送网关客户端，未配置网关时返回nil
+func newPushClient(cfg *config.Config) *push.Client {
+	return push.NewClient(push.Config{
+		Endpoint:  cfg.Push.Endpoint,
+		AppKey:    cfg.Push.AppKey,
+		AppSecret: cfg.Push.AppSecret,
+		Timeout:   time.Duration(cfg.Push.TimeoutMs) * time.Millisecond,
+	})
+}
+
+// sendOfflinePush 接收者没有任何在线连接时发送通知栏推送
+// 推送失败只记录日志：消息已进入离线队列，客户端上线后仍会收到
+func (s *MessageService) sendOfflinePush(message *model.Message, receiverID uint) {
+	if s.pusher == nil {
+		return
+	}
+
+	title := "新消息"
+	if sender, err := s.userDAO.GetByID(message.SenderID); err == nil {
+		title = sender.Username
+	}
+
+	err := s.pusher.Send(context.Background(), push.Notification{
+		UserIDs: []uint{receiverID},
+		Title:   title,
+		Body:    offlinePushBody(message),
+		Data: map[string]interface{}{
+			"type":            "message",
+			"message_id":      message.ID,
+			"conversation_id": message.ConversationID,
+		},
+	})
+	if err != nil {
+		log.Printf("Failed to send offline push for message %d to user %d: %v", message.ID, receiverID, err)
+	}
+}
+
+// offlinePushBody 通知栏显示的消息摘要，阅后即焚消息不显示内容
+func offlinePushBody(message *model.Message) string {
+	if message.Disappearing() {
+		return "[阅后即焚消息]"
+	}
+	content := message.Content
+	if utf8.RuneCountInString(content) <= offlinePushBodyLength {
+		return content
+	}
+	return string([]rune(content)[:offlinePushBodyLength]) + "…"
+}
@@ -0,0 +1,313 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync/atomic"
+	"time"
+
+	"github.com/lanxin/im-backend/config"
+	"github.com/lanxin/im-backend/internal/dao"
+	"github.com/lanxin/im-backend/internal/model"
+	"github.com/lanxin/im-backend/internal/pkg/mysql"
+	"github.com/lanxin/im-backend/pkg/kafka"
+	"gorm.io/gorm"
+)
+
+const (
+	// 单次投递失败后的最大退避时间
+	outboxMaxBackoff = 5 * time.Minute
+
+	// 已投递事件的清理周期
+	outboxCleanupInterval = time.Hour
+
+	// 领取事件的租约时长，超过后未确认的事件可被重新领取
+	outboxClaimLease = time.Minute
+)
+
+// OutboxStats 发件箱积压指标
+type OutboxStats struct {
+	Pending              int64   `json:"pending"`                // 待投递事件数
+	OldestPendingSeconds float64 `json:"oldest_pending_seconds"` // 最早待投递事件的等待时长
+	Published            uint64  `json:"published"`              // 本进程累计投递成功数
+	Failed               uint64  `json:"failed"`                 // 本进程累计投递失败次数
+}
+
+// outboxPublisher 事件的投递目标，由kafka.Producer实现
+type outboxPublisher interface {
+	SendBatch(ctx context.Context, messages []kafka.Message) []error
+}
+
+// OutboxRelay 发件箱中继
+// 轮询outbox表中到期的pending事件，投递到Kafka后标记为sent
+// 投递成功但标记失败时事件会被再次投递（至少一次语义），消费端按消息ID幂等处理
+// 同Key（同一会话）的事件按ID顺序投递：前面的事件未投递成功时，后面的事件不会先投递
+type OutboxRelay struct {
+	outboxDAO *dao.OutboxDAO
+	producers map[string]outboxPublisher // topic -> producer
+
+	pollInterval time.Duration
+	batchSize    int
+	retention    time.Duration
+
+	published uint64
+	failed    uint64
+}
+
+// NewOutboxRelay 创建发件箱中继
+func NewOutboxRelay(cfg config.OutboxConfig, producers map[string]*kafka.Producer) *OutboxRelay {
+	r := &OutboxRelay{
+		outboxDAO:    dao.NewOutboxDAO(),
+		producers:    make(map[string]outboxPublisher, len(producers)),
+		pollInterval: time.Duration(cfg.PollIntervalMs) * time.Millisecond,
+		batchSize:    cfg.BatchSize,
+		retention:    time.Duration(cfg.RetentionHours) * time.Hour,
+	}
+
+	for topic, producer := range producers {
+		r.producers[topic] = producer
+	}
+
+	if r.pollInterval <= 0 {
+		r.pollInterval = 500 * time.Millisecond
+	}
+	if r.batchSize <= 0 {
+		r.batchSize = 100
+	}
+	if r.retention <= 0 {
+		r.retention = 72 * time.Hour
+	}
+
+	return r
+}
+
+// Run 启动中继循环，直到ctx取消
+func (r *OutboxRelay) Run(ctx context.Context) {
+	log.Printf("Outbox relay started: poll=%s, batch=%d", r.pollInterval, r.batchSize)
+
+	ticker := time.NewTicker(r.pollInterval)
+	defer ticker.Stop()
+
+	cleanup := time.NewTicker(outboxCleanupInterval)
+	defer cleanup.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("Outbox relay stopped")
+			return
+
+		case <-ticker.C:
+			// 一批满载说明还有积压，立即继续处理
+			for {
+				n, err := r.relayBatch(ctx)
+				if err != nil {
+					log.Printf("Outbox relay batch failed: %v", err)
+					break
+				}
+				if n < r.batchSize || ctx.Err() != nil {
+					break
+				}
+			}
+
+		case <-cleanup.C:
+			deleted, err := r.outboxDAO.DeleteSentBefore(time.Now().Add(-r.retention))
+			if err != nil {
+				log.Printf("Outbox cleanup failed: %v", err)
+			} else if deleted > 0 {
+				log.Printf("Outbox cleanup removed %d sent events", deleted)
+			}
+		}
+	}
+}
+
+// relayBatch 领取并投递一批事件，返回本次选中的事件数
+// 领取在短事务中完成（推迟next_attempt_at作为租约）并立即提交，投递在事务外进行，不长时间持有行锁
+func (r *OutboxRelay) relayBatch(ctx context.Context) (int, error) {
+	events, selected, err := r.claim()
+	if err != nil || len(events) == 0 {
+		return selected, err
+	}
+
+	results := r.publish(ctx, events)
+	if ctx.Err() != nil {
+		// 进程正在退出，投递结果不可信，放回待投递
+		return selected, r.outboxDAO.Release(eventIDs(events))
+	}
+
+	sent, failed, deferred := settleOutbox(events, results)
+
+	if err := r.outboxDAO.MarkSent(sent); err != nil {
+		return selected, err
+	}
+	atomic.AddUint64(&r.published, uint64(len(sent)))
+
+	for _, i := range failed {
+		event := &events[i]
+		atomic.AddUint64(&r.failed, 1)
+		log.Printf("Outbox event %d publish failed (attempt %d): %v", event.ID, event.Attempts+1, results[i])
+		next := time.Now().Add(outboxBackoff(event.Attempts + 1))
+		if err := r.outboxDAO.MarkFailed(event.ID, results[i].Error(), next); err != nil {
+			return selected, err
+		}
+	}
+
+	return selected, r.outboxDAO.Release(deferred)
+}
+
+// claim 在事务中锁定一批到期事件，过滤掉不能保证顺序的事件后领取
+// 返回领取的事件和锁定的事件数
+func (r *OutboxRelay) claim() ([]model.OutboxEvent, int, error) {
+	var claimed []model.OutboxEvent
+	selected := 0
+
+	err := mysql.GetDB().Transaction(func(tx *gorm.DB) error {
+		outboxDAO := r.outboxDAO.WithTx(tx)
+
+		events, err := outboxDAO.LockPending(r.batchSize)
+		if err != nil {
+			return err
+		}
+		selected = len(events)
+		if len(events) == 0 {
+			return nil
+		}
+
+		var keys []string
+		seen := make(map[string]bool)
+		for _, event := range events {
+			if event.MessageKey != "" && !seen[event.MessageKey] {
+				seen[event.MessageKey] = true
+				keys = append(keys, event.MessageKey)
+			}
+		}
+		pending, err := outboxDAO.GetPendingKeyOrder(keys, events[len(events)-1].ID)
+		if err != nil {
+			return err
+		}
+
+		claimed = inKeyOrder(events, pending)
+		return outboxDAO.Claim(eventIDs(claimed), time.Now().Add(outboxClaimLease))
+	})
+	if err != nil {
+		return nil, selected, err
+	}
+	return claimed, selected, nil
+}
+
+// publish 按Topic分组批量投递，返回与events一一对应的错误
+func (r *OutboxRelay) publish(ctx context.Context, events []model.OutboxEvent) []error {
+	errs := make([]error, len(events))
+
+	byTopic := make(map[string][]int)
+	for i := range events {
+		byTopic[events[i].Topic] = append(byTopic[events[i].Topic], i)
+	}
+
+	for topic, indexes := range byTopic {
+		producer, ok := r.producers[topic]
+		if !ok {
+			for _, i := range indexes {
+				errs[i] = errors.New("no producer for topic " + topic)
+			}
+			continue
+		}
+
+		messages := make([]kafka.Message, len(indexes))
+		for j, i := range indexes {
+			messages[j] = kafka.Message{
+				Key:   []byte(events[i].MessageKey),
+				Value: []byte(events[i].Payload),
+			}
+		}
+		for j, err := range producer.SendBatch(ctx, messages) {
+			errs[indexes[j]] = err
+		}
+	}
+	return errs
+}
+
+// inKeyOrder 过滤出可以投递的事件
+// pending是同Key下按ID升序的全部待投递事件；某个Key遇到不在本批中的事件（被其他中继领取或锁定）后，
+// 该Key后面的事件本批都不投递，保证同Key事件按ID顺序投递。没有Key的事件不保序
+func inKeyOrder(events, pending []model.OutboxEvent) []model.OutboxEvent {
+	inBatch := make(map[uint]bool, len(events))
+	for _, event := range events {
+		inBatch[event.ID] = true
+	}
+
+	// Key -> 第一个不在本批中的事件ID
+	barrier := make(map[string]uint)
+	for _, event := range pending {
+		if _, ok := barrier[event.MessageKey]; !ok && !inBatch[event.ID] {
+			barrier[event.MessageKey] = event.ID
+		}
+	}
+
+	ready := make([]model.OutboxEvent, 0, len(events))
+	for _, event := range events {
+		if first, ok := barrier[event.MessageKey]; ok && event.MessageKey != "" && event.ID > first {
+			continue
+		}
+		ready = append(ready, event)
+	}
+	return ready
+}
+
+// settleOutbox 根据投递结果把事件分为已投递、失败和推迟三类
+// 同Key中前面的事件失败后，后面的事件无论结果如何都推迟（放回待投递，不计失败次数），
+// 等前面的事件投递成功后再重新投递；因此同一事件可能重复投递，由消费端按消息ID幂等处理
+func settleOutbox(events []model.OutboxEvent, results []error) (sent []uint, failed []int, deferred []uint) {
+	failedKeys := make(map[string]bool)
+	for i, event := range events {
+		if event.MessageKey != "" && failedKeys[event.MessageKey] {
+			deferred = append(deferred, event.ID)
+			continue
+		}
+		if results[i] != nil {
+			failed = append(failed, i)
+			if event.MessageKey != "" {
+				failedKeys[event.MessageKey] = true
+			}
+			continue
+		}
+		sent = append(sent, event.ID)
+	}
+	return sent, failed, deferred
+}
+
+// eventIDs 提取事件ID
+func eventIDs(events []model.OutboxEvent) []uint {
+	ids := make([]uint, len(events))
+	for i, event := range events {
+		ids[i] = event.ID
+	}
+	return ids
+}
+
+// Stats 返回发件箱积压指标
+func (r *OutboxRelay) Stats() OutboxStats {
+	stats := OutboxStats{
+		Published: atomic.LoadUint64(&r.published),
+		Failed:    atomic.LoadUint64(&r.failed),
+	}
+
+	if pending, err := r.outboxDAO.CountPending(); err == nil {
+		stats.Pending = pending
+	}
+	if oldest, err := r.outboxDAO.GetOldestPending(); err == nil {
+		stats.OldestPendingSeconds = time.Since(oldest.CreatedAt).Seconds()
+	}
+
+	return stats
+}
+
+// outboxBackoff 按失败次数指数退避
+func outboxBackoff(attempts int) time.Duration {
+	backoff := time.Second << uint(attempts)
+	if attempts > 16 || backoff > outboxMaxBackoff {
+		return outboxMaxBackoff
+	}
+	return backoff
+}
@@ -0,0 +1,158 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lanxin/im-backend/config"
+	"github.com/lanxin/im-backend/internal/model"
+	"github.com/lanxin/im-backend/internal/testutil"
+	"github.com/lanxin/im-backend/pkg/kafka"
+)
+
+// fakePublisher 记录每次批量投递，按Key返回预设的错误
+type fakePublisher struct {
+	batches [][]kafka.Message
+	fail    map[string]error // value -> error
+}
+
+func (p *fakePublisher) SendBatch(ctx context.Context, messages []kafka.Message) []error {
+	p.batches = append(p.batches, messages)
+	errs := make([]error, len(messages))
+	for i, m := range messages {
+		errs[i] = p.fail[string(m.Value)]
+	}
+	return errs
+}
+
+func outboxEvent(id uint, key string) model.OutboxEvent {
+	return model.OutboxEvent{ID: id, Topic: "msg", MessageKey: key, Payload: "p" + string(rune('0'+id))}
+}
+
+func TestInKeyOrderStopsAtEventOutsideBatch(t *testing.T) {
+	events := []model.OutboxEvent{outboxEvent(1, "a"), outboxEvent(2, "a"), outboxEvent(3, "b"), outboxEvent(5, "b"), outboxEvent(6, "")}
+	// 4被其他中继锁定，不在本批中
+	pending := []model.OutboxEvent{outboxEvent(1, "a"), outboxEvent(2, "a"), outboxEvent(3, "b"), outboxEvent(4, "b"), outboxEvent(5, "b")}
+
+	got := eventIDs(inKeyOrder(events, pending))
+	want := []uint{1, 2, 3, 6}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("inKeyOrder = %v, want %v", got, want)
+	}
+}
+
+func TestInKeyOrderBlocksKeyWhoseHeadIsElsewhere(t *testing.T) {
+	events := []model.OutboxEvent{outboxEvent(7, "a"), outboxEvent(8, "a")}
+	pending := []model.OutboxEvent{outboxEvent(3, "a"), outboxEvent(7, "a"), outboxEvent(8, "a")}
+
+	if got := inKeyOrder(events, pending); len(got) != 0 {
+		t.Fatalf("events behind pending event 3 must wait, got %v", eventIDs(got))
+	}
+}
+
+func TestSettleOutboxDefersSameKeyAfterFailure(t *testing.T) {
+	events := []model.OutboxEvent{outboxEvent(1, "a"), outboxEvent(2, "b"), outboxEvent(3, "a"), outboxEvent(4, ""), outboxEvent(5, "a")}
+	boom := errors.New("boom")
+	// 3写入成功但1失败，3必须推迟；无Key的4失败不影响其他事件
+	results := []error{boom, nil, nil, boom, boom}
+
+	sent, failed, deferred := settleOutbox(events, results)
+	if want := []uint{2}; !reflect.DeepEqual(sent, want) {
+		t.Errorf("sent = %v, want %v", sent, want)
+	}
+	if want := []int{0, 3}; !reflect.DeepEqual(failed, want) {
+		t.Errorf("failed = %v, want %v", failed, want)
+	}
+	if want := []uint{3, 5}; !reflect.DeepEqual(deferred, want) {
+		t.Errorf("deferred = %v, want %v", deferred, want)
+	}
+}
+
+func TestOutboxPublishBatchesPerTopic(t *testing.T) {
+	pub := &fakePublisher{}
+	r := &OutboxRelay{producers: map[string]outboxPublisher{"msg": pub}}
+
+	events := []model.OutboxEvent{outboxEvent(1, "a"), outboxEvent(2, "b"), {ID: 3, Topic: "unknown"}}
+	errs := r.publish(context.Background(), events)
+
+	if len(pub.batches) != 1 || len(pub.batches[0]) != 2 {
+		t.Fatalf("want one batch with 2 messages, got %v", pub.batches)
+	}
+	if string(pub.batches[0][0].Key) != "a" || string(pub.batches[0][1].Key) != "b" {
+		t.Errorf("batch not in event order: %v", pub.batches[0])
+	}
+	if errs[0] != nil || errs[1] != nil || errs[2] == nil {
+		t.Errorf("unexpected results %v", errs)
+	}
+}
+
+func TestOutboxBackoff(t *testing.T) {
+	cases := map[int]time.Duration{1: 2 * time.Second, 3: 8 * time.Second, 9: outboxMaxBackoff, 40: outboxMaxBackoff}
+	for attempts, want := range cases {
+		if got := outboxBackoff(attempts); got != want {
+			t.Errorf("outboxBackoff(%d) = %s, want %s", attempts, got, want)
+		}
+	}
+}
+
+// TestRelayBatchClaimsThenPublishes 领取在事务中提交后才投递，结果按Key分别处理
+func TestRelayBatchClaimsThenPublishes(t *testing.T) {
+	mock := testutil.NewMockDB(t)
+	pub := &fakePublisher{fail: map[string]error{"first": errors.New("broker down")}}
+	r := NewOutboxRelay(config.OutboxConfig{BatchSize: 10}, nil)
+	r.producers["msg"] = pub
+
+	cols := []string{"id", "topic", "message_key", "payload", "status", "attempts"}
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT \\* FROM `outbox` WHERE .*NOT EXISTS.*FOR UPDATE SKIP LOCKED").
+		WillReturnRows(sqlmock.NewRows(cols).
+			AddRow(1, "msg", "a", "first", "pending", 0).
+			AddRow(2, "msg", "a", "second", "pending", 0).
+			AddRow(3, "msg", "b", "third", "pending", 0).
+			AddRow(5, "msg", "b", "fifth", "pending", 0))
+	mock.ExpectQuery("SELECT `id`,`message_key` FROM `outbox` WHERE message_key IN").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "message_key"}).
+			AddRow(1, "a").AddRow(2, "a").AddRow(3, "b").AddRow(4, "b").AddRow(5, "b"))
+	mock.ExpectExec("UPDATE `outbox` SET `next_attempt_at`=\\? WHERE id IN \\(\\?,\\?,\\?\\)").
+		WithArgs(sqlmock.AnyArg(), 1, 2, 3).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectCommit()
+
+	// 投递在事务提交之后
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `outbox` SET `sent_at`=\\?,`status`=\\? WHERE id IN \\(\\?\\)").
+		WithArgs(sqlmock.AnyArg(), model.OutboxStatusSent, 3).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `outbox` SET `attempts`=attempts \\+ 1,`last_error`=\\?,`next_attempt_at`=\\? WHERE id = \\?").
+		WithArgs("broker down", sqlmock.AnyArg(), 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `outbox` SET `next_attempt_at`=\\? WHERE id IN \\(\\?\\) AND status = \\?").
+		WithArgs(sqlmock.AnyArg(), 2, model.OutboxStatusPending).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	n, err := r.relayBatch(context.Background())
+	if err != nil {
+		t.Fatalf("relayBatch: %v", err)
+	}
+	if n != 4 {
+		t.Errorf("selected = %d, want 4", n)
+	}
+	if len(pub.batches) != 1 || len(pub.batches[0]) != 3 {
+		t.Fatalf("want a single batch of 3 messages (5 waits for 4), got %v", pub.batches)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+	if r.published != 1 || r.failed != 1 {
+		t.Errorf("published = %d, failed = %d, want 1 and 1", r.published, r.failed)
+	}
+}
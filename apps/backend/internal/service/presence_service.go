@@ -0,0 +1,365 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	goredis "github.com/go-redis/redis/v8"
+	"github.com/lanxin/im-backend/config"
+	"github.com/lanxin/im-backend/internal/dao"
+	"github.com/lanxin/im-backend/internal/model"
+	"github.com/lanxin/im-backend/internal/pkg/redis"
+	"github.com/lanxin/im-backend/internal/websocket"
+	"gorm.io/gorm"
+)
+
+const (
+	presenceStatePrefix = "presence:state:"          // STRING: 最近一次推送给联系人的状态
+	lastSeenKey         = "presence:last_seen"       // HASH: uid -> 最后在线时间（unix秒）
+	lastSeenDirtyKey    = "presence:last_seen:dirty" // SET: 待写回数据库的用户
+
+	lastSeenFlushBatch    = 500
+	maxPresenceQuery      = 100                 // 单次最多查询的用户数
+	maxCustomStatusLength = 100                 // 自定义状态最大长度（字符）
+	maxCustomStatusTTL    = 30 * 24 * time.Hour // 自定义状态最长有效期
+)
+
+// CustomStatus 用户设置的自定义状态
+type CustomStatus struct {
+	Text      string     `json:"text"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"` // 为空表示不过期
+}
+
+// UserPresenceInfo 用户的在线状态
+type UserPresenceInfo struct {
+	UserID       uint          `json:"user_id"`
+	State        string        `json:"state"`                    // online / away / offline
+	LastSeenAt   *time.Time    `json:"last_seen_at,omitempty"`   // 离线或离开时返回，对方隐藏时为空
+	CustomStatus *CustomStatus `json:"custom_status,omitempty"`  // 未设置或已过期时为空
+	HideLastSeen *bool         `json:"hide_last_seen,omitempty"` // 只在查询自己时返回
+}
+
+// PresenceService 在线状态服务
+//
+// 功能说明:
+//   - 连接状态由Hub维护，用户状态变化时推送presence给联系人（把该用户加为联系人的用户）
+//   - 最后在线时间写入Redis，定期批量写回数据库
+//   - 自定义状态和隐藏最后在线时间的隐私设置保存在数据库
+type PresenceService struct {
+	presenceDAO *dao.UserPresenceDAO
+	contactDAO  *dao.ContactDAO
+	hub         *websocket.Hub
+	redisClient *goredis.Client
+
+	changes       chan uint
+	flushInterval time.Duration
+}
+
+// NewPresenceService 创建在线状态服务
+func NewPresenceService(cfg *config.Config, hub *websocket.Hub) *PresenceService {
+	s := &PresenceService{
+		presenceDAO:   dao.NewUserPresenceDAO(),
+		contactDAO:    dao.NewContactDAO(),
+		hub:           hub,
+		redisClient:   redis.GetClient(),
+		changes:       make(chan uint, 4096),
+		flushInterval: time.Duration(cfg.WebSocket.PresenceFlushIntervalMs) * time.Millisecond,
+	}
+
+	if s.flushInterval <= 0 {
+		s.flushInterval = 30 * time.Second
+	}
+
+	return s
+}
+
+// Notify 实现websocket.PresenceHandler，把状态变化交给Run处理
+// 在Hub的通知协程中调用，队列已满时丢弃
+func (s *PresenceService) Notify(userID uint) {
+	select {
+	case s.changes <- userID:
+	default:
+		log.Printf("Presence change queue full, dropping update of user %d", userID)
+	}
+}
+
+// Run 依次处理状态变化并定期写回最后在线时间，直到ctx取消
+func (s *PresenceService) Run(ctx context.Context) {
+	log.Printf("Presence service started: flush_interval=%s", s.flushInterval)
+
+	ticker := time.NewTicker(s.flushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			s.flushLastSeen()
+			log.Println("Presence service stopped")
+			return
+
+		case userID := <-s.changes:
+			s.publish(userID)
+
+		case <-ticker.C:
+			s.flushLastSeen()
+		}
+	}
+}
+
+// GetPresence 批量查询用户的在线状态
+// 只能查看自己和联系人（与推送范围相同），其他用户以及把viewer拉黑的联系人总是显示为离线；
+// 隐藏了最后在线时间的用户不返回last_seen_at
+func (s *PresenceService) GetPresence(viewerID uint, userIDs []uint) ([]UserPresenceInfo, error) {
+	userIDs = dedupeIDs(userIDs)
+	if len(userIDs) == 0 {
+		return []UserPresenceInfo{}, nil
+	}
+	if len(userIDs) > maxPresenceQuery {
+		return nil, fmt.Errorf("at most %d users per query", maxPresenceQuery)
+	}
+
+	watchedIDs, err := s.contactDAO.GetWatchedIDs(viewerID, userIDs)
+	if err != nil {
+		return nil, err
+	}
+	visibleIDs := watchedIDs
+	if containsUser(userIDs, viewerID) {
+		visibleIDs = append(visibleIDs, viewerID)
+	}
+	visible := make(map[uint]bool, len(visibleIDs))
+	for _, id := range visibleIDs {
+		visible[id] = true
+	}
+
+	rows, err := s.presenceDAO.GetByUserIDs(visibleIDs)
+	if err != nil {
+		return nil, err
+	}
+	byUser := make(map[uint]*model.UserPresence, len(rows))
+	for i := range rows {
+		byUser[rows[i].UserID] = &rows[i]
+	}
+
+	lastSeen := s.cachedLastSeen(visibleIDs)
+	now := time.Now()
+
+	list := make([]UserPresenceInfo, 0, len(userIDs))
+	for _, userID := range userIDs {
+		if !visible[userID] {
+			list = append(list, UserPresenceInfo{UserID: userID, State: websocket.PresenceOffline})
+			continue
+		}
+		list = append(list, s.buildPresence(userID, viewerID, byUser[userID], lastSeen[userID], now))
+	}
+	return list, nil
+}
+
+// SetCustomStatus 设置自定义状态，text为空表示清除；expiresIn为0表示不过期
+func (s *PresenceService) SetCustomStatus(userID uint, text string, expiresIn int) (*UserPresenceInfo, error) {
+	text = strings.TrimSpace(text)
+	if utf8.RuneCountInString(text) > maxCustomStatusLength {
+		return nil, fmt.Errorf("custom status must be at most %d characters", maxCustomStatusLength)
+	}
+	if expiresIn < 0 || time.Duration(expiresIn)*time.Second > maxCustomStatusTTL {
+		return nil, fmt.Errorf("expires_in must be between 0 and %d seconds", int(maxCustomStatusTTL/time.Second))
+	}
+
+	var expiresAt *time.Time
+	if text != "" && expiresIn > 0 {
+		at := time.Now().Add(time.Duration(expiresIn) * time.Second)
+		expiresAt = &at
+	}
+	if err := s.presenceDAO.UpdateCustomStatus(userID, text, expiresAt); err != nil {
+		return nil, err
+	}
+
+	go s.broadcast(userID)
+	return s.self(userID)
+}
+
+// SetHideLastSeen 修改是否对其他用户隐藏最后在线时间
+func (s *PresenceService) SetHideLastSeen(userID uint, hide bool) (*UserPresenceInfo, error) {
+	if err := s.presenceDAO.UpdateHideLastSeen(userID, hide); err != nil {
+		return nil, err
+	}
+
+	go s.broadcast(userID)
+	return s.self(userID)
+}
+
+// self 返回用户自己视角的在线状态
+func (s *PresenceService) self(userID uint) (*UserPresenceInfo, error) {
+	list, err := s.GetPresence(userID, []uint{userID})
+	if err != nil {
+		return nil, err
+	}
+	return &list[0], nil
+}
+
+// publish 处理一次状态变化：记录最后在线时间，状态与上次推送不同时通知联系人
+func (s *PresenceService) publish(userID uint) {
+	ctx := context.Background()
+	state := s.hub.UserPresence(userID)
+	s.recordLastSeen(userID, time.Now())
+
+	key := presenceStatePrefix + strconv.FormatUint(uint64(userID), 10)
+	prev, err := s.redisClient.GetSet(ctx, key, state).Result()
+	if err != nil && err != goredis.Nil {
+		log.Printf("Failed to update presence state of user %d: %v", userID, err)
+		return
+	}
+	if prev == "" {
+		prev = websocket.PresenceOffline
+	}
+	if prev == state {
+		return
+	}
+
+	s.broadcast(userID)
+}
+
+// broadcast 把用户当前的在线状态推送给联系人
+func (s *PresenceService) broadcast(userID uint) {
+	watcherIDs, err := s.contactDAO.GetWatcherIDs(userID)
+	if err != nil {
+		log.Printf("Failed to load contacts watching user %d: %v", userID, err)
+		return
+	}
+	if len(watcherIDs) == 0 {
+		return
+	}
+
+	var row *model.UserPresence
+	if presence, err := s.presenceDAO.GetByUserID(userID); err == nil {
+		row = presence
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		log.Printf("Failed to load presence of user %d: %v", userID, err)
+		return
+	}
+
+	// 推送对象都不是本人，按其他用户的视角生成
+	info := s.buildPresence(userID, 0, row, s.cachedLastSeen([]uint{userID})[userID], time.Now())
+	for _, watcherID := range watcherIDs {
+		s.hub.SendToUser(watcherID, websocket.WebSocketMessage{
+			Type: "presence",
+			Data: info,
+		})
+	}
+}
+
+// buildPresence 组装viewer视角的在线状态，row为空表示用户没有持久化的在线信息
+func (s *PresenceService) buildPresence(userID, viewerID uint, row *model.UserPresence, lastSeen *time.Time, now time.Time) UserPresenceInfo {
+	info := UserPresenceInfo{
+		UserID: userID,
+		State:  s.hub.UserPresence(userID),
+	}
+
+	hideLastSeen := false
+	if row != nil {
+		hideLastSeen = row.HideLastSeen
+		if lastSeen == nil {
+			lastSeen = row.LastSeenAt
+		}
+		if text := row.ActiveCustomStatus(now); text != "" {
+			info.CustomStatus = &CustomStatus{Text: text, ExpiresAt: row.CustomStatusExpiresAt}
+		}
+	}
+
+	if viewerID == userID {
+		info.HideLastSeen = &hideLastSeen
+	}
+	if info.State != websocket.PresenceOnline && (!hideLastSeen || viewerID == userID) {
+		info.LastSeenAt = lastSeen
+	}
+	return info
+}
+
+// recordLastSeen 把最后在线时间写入Redis，并标记为待写回数据库
+func (s *PresenceService) recordLastSeen(userID uint, at time.Time) {
+	ctx := context.Background()
+	uid := strconv.FormatUint(uint64(userID), 10)
+
+	pipe := s.redisClient.TxPipeline()
+	pipe.HSet(ctx, lastSeenKey, uid, at.Unix())
+	pipe.SAdd(ctx, lastSeenDirtyKey, uid)
+	if _, err := pipe.Exec(ctx); err != nil {
+		log.Printf("Failed to record last seen of user %d: %v", userID, err)
+	}
+}
+
+// cachedLastSeen 从Redis批量读取最后在线时间，没有记录的用户不在结果中
+func (s *PresenceService) cachedLastSeen(userIDs []uint) map[uint]*time.Time {
+	result := make(map[uint]*time.Time, len(userIDs))
+	if len(userIDs) == 0 {
+		return result
+	}
+
+	fields := make([]string, len(userIDs))
+	for i, id := range userIDs {
+		fields[i] = strconv.FormatUint(uint64(id), 10)
+	}
+	values, err := s.redisClient.HMGet(context.Background(), lastSeenKey, fields...).Result()
+	if err != nil {
+		log.Printf("Failed to read last seen from Redis: %v", err)
+		return result
+	}
+
+	for i, value := range values {
+		str, ok := value.(string)
+		if !ok {
+			continue
+		}
+		unix, err := strconv.ParseInt(str, 10, 64)
+		if err != nil {
+			continue
+		}
+		at := time.Unix(unix, 0)
+		result[userIDs[i]] = &at
+	}
+	return result
+}
+
+// flushLastSeen 把待写回的最后在线时间批量写入数据库
+// 待写回集合用SPOP领取，多节点同时运行时每个用户只由一个节点写回；写入失败时放回集合
+func (s *PresenceService) flushLastSeen() {
+	ctx := context.Background()
+
+	for {
+		uids, err := s.redisClient.SPopN(ctx, lastSeenDirtyKey, lastSeenFlushBatch).Result()
+		if err != nil || len(uids) == 0 {
+			return
+		}
+
+		userIDs := make([]uint, 0, len(uids))
+		for _, uid := range uids {
+			if id, err := strconv.ParseUint(uid, 10, 64); err == nil {
+				userIDs = append(userIDs, uint(id))
+			}
+		}
+
+		lastSeen := make(map[uint]time.Time, len(userIDs))
+		for userID, at := range s.cachedLastSeen(userIDs) {
+			lastSeen[userID] = *at
+		}
+
+		if err := s.presenceDAO.SaveLastSeen(lastSeen); err != nil {
+			log.Printf("Failed to flush last seen of %d users: %v", len(lastSeen), err)
+			members := make([]interface{}, len(uids))
+			for i, uid := range uids {
+				members[i] = uid
+			}
+			s.redisClient.SAdd(ctx, lastSeenDirtyKey, members...)
+			return
+		}
+
+		if len(uids) < lastSeenFlushBatch {
+			return
+		}
+	}
+}
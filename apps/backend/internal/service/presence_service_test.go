@@ -0,0 +1,58 @@
+package service
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lanxin/im-backend/internal/dao"
+	"github.com/lanxin/im-backend/internal/pkg/redis"
+	"github.com/lanxin/im-backend/internal/testutil"
+	"github.com/lanxin/im-backend/internal/websocket"
+)
+
+func TestGetPresenceOnlyShowsContacts(t *testing.T) {
+	mock := testutil.NewMockDB(t)
+	mr := testutil.NewRedis(t)
+	s := &PresenceService{
+		presenceDAO: dao.NewUserPresenceDAO(),
+		contactDAO:  dao.NewContactDAO(),
+		hub:         websocket.NewHub(),
+		redisClient: redis.GetClient(),
+	}
+
+	lastSeen := strconv.FormatInt(time.Now().Add(-time.Hour).Unix(), 10)
+	for _, uid := range []string{"1", "2", "3"} {
+		mr.HSet(lastSeenKey, uid, lastSeen)
+	}
+
+	// 用户2是联系人；用户3不是联系人（或已把查看者拉黑），只显示为离线
+	mock.ExpectQuery("SELECT DISTINCT `contact_id` FROM `contacts` WHERE \\(user_id = \\? AND contact_id IN \\(\\?,\\?,\\?\\) AND status = \\?\\) AND contact_id NOT IN \\(SELECT `user_id` FROM `contacts` WHERE user_id IN \\(\\?,\\?,\\?\\) AND contact_id = \\? AND status = \\?").
+		WithArgs(1, 1, 2, 3, "normal", 1, 2, 3, 1, "blocked").
+		WillReturnRows(sqlmock.NewRows([]string{"contact_id"}).AddRow(2))
+	mock.ExpectQuery("SELECT \\* FROM `user_presence` WHERE user_id IN \\(\\?,\\?\\)").
+		WithArgs(2, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "custom_status", "hide_last_seen"}).
+			AddRow(2, "开会中", false))
+
+	list, err := s.GetPresence(1, []uint{1, 2, 3})
+	if err != nil {
+		t.Fatalf("GetPresence() error = %v", err)
+	}
+	if len(list) != 3 {
+		t.Fatalf("GetPresence() = %+v, want 3 entries", list)
+	}
+	if self := list[0]; self.HideLastSeen == nil || self.LastSeenAt == nil {
+		t.Errorf("self = %+v, want own privacy setting and last seen", self)
+	}
+	if contact := list[1]; contact.LastSeenAt == nil || contact.CustomStatus == nil {
+		t.Errorf("contact = %+v, want last seen and custom status", contact)
+	}
+	if stranger := list[2]; stranger.State != websocket.PresenceOffline || stranger.LastSeenAt != nil || stranger.CustomStatus != nil {
+		t.Errorf("stranger = %+v, want bare offline", stranger)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
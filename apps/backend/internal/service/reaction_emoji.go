@@ -0,0 +1,92 @@
+package service
+
+import (
+	"unicode/utf8"
+)
+
+// 单个表情回应的最大长度，足够容纳肤色、性别组合和家庭等ZWJ序列
+const (
+	maxEmojiBytes = 32
+	maxEmojiRunes = 12
+)
+
+// isReactionEmoji 判断字符串是否为单个emoji（含肤色修饰、ZWJ组合、旗帜和键帽序列）
+// 只接受emoji码位和组合用的控制字符，普通文字、空白和标点都不是合法的回应
+func isReactionEmoji(emoji string) bool {
+	if emoji == "" || len(emoji) > maxEmojiBytes || !utf8.ValidString(emoji) || utf8.RuneCountInString(emoji) > maxEmojiRunes {
+		return false
+	}
+
+	// pictographs统计独立的emoji个数：ZWJ连接的和成对的区域指示符（旗帜）算作一个
+	var pictographs, keycapBases int
+	var prev rune
+	keycap, pairedFlag := false, false
+	for _, r := range emoji {
+		switch {
+		case isEmojiPictograph(r):
+			switch {
+			case prev == 0x200D:
+			case isRegionalIndicator(r) && isRegionalIndicator(prev) && !pairedFlag:
+				pairedFlag = true
+			default:
+				pictographs++
+				pairedFlag = false
+			}
+		case r == 0x20E3:
+			keycap = true
+		case isKeycapBase(r):
+			keycapBases++
+		case isEmojiComponent(r):
+		default:
+			return false
+		}
+		prev = r
+	}
+
+	// 键帽序列（如 1️⃣）：数字、#或*开头，后跟U+20E3
+	if keycap || keycapBases > 0 {
+		first, _ := utf8.DecodeRuneInString(emoji)
+		return keycap && keycapBases == 1 && pictographs == 0 && isKeycapBase(first)
+	}
+	return pictographs == 1
+}
+
+// isEmojiPictograph emoji本体所在的码位区间
+func isEmojiPictograph(r rune) bool {
+	switch {
+	case r >= 0x1F000 && r <= 0x1FAFF: // 表情符号、交通、旗帜用区域指示符、补充符号等
+		return !isSkinTone(r)
+	case r >= 0x2600 && r <= 0x27BF: // 杂项符号、装饰符号
+		return true
+	case r >= 0x2300 && r <= 0x23FF: // ⌚ ⏰ 等
+		return true
+	case r >= 0x2B00 && r <= 0x2BFF: // ⭐ ⬆ 等
+		return true
+	case r >= 0x2190 && r <= 0x21FF: // 箭头
+		return true
+	case r >= 0x25A0 && r <= 0x25FF: // ▶ ◀ 等几何图形
+		return true
+	}
+	switch r {
+	case 0x00A9, 0x00AE, 0x203C, 0x2049, 0x2122, 0x2139, 0x2934, 0x2935, 0x3030, 0x303D, 0x3297, 0x3299:
+		return true
+	}
+	return false
+}
+
+// isEmojiComponent 组合emoji用的零宽连接符、变体选择符、肤色修饰和标签字符
+func isEmojiComponent(r rune) bool {
+	return r == 0x200D || r == 0xFE0F || r == 0xFE0E || isSkinTone(r) || r >= 0xE0020 && r <= 0xE007F
+}
+
+func isRegionalIndicator(r rune) bool {
+	return r >= 0x1F1E6 && r <= 0x1F1FF
+}
+
+func isSkinTone(r rune) bool {
+	return r >= 0x1F3FB && r <= 0x1F3FF
+}
+
+func isKeycapBase(r rune) bool {
+	return r == '#' || r == '*' || r >= '0' && r <= '9'
+}
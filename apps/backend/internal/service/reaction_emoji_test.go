@@ -0,0 +1,51 @@
+package service
+
+import "testing"
+
+func TestIsReactionEmoji(t *testing.T) {
+	valid := []string{
+		"👍",
+		"😂",
+		"❤️",
+		"👍🏽",      // 肤色
+		"👩‍💻",     // ZWJ组合
+		"👨‍👩‍👧‍👦", // 家庭
+		"🏳️‍🌈",    // 带变体选择符的ZWJ组合
+		"🇨🇳",      // 区域指示符旗帜
+		"1️⃣",     // 键帽
+		"#⃣",
+		"⭐",
+		"✅",
+		"🏴\U000E0067\U000E0062\U000E0065\U000E006E\U000E0067\U000E007F", // 标签序列旗帜
+	}
+	for _, emoji := range valid {
+		if !isReactionEmoji(emoji) {
+			t.Errorf("isReactionEmoji(%q) = false, want true", emoji)
+		}
+	}
+
+	invalid := []string{
+		"",
+		"a",
+		"ok",
+		"好",
+		"👍 ",
+		" 👍",
+		"👍a",
+		"1",
+		"👍1",
+		"1👍",
+		"11️⃣",
+		"‍",
+		"🏽",
+		"<script>",
+		"👍👍",
+		"🇨🇳🇺🇸",
+		"👍👍👍👍👍👍👍👍👍",
+	}
+	for _, emoji := range invalid {
+		if isReactionEmoji(emoji) {
+			t.Errorf("isReactionEmoji(%q) = true, want false", emoji)
+		}
+	}
+}
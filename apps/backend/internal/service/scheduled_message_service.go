@@ -0,0 +1,406 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/lanxin/im-backend/config"
+	"github.com/lanxin/im-backend/internal/dao"
+	"github.com/lanxin/im-backend/internal/model"
+	"github.com/lanxin/im-backend/internal/pkg/mysql"
+	"github.com/lanxin/im-backend/internal/websocket"
+	"gorm.io/gorm"
+)
+
+const (
+	maxScheduleAhead       = 30 * 24 * time.Hour // 最远可预约的时间
+	minScheduleAhead       = 10 * time.Second    // 计划时间至少晚于当前时间
+	maxPendingScheduled    = 100                 // 每个用户同时待发送的定时消息上限
+	scheduledDispatchBatch = 50
+	scheduledClaimLease    = 5 * time.Minute // 领取后超过该时间仍未完成（如节点在发送中退出）则重新领取
+)
+
+// ScheduledMessageInput 创建或修改定时消息的内容
+// 修改时忽略receiver_id/group_id，发送目标创建后不可更改
+type ScheduledMessageInput struct {
+	ReceiverID     *uint
+	GroupID        *uint
+	Content        string
+	Type           string
+	FileURL        string
+	FileSize       int64
+	Duration       int
+	Payload        json.RawMessage
+	MentionUserIDs []uint
+	MentionAll     bool
+	ScheduledAt    time.Time
+}
+
+// ScheduledMessageService 定时消息的创建、修改、取消和查询
+// 到期发送由ScheduledMessageDispatcher完成
+type ScheduledMessageService struct {
+	scheduledDAO   *dao.ScheduledMessageDAO
+	userDAO        *dao.UserDAO
+	groupDAO       *dao.GroupDAO
+	groupMemberDAO *dao.GroupMemberDAO
+	logDAO         *dao.OperationLogDAO
+	payloads       *messagePayloadValidator
+}
+
+func NewScheduledMessageService() *ScheduledMessageService {
+	return &ScheduledMessageService{
+		scheduledDAO:   dao.NewScheduledMessageDAO(),
+		userDAO:        dao.NewUserDAO(),
+		groupDAO:       dao.NewGroupDAO(),
+		groupMemberDAO: dao.NewGroupMemberDAO(),
+		logDAO:         dao.NewOperationLogDAO(),
+		payloads:       newMessagePayloadValidator(),
+	}
+}
+
+// Create 创建定时消息
+// 创建时校验发送目标和消息内容，@成员和群权限在发送时按当时的状态校验
+func (s *ScheduledMessageService) Create(senderID uint, input ScheduledMessageInput, ip, userAgent string) (*model.ScheduledMessage, error) {
+	if (input.ReceiverID == nil) == (input.GroupID == nil) {
+		return nil, errors.New("exactly one of receiver_id and group_id is required")
+	}
+	if input.ReceiverID != nil {
+		if *input.ReceiverID == senderID {
+			return nil, errors.New("cannot schedule a message to yourself")
+		}
+		if _, err := s.userDAO.GetByID(*input.ReceiverID); err != nil {
+			return nil, errors.New("receiver not found")
+		}
+		if input.MentionAll || len(input.MentionUserIDs) > 0 {
+			return nil, errors.New("mentions are only supported in group messages")
+		}
+	} else {
+		if _, err := s.groupDAO.GetByID(*input.GroupID); err != nil {
+			return nil, errors.New("group not found")
+		}
+		if !s.groupMemberDAO.IsMember(*input.GroupID, senderID) {
+			return nil, errors.New("not a group member")
+		}
+	}
+
+	count, err := s.scheduledDAO.CountPending(senderID)
+	if err != nil {
+		return nil, err
+	}
+	if count >= maxPendingScheduled {
+		return nil, fmt.Errorf("at most %d pending scheduled messages are allowed", maxPendingScheduled)
+	}
+
+	scheduled := &model.ScheduledMessage{
+		SenderID:   senderID,
+		ReceiverID: input.ReceiverID,
+		GroupID:    input.GroupID,
+		Status:     model.ScheduledStatusPending,
+	}
+	if err := s.fill(scheduled, input); err != nil {
+		return nil, err
+	}
+	if err := s.scheduledDAO.Create(scheduled); err != nil {
+		return nil, err
+	}
+
+	s.log(senderID, scheduled, "create", ip, userAgent)
+	return scheduled, nil
+}
+
+// List 获取用户的定时消息，status为空时返回全部
+func (s *ScheduledMessageService) List(senderID uint, status string) ([]model.ScheduledMessage, error) {
+	return s.scheduledDAO.ListBySender(senderID, status)
+}
+
+// Update 修改待发送的定时消息的内容和计划时间
+func (s *ScheduledMessageService) Update(id, senderID uint, input ScheduledMessageInput, ip, userAgent string) (*model.ScheduledMessage, error) {
+	scheduled, err := s.scheduledDAO.GetByID(id, senderID)
+	if err != nil {
+		return nil, errors.New("scheduled message not found")
+	}
+	if scheduled.Status != model.ScheduledStatusPending {
+		return nil, errors.New("only pending scheduled messages can be edited")
+	}
+	if scheduled.ReceiverID != nil && (input.MentionAll || len(input.MentionUserIDs) > 0) {
+		return nil, errors.New("mentions are only supported in group messages")
+	}
+	if err := s.fill(scheduled, input); err != nil {
+		return nil, err
+	}
+
+	updated, err := s.scheduledDAO.UpdatePending(id, senderID, map[string]interface{}{
+		"content":          scheduled.Content,
+		"type":             scheduled.Type,
+		"file_url":         scheduled.FileURL,
+		"file_size":        scheduled.FileSize,
+		"duration":         scheduled.Duration,
+		"payload":          jsonColumn(scheduled.Payload),
+		"mention_user_ids": mentionJSON(scheduled.MentionUserIDs),
+		"mention_all":      scheduled.MentionAll,
+		"scheduled_at":     scheduled.ScheduledAt,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !updated {
+		// 读取之后被调度器发送或被其他设备取消
+		return nil, errors.New("only pending scheduled messages can be edited")
+	}
+
+	s.log(senderID, scheduled, "update", ip, userAgent)
+	return s.scheduledDAO.GetByID(id, senderID)
+}
+
+// Cancel 取消待发送的定时消息
+func (s *ScheduledMessageService) Cancel(id, senderID uint, ip, userAgent string) error {
+	scheduled, err := s.scheduledDAO.GetByID(id, senderID)
+	if err != nil {
+		return errors.New("scheduled message not found")
+	}
+
+	canceled, err := s.scheduledDAO.UpdatePending(id, senderID, map[string]interface{}{
+		"status": model.ScheduledStatusCanceled,
+	})
+	if err != nil {
+		return err
+	}
+	if !canceled {
+		return errors.New("only pending scheduled messages can be canceled")
+	}
+
+	s.log(senderID, scheduled, "cancel", ip, userAgent)
+	return nil
+}
+
+// fill 校验计划时间和消息内容并写入scheduled
+// 内容校验与立即发送时的规则一致，content摘要和规范化后的payload一并保存
+func (s *ScheduledMessageService) fill(scheduled *model.ScheduledMessage, input ScheduledMessageInput) error {
+	now := time.Now()
+	if input.ScheduledAt.Before(now.Add(minScheduleAhead)) {
+		return errors.New("scheduled_at must be in the future")
+	}
+	if input.ScheduledAt.After(now.Add(maxScheduleAhead)) {
+		return errors.New("scheduled_at cannot be more than 30 days ahead")
+	}
+
+	if input.Type == "" {
+		input.Type = model.MessageTypeText
+	}
+	message := &model.Message{
+		Content:  input.Content,
+		Type:     input.Type,
+		FileURL:  input.FileURL,
+		FileSize: input.FileSize,
+		Duration: input.Duration,
+	}
+	if err := s.payloads.apply(message, SendOptions{Payload: input.Payload}); err != nil {
+		return err
+	}
+
+	scheduled.Content = message.Content
+	scheduled.Type = message.Type
+	scheduled.FileURL = message.FileURL
+	scheduled.FileSize = message.FileSize
+	scheduled.Duration = message.Duration
+	scheduled.Payload = message.Payload
+	scheduled.MentionUserIDs = dedupeIDs(input.MentionUserIDs)
+	scheduled.MentionAll = input.MentionAll
+	scheduled.ScheduledAt = input.ScheduledAt
+	return nil
+}
+
+func (s *ScheduledMessageService) log(senderID uint, scheduled *model.ScheduledMessage, operation, ip, userAgent string) {
+	s.logDAO.CreateLog(dao.LogRequest{
+		Action:    model.ActionMessageSchedule,
+		UserID:    &senderID,
+		IP:        ip,
+		UserAgent: userAgent,
+		Details: map[string]interface{}{
+			"scheduled_id": scheduled.ID,
+			"operation":    operation,
+			"receiver_id":  scheduled.ReceiverID,
+			"group_id":     scheduled.GroupID,
+			"scheduled_at": scheduled.ScheduledAt,
+		},
+		Result: model.ResultSuccess,
+	})
+}
+
+// jsonColumn 把JSON写成map更新的列值，空值写NULL
+func jsonColumn(data json.RawMessage) interface{} {
+	if len(data) == 0 {
+		return nil
+	}
+	return string(data)
+}
+
+// mentionJSON 把@成员列表序列化为JSON列值（map更新不经过serializer）
+func mentionJSON(ids []uint) interface{} {
+	if len(ids) == 0 {
+		return nil
+	}
+	data, _ := json.Marshal(ids)
+	return string(data)
+}
+
+// ScheduledMessageDispatcher 定时消息调度器
+// 轮询到期的pending记录，领取（标记为dispatching）后按普通消息发送，再标记为sent；发送被拒绝（如已退群）时标记为failed
+// 领取使用 FOR UPDATE SKIP LOCKED，多节点同时运行时每条记录只由一个节点发送
+// 发送使用固定的client_msg_id，租约到期后重新领取也不会重复发送
+type ScheduledMessageDispatcher struct {
+	scheduledDAO   *dao.ScheduledMessageDAO
+	messageService *MessageService
+	groupService   *GroupService
+	hub            *websocket.Hub
+
+	pollInterval time.Duration
+}
+
+// NewScheduledMessageDispatcher 创建定时消息调度器
+func NewScheduledMessageDispatcher(cfg *config.Config, hub *websocket.Hub) *ScheduledMessageDispatcher {
+	d := &ScheduledMessageDispatcher{
+		scheduledDAO:   dao.NewScheduledMessageDAO(),
+		messageService: NewMessageService(cfg, hub),
+		groupService:   NewGroupService(cfg, hub),
+		hub:            hub,
+		pollInterval:   time.Duration(cfg.Message.ScheduledPollIntervalMs) * time.Millisecond,
+	}
+
+	if d.pollInterval <= 0 {
+		d.pollInterval = time.Second
+	}
+
+	return d
+}
+
+// Run 启动调度循环，直到ctx取消
+func (d *ScheduledMessageDispatcher) Run(ctx context.Context) {
+	log.Printf("Scheduled message dispatcher started: poll=%s", d.pollInterval)
+
+	ticker := time.NewTicker(d.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("Scheduled message dispatcher stopped")
+			return
+
+		case <-ticker.C:
+			// 一批满载说明还有到期消息，立即继续处理
+			for {
+				n, err := d.dispatchBatch()
+				if err != nil {
+					log.Printf("Scheduled message dispatch failed: %v", err)
+					break
+				}
+				if n < scheduledDispatchBatch || ctx.Err() != nil {
+					break
+				}
+			}
+		}
+	}
+}
+
+// dispatchBatch 领取并发送一批到期消息，返回处理的条数
+func (d *ScheduledMessageDispatcher) dispatchBatch() (int, error) {
+	due, err := d.claimDue()
+	if err != nil {
+		return 0, err
+	}
+
+	for i := range due {
+		d.dispatch(&due[i])
+	}
+	return len(due), nil
+}
+
+// claimDue 在短事务中锁定并领取一批到期消息，提交后行锁即释放
+// 发送期间用户修改或取消会因状态不再是pending而失败，不会等待发送完成
+func (d *ScheduledMessageDispatcher) claimDue() ([]model.ScheduledMessage, error) {
+	var due []model.ScheduledMessage
+
+	err := mysql.GetDB().Transaction(func(tx *gorm.DB) error {
+		scheduledDAO := d.scheduledDAO.WithTx(tx)
+
+		var err error
+		due, err = scheduledDAO.LockDue(scheduledDispatchBatch, time.Now().Add(-scheduledClaimLease))
+		if err != nil {
+			return err
+		}
+
+		ids := make([]uint, len(due))
+		for i, scheduled := range due {
+			ids[i] = scheduled.ID
+		}
+		return scheduledDAO.Claim(ids)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return due, nil
+}
+
+// dispatch 发送一条已领取的定时消息并记录结果
+// 记录结果失败时消息保持dispatching，租约到期后重新领取，相同的client_msg_id保证不会重复发送
+func (d *ScheduledMessageDispatcher) dispatch(scheduled *model.ScheduledMessage) {
+	message, err := d.send(scheduled)
+	if err != nil {
+		log.Printf("Scheduled message %d failed: %v", scheduled.ID, err)
+		if markErr := d.scheduledDAO.MarkFailed(scheduled.ID, err.Error()); markErr != nil {
+			log.Printf("Failed to mark scheduled message %d as failed: %v", scheduled.ID, markErr)
+			return
+		}
+		d.notify(scheduled.SenderID, scheduled.ID, model.ScheduledStatusFailed, nil, err.Error())
+		return
+	}
+
+	if err := d.scheduledDAO.MarkSent(scheduled.ID, message.ID); err != nil {
+		log.Printf("Failed to mark scheduled message %d as sent: %v", scheduled.ID, err)
+		return
+	}
+	d.notify(scheduled.SenderID, scheduled.ID, model.ScheduledStatusSent, &message.ID, "")
+}
+
+// send 以发送者身份发送定时消息，与客户端立即发送走相同的校验和投递流程
+func (d *ScheduledMessageDispatcher) send(scheduled *model.ScheduledMessage) (*model.Message, error) {
+	clientMsgID := fmt.Sprintf("scheduled:%d", scheduled.ID)
+	opts := SendOptions{
+		Payload:        scheduled.Payload,
+		MentionUserIDs: scheduled.MentionUserIDs,
+		MentionAll:     scheduled.MentionAll,
+	}
+
+	if scheduled.GroupID != nil {
+		return d.groupService.SendGroupMessage(*scheduled.GroupID, scheduled.SenderID, scheduled.Content, scheduled.Type,
+			&scheduled.FileURL, &scheduled.FileSize, &scheduled.Duration, clientMsgID, opts)
+	}
+	if scheduled.ReceiverID == nil {
+		return nil, errors.New("scheduled message without target")
+	}
+	return d.messageService.SendMessage(scheduled.SenderID, *scheduled.ReceiverID, scheduled.Content, scheduled.Type,
+		&scheduled.FileURL, &scheduled.FileSize, &scheduled.Duration, clientMsgID, opts, "", "scheduled-message-dispatcher")
+}
+
+// notify 通知发送者的在线设备定时消息已发送或发送失败
+func (d *ScheduledMessageDispatcher) notify(senderID, scheduledID uint, status string, messageID *uint, errMsg string) {
+	data := map[string]interface{}{
+		"scheduled_id": scheduledID,
+		"status":       status,
+	}
+	if messageID != nil {
+		data["message_id"] = *messageID
+	}
+	if errMsg != "" {
+		data["error"] = errMsg
+	}
+	d.hub.SendToUser(senderID, websocket.WebSocketMessage{
+		Type: "scheduled_message_status",
+		Data: data,
+	})
+}
@@ -0,0 +1,73 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lanxin/im-backend/internal/dao"
+	"github.com/lanxin/im-backend/internal/model"
+	"github.com/lanxin/im-backend/internal/testutil"
+	"github.com/lanxin/im-backend/internal/websocket"
+)
+
+func TestScheduledDispatcherClaimsBeforeSending(t *testing.T) {
+	mock := testutil.NewMockDB(t)
+	d := &ScheduledMessageDispatcher{scheduledDAO: dao.NewScheduledMessageDAO(), hub: websocket.NewHub()}
+
+	// 领取：锁定到期的pending和租约过期的dispatching记录，标记为dispatching后提交
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT \\* FROM `scheduled_messages` WHERE \\(status = \\? AND scheduled_at <= \\?\\) OR \\(status = \\? AND claimed_at <= \\?\\) ORDER BY scheduled_at ASC, id ASC LIMIT 50 FOR UPDATE SKIP LOCKED").
+		WithArgs(model.ScheduledStatusPending, sqlmock.AnyArg(), model.ScheduledStatusDispatching, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "sender_id", "status"}).
+			AddRow(1, 7, model.ScheduledStatusPending).
+			AddRow(2, 7, model.ScheduledStatusDispatching))
+	mock.ExpectExec("UPDATE `scheduled_messages` SET `claimed_at`=\\?,`status`=\\?,`updated_at`=\\? WHERE id IN \\(\\?,\\?\\)").
+		WithArgs(sqlmock.AnyArg(), model.ScheduledStatusDispatching, sqlmock.AnyArg(), 1, 2).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	// 发送在事务提交之后；两条记录都没有发送目标，发送失败
+	// 第一条标记失败时出错不影响后面的记录
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `scheduled_messages` SET `last_error`=\\?,`status`=\\?,`updated_at`=\\? WHERE id = \\? AND status = \\?").
+		WithArgs("scheduled message without target", model.ScheduledStatusFailed, sqlmock.AnyArg(), 1, model.ScheduledStatusDispatching).
+		WillReturnError(errors.New("connection reset"))
+	mock.ExpectRollback()
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `scheduled_messages` SET `last_error`=\\?,`status`=\\?,`updated_at`=\\? WHERE id = \\? AND status = \\?").
+		WithArgs("scheduled message without target", model.ScheduledStatusFailed, sqlmock.AnyArg(), 2, model.ScheduledStatusDispatching).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	n, err := d.dispatchBatch()
+	if err != nil {
+		t.Fatalf("dispatchBatch() error = %v", err)
+	}
+	if n != 2 {
+		t.Errorf("dispatchBatch() = %d, want 2", n)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestScheduledDispatcherClaimFailureSendsNothing(t *testing.T) {
+	mock := testutil.NewMockDB(t)
+	d := &ScheduledMessageDispatcher{scheduledDAO: dao.NewScheduledMessageDAO(), hub: websocket.NewHub()}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT \\* FROM `scheduled_messages`").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "sender_id", "status"}).AddRow(1, 7, model.ScheduledStatusPending))
+	mock.ExpectExec("UPDATE `scheduled_messages` SET `claimed_at`").
+		WillReturnError(errors.New("lock wait timeout"))
+	mock.ExpectRollback()
+
+	n, err := d.dispatchBatch()
+	if err == nil || n != 0 {
+		t.Fatalf("dispatchBatch() = %d, %v, want claim error and nothing sent", n, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,237 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lanxin/im-backend/config"
+	"github.com/lanxin/im-backend/internal/dao"
+	"github.com/lanxin/im-backend/internal/model"
+	"github.com/lanxin/im-backend/internal/pkg/mysql"
+	"github.com/lanxin/im-backend/internal/pkg/redis"
+	"github.com/lanxin/im-backend/internal/websocket"
+	"gorm.io/gorm"
+)
+
+// 撤销标记写入Redis的尝试次数
+const sessionRevokeAttempts = 3
+
+// ErrSessionRevokeIncomplete 会话已在数据库中撤销，但撤销标记没有写入Redis
+var ErrSessionRevokeIncomplete = errors.New("session revoked but failed to invalidate its tokens")
+
+// DeviceInfo 登录时客户端上报的设备信息
+type DeviceInfo struct {
+	DeviceID   string
+	DeviceName string
+	Platform   string
+	AppVersion string
+	IP         string
+}
+
+// SessionService 设备登录会话
+// 每次登录创建一个会话，Token携带会话ID；撤销会话时在Redis中标记，
+// 该会话的所有Token立即失效，同时断开该设备的WebSocket连接
+type SessionService struct {
+	sessionDAO *dao.DeviceSessionDAO
+	logDAO     *dao.OperationLogDAO
+	hub        *websocket.Hub
+	cfg        *config.Config
+}
+
+func NewSessionService(cfg *config.Config, hub *websocket.Hub) *SessionService {
+	return &SessionService{
+		sessionDAO: dao.NewDeviceSessionDAO(),
+		logDAO:     dao.NewOperationLogDAO(),
+		hub:        hub,
+		cfg:        cfg,
+	}
+}
+
+// Create 为新登录创建会话
+// 同一设备的旧会话以及超出同类平台数量限制的最早会话会被挤掉（reason为replaced）
+func (s *SessionService) Create(userID uint, device DeviceInfo) (*model.DeviceSession, error) {
+	device = normalizeDevice(device)
+	now := time.Now()
+	session := &model.DeviceSession{
+		UserID:       userID,
+		DeviceID:     device.DeviceID,
+		DeviceName:   device.DeviceName,
+		Platform:     device.Platform,
+		AppVersion:   device.AppVersion,
+		LastIP:       device.IP,
+		LastActiveAt: now,
+		ExpiresAt:    now.Add(time.Duration(s.cfg.JWT.ExpireHours) * time.Hour),
+	}
+
+	var replaced []model.DeviceSession
+	err := mysql.GetDB().Transaction(func(tx *gorm.DB) error {
+		sessionDAO := s.sessionDAO.WithTx(tx)
+
+		// 锁定用户行，同一用户在多台设备上同时登录时按顺序计算名额
+		if err := sessionDAO.LockUser(userID); err != nil {
+			return err
+		}
+
+		active, err := sessionDAO.ListActive(userID)
+		if err != nil {
+			return err
+		}
+		replaced = s.sessionsToReplace(active, device)
+
+		ids := make([]uint, len(replaced))
+		for i, old := range replaced {
+			ids[i] = old.ID
+		}
+		if _, err := sessionDAO.Revoke(ids, model.SessionRevokeReplaced); err != nil {
+			return err
+		}
+
+		return sessionDAO.Create(session)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// 事务提交后再让旧设备下线
+	// 撤销标记写入失败时数据库中已是撤销状态，鉴权回源数据库后同样会拒绝，不影响本次登录
+	for _, old := range replaced {
+		if err := s.kick(userID, old.ID, model.SessionRevokeReplaced); err != nil {
+			log.Printf("Failed to mark session %d as revoked: %v", old.ID, err)
+		}
+	}
+
+	return session, nil
+}
+
+// sessionsToReplace 计算新登录需要挤掉的会话
+// active按登录时间从早到晚排列
+func (s *SessionService) sessionsToReplace(active []model.DeviceSession, device DeviceInfo) []model.DeviceSession {
+	var replaced []model.DeviceSession
+	var sameClass []model.DeviceSession
+
+	class := model.PlatformClass(device.Platform)
+	for _, old := range active {
+		// 同一台设备重新登录，旧会话直接作废
+		if old.DeviceID == device.DeviceID {
+			replaced = append(replaced, old)
+			continue
+		}
+		if model.PlatformClass(old.Platform) == class {
+			sameClass = append(sameClass, old)
+		}
+	}
+
+	limit := s.cfg.Session.MaxPerPlatform[class]
+	if limit <= 0 {
+		return replaced
+	}
+
+	// 新会话占用一个名额，超出的部分从最早登录的开始挤掉
+	if over := len(sameClass) + 1 - limit; over > 0 {
+		replaced = append(replaced, sameClass[:over]...)
+	}
+	return replaced
+}
+
+// List 获取用户当前有效的登录设备
+func (s *SessionService) List(userID, currentSessionID uint) ([]model.DeviceSession, error) {
+	sessions, err := s.sessionDAO.ListActive(userID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == currentSessionID
+	}
+	return sessions, nil
+}
+
+// Revoke 撤销用户的一个登录会话（退出登录或在设备列表中移除）
+func (s *SessionService) Revoke(userID, sessionID uint, reason, ip, userAgent string) error {
+	session, err := s.sessionDAO.GetByID(sessionID, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("session not found")
+		}
+		return err
+	}
+	if session.RevokedAt != nil {
+		return errors.New("session already revoked")
+	}
+
+	_, err = s.sessionDAO.Revoke([]uint{sessionID}, reason)
+	if err == nil {
+		if kickErr := s.kick(userID, sessionID, reason); kickErr != nil {
+			err = fmt.Errorf("%w: %v", ErrSessionRevokeIncomplete, kickErr)
+		}
+	}
+
+	s.logDAO.CreateLog(dao.LogRequest{
+		Action:    model.ActionSessionRevoke,
+		UserID:    &userID,
+		IP:        ip,
+		UserAgent: userAgent,
+		Details: map[string]interface{}{
+			"session_id":  sessionID,
+			"device_id":   session.DeviceID,
+			"device_name": session.DeviceName,
+			"platform":    session.Platform,
+			"reason":      reason,
+		},
+		Result:       logResult(err),
+		ErrorMessage: logError(err),
+	})
+
+	return err
+}
+
+// IsActive 检查会话是否仍然有效（刷新Token时使用）
+func (s *SessionService) IsActive(userID, sessionID uint) (bool, error) {
+	session, err := s.sessionDAO.GetByID(sessionID, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	return session.RevokedAt == nil && session.ExpiresAt.After(time.Now()), nil
+}
+
+// Refresh 刷新Token后延长会话有效期
+func (s *SessionService) Refresh(sessionID uint, ip string) error {
+	expiresAt := time.Now().Add(time.Duration(s.cfg.JWT.ExpireHours) * time.Hour)
+	return s.sessionDAO.Refresh(sessionID, expiresAt, ip)
+}
+
+// kick 使会话的Token失效并断开该设备的WebSocket连接
+// Redis标记保留一个Token有效期，覆盖该会话最后签发的Token；写入失败时重试，仍失败则返回错误
+// 数据库中的撤销状态是最终依据，鉴权在Redis没有标记时回源数据库，最多受未撤销状态的短暂缓存影响
+func (s *SessionService) kick(userID, sessionID uint, reason string) error {
+	var err error
+	for attempt := 0; attempt < sessionRevokeAttempts; attempt++ {
+		if err = redis.RevokeSession(sessionID, s.cfg.JWT.ExpireHours*3600); err == nil {
+			break
+		}
+	}
+	if disconnectErr := s.hub.DisconnectSession(userID, sessionID, reason); disconnectErr != nil {
+		log.Printf("Failed to disconnect session %d: %v", sessionID, disconnectErr)
+	}
+	return err
+}
+
+// normalizeDevice 补全客户端未上报的设备信息
+// 旧版客户端不上报device_id，每次登录视为一台新设备；未知平台单独按unknown类别限制数量
+func normalizeDevice(device DeviceInfo) DeviceInfo {
+	if device.DeviceID == "" {
+		device.DeviceID = uuid.NewString()
+	}
+	switch device.Platform {
+	case model.PlatformAndroid, model.PlatformIOS, model.PlatformWindows,
+		model.PlatformMacOS, model.PlatformLinux, model.PlatformWeb:
+	default:
+		device.Platform = model.PlatformUnknown
+	}
+	return device
+}
@@ -0,0 +1,97 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/lanxin/im-backend/config"
+	"github.com/lanxin/im-backend/internal/model"
+)
+
+func TestSessionsToReplace(t *testing.T) {
+	s := &SessionService{cfg: &config.Config{Session: config.SessionConfig{
+		MaxPerPlatform: map[string]int{
+			model.PlatformClassMobile:  1,
+			model.PlatformClassWeb:     2,
+			model.PlatformClassUnknown: 2,
+		},
+	}}}
+	session := func(id uint, deviceID, platform string) model.DeviceSession {
+		return model.DeviceSession{ID: id, DeviceID: deviceID, Platform: platform}
+	}
+
+	tests := []struct {
+		name   string
+		active []model.DeviceSession
+		device DeviceInfo
+		want   []uint
+	}{
+		{
+			name:   "same device is replaced",
+			active: []model.DeviceSession{session(1, "pc", model.PlatformWindows), session(2, "browser", model.PlatformWeb)},
+			device: DeviceInfo{DeviceID: "browser", Platform: model.PlatformWeb},
+			want:   []uint{2},
+		},
+		{
+			name:   "oldest of the class over the limit",
+			active: []model.DeviceSession{session(1, "a", model.PlatformAndroid), session(2, "b", model.PlatformWeb)},
+			device: DeviceInfo{DeviceID: "c", Platform: model.PlatformIOS},
+			want:   []uint{1},
+		},
+		{
+			name:   "within the class limit",
+			active: []model.DeviceSession{session(1, "a", model.PlatformWeb), session(2, "b", model.PlatformAndroid)},
+			device: DeviceInfo{DeviceID: "c", Platform: model.PlatformWeb},
+		},
+		{
+			name: "same device and over the limit",
+			active: []model.DeviceSession{
+				session(1, "a", model.PlatformWeb),
+				session(2, "b", model.PlatformWeb),
+				session(3, "c", model.PlatformWeb),
+			},
+			device: DeviceInfo{DeviceID: "c", Platform: model.PlatformWeb},
+			want:   []uint{3, 1},
+		},
+		{
+			name: "unknown platforms share their own limit",
+			active: []model.DeviceSession{
+				session(1, "a", model.PlatformUnknown),
+				session(2, "b", model.PlatformUnknown),
+				session(3, "c", model.PlatformWeb),
+			},
+			device: DeviceInfo{DeviceID: "d", Platform: model.PlatformUnknown},
+			want:   []uint{1},
+		},
+		{
+			name: "unconfigured class is unlimited",
+			active: []model.DeviceSession{
+				session(1, "a", model.PlatformMacOS),
+				session(2, "b", model.PlatformLinux),
+			},
+			device: DeviceInfo{DeviceID: "c", Platform: model.PlatformWindows},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []uint
+			for _, replaced := range s.sessionsToReplace(tt.active, tt.device) {
+				got = append(got, replaced.ID)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("sessionsToReplace() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeDeviceUnknownPlatform(t *testing.T) {
+	device := normalizeDevice(DeviceInfo{Platform: "harmonyos"})
+	if device.Platform != model.PlatformUnknown || device.DeviceID == "" {
+		t.Errorf("normalizeDevice() = %+v, want unknown platform with a generated device_id", device)
+	}
+	if class := model.PlatformClass(device.Platform); class != model.PlatformClassUnknown {
+		t.Errorf("PlatformClass() = %q, want %q", class, model.PlatformClassUnknown)
+	}
+}
@@ -0,0 +1,116 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/lanxin/im-backend/internal/dao"
+	"github.com/lanxin/im-backend/internal/model"
+	"github.com/lanxin/im-backend/internal/pkg/mysql"
+	"gorm.io/gorm"
+)
+
+// systemMessenger 在会话中写入服务端生成的系统通知（群成员变动、撤回等）
+// 和普通消息一样分配序号、更新会话列表、写入发件箱事件并投递给操作者以外的参与者
+type systemMessenger struct {
+	messageDAO      *dao.MessageDAO
+	conversationDAO *dao.ConversationDAO
+	memberDAO       *dao.ConversationMemberDAO
+	userDAO         *dao.UserDAO
+	payloads        *messagePayloadValidator
+	messages        *MessageService // 发件箱事件和投递与普通消息共用
+}
+
+func newSystemMessenger(messages *MessageService) *systemMessenger {
+	return &systemMessenger{
+		messageDAO:      dao.NewMessageDAO(),
+		conversationDAO: dao.NewConversationDAO(),
+		memberDAO:       dao.NewConversationMemberDAO(),
+		userDAO:         dao.NewUserDAO(),
+		payloads:        newMessagePayloadValidator(),
+		messages:        messages,
+	}
+}
+
+// post 写入系统通知并投递给会话参与者
+// 触发通知的操作本身已经完成，写入失败只记录日志
+func (m *systemMessenger) post(conv *model.Conversation, operatorID uint, notice model.SystemPayload, content string) *model.Message {
+	payload, err := json.Marshal(notice)
+	if err != nil {
+		return nil
+	}
+
+	message := &model.Message{
+		ConversationID: conv.ID,
+		SenderID:       operatorID,
+		GroupID:        conv.GroupID,
+		Content:        content,
+		Type:           model.MessageTypeSystem,
+		Status:         model.MessageStatusSent,
+	}
+	// 单聊的接收者为操作者以外的一方
+	if conv.Type == model.ConversationTypeSingle && conv.User1ID != nil && conv.User2ID != nil {
+		message.ReceiverID = *conv.User1ID
+		if message.ReceiverID == operatorID {
+			message.ReceiverID = *conv.User2ID
+		}
+	}
+	if err := m.payloads.apply(message, SendOptions{Payload: payload, serverGenerated: true}); err != nil {
+		log.Printf("Invalid system message %s for conversation %d: %v", notice.Event, conv.ID, err)
+		return nil
+	}
+
+	err = mysql.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := m.messageDAO.WithTx(tx).CreateWithSeq(message); err != nil {
+			return err
+		}
+		now := time.Now()
+		if err := m.conversationDAO.WithTx(tx).UpdateLastMessage(conv.ID, message.ID, &now); err != nil {
+			return err
+		}
+		if err := m.memberDAO.WithTx(tx).OnNewMessage(conv.ID, operatorID, message.Seq); err != nil {
+			return err
+		}
+		return m.messages.enqueueMessageEvent(tx, message)
+	})
+	if err != nil {
+		log.Printf("Failed to save system message %s for conversation %d: %v", notice.Event, conv.ID, err)
+		return nil
+	}
+
+	if !m.messages.asyncDelivery {
+		go m.messages.DeliverMessage(message)
+	}
+	return message
+}
+
+// userName 用户在通知文本中的显示名称
+func (m *systemMessenger) userName(userID uint) string {
+	return m.userNames([]uint{userID})
+}
+
+// userNames 多个用户的显示名称，用顿号连接
+// 用户已注销或用户名为空时显示为"用户+ID"，通知文本中不会出现空白的名字
+func (m *systemMessenger) userNames(userIDs []uint) string {
+	byID := make(map[uint]string, len(userIDs))
+	if users, err := m.userDAO.GetByIDs(userIDs); err == nil {
+		for _, user := range users {
+			if name := strings.TrimSpace(user.Username); name != "" {
+				byID[user.ID] = name
+			}
+		}
+	}
+
+	names := make([]string, len(userIDs))
+	for i, userID := range userIDs {
+		name, ok := byID[userID]
+		if !ok {
+			name = fmt.Sprintf("用户%d", userID)
+		}
+		names[i] = name
+	}
+	return strings.Join(names, "、")
+}
@@ -0,0 +1,22 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lanxin/im-backend/internal/dao"
+	"github.com/lanxin/im-backend/internal/testutil"
+)
+
+func TestSystemMessengerUserNamesFallBackToID(t *testing.T) {
+	mock := testutil.NewMockDB(t)
+	m := &systemMessenger{userDAO: dao.NewUserDAO()}
+
+	mock.ExpectQuery("SELECT \\* FROM `users` WHERE id IN \\(\\?,\\?,\\?\\)").
+		WithArgs(1, 2, 3).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username"}).AddRow(1, "张三").AddRow(2, " "))
+
+	if got, want := m.userNames([]uint{1, 2, 3}), "张三、用户2、用户3"; got != want {
+		t.Errorf("userNames() = %q, want %q", got, want)
+	}
+}
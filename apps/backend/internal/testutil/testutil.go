@@ -0,0 +1,55 @@
+// Package testutil 各包单元测试共用的数据库和Redis替身
+package testutil
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/alicebob/miniredis/v2"
+	goredis "github.com/go-redis/redis/v8"
+	"github.com/lanxin/im-backend/internal/pkg/mysql"
+	"github.com/lanxin/im-backend/internal/pkg/redis"
+	gmysql "gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// NewMockDB 用sqlmock替换全局数据库连接，测试结束后恢复
+// DAO在创建时保存数据库连接，必须在创建DAO/Service之前调用
+func NewMockDB(t *testing.T) sqlmock.Sqlmock {
+	t.Helper()
+
+	sqlDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock: %v", err)
+	}
+	db, err := gorm.Open(gmysql.New(gmysql.Config{
+		Conn:                      sqlDB,
+		SkipInitializeWithVersion: true,
+	}), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("gorm: %v", err)
+	}
+
+	prev := mysql.DB
+	mysql.DB = db
+	t.Cleanup(func() {
+		mysql.DB = prev
+		sqlDB.Close()
+	})
+	return mock
+}
+
+// NewRedis 用miniredis替换全局Redis连接，测试结束后恢复
+func NewRedis(t *testing.T) *miniredis.Miniredis {
+	t.Helper()
+
+	mr := miniredis.RunT(t)
+	prev := redis.Client
+	redis.Client = goredis.NewClient(&goredis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() {
+		redis.Client.Close()
+		redis.Client = prev
+	})
+	return mr
+}
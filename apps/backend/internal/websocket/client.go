@@ -4,11 +4,13 @@ import (
 	"encoding/json"
 	"log"
 	"net/http"
+	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
 	"github.com/lanxin/im-backend/internal/pkg/jwt"
+	"github.com/lanxin/im-backend/internal/pkg/redis"
 )
 
 const (
@@ -23,6 +25,15 @@ const (
 
 	// 最大消息大小
 	maxMessageSize = 10240 // 10KB
+
+	// 推送消息等待客户端确认的时间，超时后放回离线队列
+	ackTimeout = 30 * time.Second
+
+	// 检查确认超时的周期
+	ackCheckPeriod = 10 * time.Second
+
+	// 设备会话被撤销时的关闭码，客户端收到后应回到登录页而不是重连
+	CloseSessionRevoked = 4001
 )
 
 // 允许的WebSocket Origin列表（生产环境配置）
@@ -36,6 +47,8 @@ var allowedOrigins = []string{
 var upgrader = websocket.Upgrader{
 	ReadBufferSize:  1024,
 	WriteBufferSize: 1024,
+	// 客户端同时支持两种协议时优先使用protobuf
+	Subprotocols: []string{SubprotocolProtobuf, SubprotocolJSON},
 	CheckOrigin: func(r *http.Request) bool {
 		// ✅ 生产环境origin检查
 		origin := r.Header.Get("Origin")
@@ -78,6 +91,73 @@ type Client struct {
 
 	// 用户名
 	username string
+
+	// 签发Token时的设备会话ID（旧Token为0）
+	sessionID uint
+
+	// 会话被撤销时写入通知帧，writePump发出后关闭连接
+	kick chan []byte
+
+	// 是否使用protobuf二进制协议（握手时协商）
+	binary bool
+
+	// 连接的在线状态（online/away），由Hub主循环维护
+	presence string
+
+	// 连接来源（用于操作日志）
+	ip        string
+	userAgent string
+
+	// 已推送但未确认的消息（messageID -> 推送时间）
+	pendingAcks map[uint]time.Time
+	ackMu       sync.Mutex
+}
+
+// trackAck 登记一条待确认的消息
+func (c *Client) trackAck(messageID uint) {
+	c.ackMu.Lock()
+	defer c.ackMu.Unlock()
+	if _, exists := c.pendingAcks[messageID]; !exists {
+		c.pendingAcks[messageID] = time.Now()
+	}
+}
+
+// confirmAcks 客户端确认收到消息
+func (c *Client) confirmAcks(messageIDs []uint) {
+	c.ackMu.Lock()
+	defer c.ackMu.Unlock()
+	for _, id := range messageIDs {
+		delete(c.pendingAcks, id)
+	}
+}
+
+// expiredAcks 取出确认超时的消息
+func (c *Client) expiredAcks() []uint {
+	c.ackMu.Lock()
+	defer c.ackMu.Unlock()
+
+	var expired []uint
+	deadline := time.Now().Add(-ackTimeout)
+	for id, pushedAt := range c.pendingAcks {
+		if pushedAt.Before(deadline) {
+			expired = append(expired, id)
+			delete(c.pendingAcks, id)
+		}
+	}
+	return expired
+}
+
+// drainAcks 取出全部待确认消息（连接关闭时调用）
+func (c *Client) drainAcks() []uint {
+	c.ackMu.Lock()
+	defer c.ackMu.Unlock()
+
+	pending := make([]uint, 0, len(c.pendingAcks))
+	for id := range c.pendingAcks {
+		pending = append(pending, id)
+	}
+	c.pendingAcks = make(map[uint]time.Time)
+	return pending
 }
 
 // readPump 从WebSocket连接读取消息并发送到hub
@@ -95,7 +175,7 @@ func (c *Client) readPump() {
 	})
 
 	for {
-		_, message, err := c.conn.ReadMessage()
+		messageType, message, err := c.conn.ReadMessage()
 		if err != nil {
 			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
 				log.Printf("WebSocket error: %v", err)
@@ -104,6 +184,15 @@ func (c *Client) readPump() {
 		}
 
 		// 处理客户端消息
+		if messageType == websocket.BinaryMessage {
+			frame, err := decodeEnvelope(message)
+			if err != nil {
+				log.Printf("Error decoding envelope: %v", err)
+				continue
+			}
+			c.handleFrame(frame)
+			continue
+		}
 		c.handleMessage(message)
 	}
 }
@@ -111,8 +200,10 @@ func (c *Client) readPump() {
 // writePump 从hub接收消息并写入WebSocket连接
 func (c *Client) writePump() {
 	ticker := time.NewTicker(pingPeriod)
+	ackTicker := time.NewTicker(ackCheckPeriod)
 	defer func() {
 		ticker.Stop()
+		ackTicker.Stop()
 		c.conn.Close()
 	}()
 
@@ -126,21 +217,17 @@ func (c *Client) writePump() {
 				return
 			}
 
-			w, err := c.conn.NextWriter(websocket.TextMessage)
-			if err != nil {
+			// 每帧独立写入一条WebSocket消息，客户端无需再按换行拆分
+			if err := c.writeFrame(message); err != nil {
 				return
 			}
-			w.Write(message)
 
-			// 将队列中的消息一起发送
+			// 继续写出队列中已有的消息
 			n := len(c.send)
 			for i := 0; i < n; i++ {
-				w.Write([]byte{'\n'})
-				w.Write(<-c.send)
-			}
-
-			if err := w.Close(); err != nil {
-				return
+				if err := c.writeFrame(<-c.send); err != nil {
+					return
+				}
 			}
 
 		case <-ticker.C:
@@ -148,32 +235,136 @@ func (c *Client) writePump() {
 			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
 				return
 			}
+
+		case frame := <-c.kick:
+			// 先发出通知再关闭，客户端据此区分被踢下线和网络断开
+			c.writeFrame(frame)
+			c.conn.WriteControl(websocket.CloseMessage,
+				websocket.FormatCloseMessage(CloseSessionRevoked, "session revoked"),
+				time.Now().Add(writeWait))
+			return
+
+		case <-ackTicker.C:
+			// 确认超时的消息交回业务层
+			if expired := c.expiredAcks(); len(expired) > 0 {
+				c.hub.notifyUnacked(c.userID, expired)
+			}
 		}
 	}
 }
 
-// handleMessage 处理客户端发来的消息
+// writeFrame 写入一帧，protobuf连接把JSON帧转换为Envelope后以二进制发送
+func (c *Client) writeFrame(data []byte) error {
+	c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+
+	if !c.binary {
+		return c.conn.WriteMessage(websocket.TextMessage, data)
+	}
+
+	envelope, err := encodeEnvelope(data)
+	if err != nil {
+		// 单帧转换失败不影响连接，丢弃该帧
+		log.Printf("Error encoding envelope for user %d: %v", c.userID, err)
+		return nil
+	}
+	return c.conn.WriteMessage(websocket.BinaryMessage, envelope)
+}
+
+// handleMessage 处理客户端发来的JSON消息
 func (c *Client) handleMessage(message []byte) {
-	var msg WebSocketMessage
-	if err := json.Unmarshal(message, &msg); err != nil {
+	var frame ClientFrame
+	if err := json.Unmarshal(message, &frame); err != nil {
 		log.Printf("Error unmarshaling message: %v", err)
 		return
 	}
 
-	switch msg.Type {
-	case "ping":
+	c.handleFrame(&frame)
+}
+
+// handleFrame 处理一个上行帧
+// 同一连接上的帧按到达顺序依次处理，保证客户端发送顺序
+func (c *Client) handleFrame(frame *ClientFrame) {
+	switch frame.Type {
+	case FrameTypePing:
 		// 响应心跳
-		pong := WebSocketMessage{
-			Type: "pong",
+		c.reply(WebSocketMessage{
+			Type: FrameTypePong,
 			Data: map[string]interface{}{
 				"timestamp": time.Now().Unix(),
 			},
+		})
+
+	case FrameTypeAck:
+		// 先在连接上清除待确认记录，再交给业务层更新送达状态
+		var ack struct {
+			MessageIDs []uint `json:"message_ids"`
 		}
-		data, _ := json.Marshal(pong)
-		c.send <- data
+		if err := json.Unmarshal(frame.Data, &ack); err == nil {
+			c.confirmAcks(ack.MessageIDs)
+		}
+		c.handleUpstream(frame)
+
+	case FrameTypePresence:
+		c.handlePresence(frame)
+
+	case FrameTypeSend, FrameTypeRead, FrameTypeTyping, FrameTypeRecordingVoice, FrameTypeRecall:
+		c.handleUpstream(frame)
+
+	default:
+		log.Printf("Unknown message type: %s", frame.Type)
+		c.respond(frame.RequestID, 400, "unknown frame type: "+frame.Type, nil)
+	}
+}
+
+// handleUpstream 将业务帧交给上行处理器，并回复应答帧
+func (c *Client) handleUpstream(frame *ClientFrame) {
+	if c.hub.upstream == nil {
+		c.respond(frame.RequestID, 501, "upstream messaging not enabled", nil)
+		return
+	}
+
+	ctx := &FrameContext{
+		UserID:    c.userID,
+		Username:  c.username,
+		IP:        c.ip,
+		UserAgent: c.userAgent,
+	}
+
+	data, err := c.hub.upstream.HandleFrame(ctx, frame)
+	if err != nil {
+		c.respond(frame.RequestID, 400, err.Error(), nil)
+		return
+	}
+
+	// typing等无需应答的帧没有request_id
+	if frame.RequestID != "" {
+		c.respond(frame.RequestID, 0, "success", data)
+	}
+}
 
+// respond 发送应答帧
+func (c *Client) respond(requestID string, code int, message string, data interface{}) {
+	c.reply(ResponseFrame{
+		Type:      FrameTypeResponse,
+		RequestID: requestID,
+		Code:      code,
+		Message:   message,
+		Data:      data,
+	})
+}
+
+// reply 向当前连接写入一帧，发送队列已满时丢弃
+func (c *Client) reply(v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		log.Printf("Error marshaling reply: %v", err)
+		return
+	}
+
+	select {
+	case c.send <- data:
 	default:
-		log.Printf("Unknown message type: %s", msg.Type)
+		log.Printf("Failed to reply to client of user %d: send buffer full", c.userID)
 	}
 }
 
@@ -193,6 +384,23 @@ func ServeWS(hub *Hub, c *gin.Context, secret string) {
 		return
 	}
 
+	// 已登出的Token和已撤销的设备会话不允许建立连接
+	if redis.IsTokenBlacklisted(tokenString) {
+		c.JSON(401, gin.H{"code": 401, "message": "Token has been revoked"})
+		return
+	}
+	if claims.SessionID != 0 && hub.sessionChecker != nil {
+		revoked, err := hub.sessionChecker(claims.UserID, claims.SessionID)
+		if err != nil {
+			c.JSON(503, gin.H{"code": 503, "message": "Failed to verify session"})
+			return
+		}
+		if revoked {
+			c.JSON(401, gin.H{"code": 401, "message": "Token has been revoked"})
+			return
+		}
+	}
+
 	// 升级HTTP连接为WebSocket
 	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
 	if err != nil {
@@ -201,11 +409,19 @@ func ServeWS(hub *Hub, c *gin.Context, secret string) {
 	}
 
 	client := &Client{
-		hub:      hub,
-		conn:     conn,
-		send:     make(chan []byte, 256),
-		userID:   claims.UserID,
-		username: claims.Username,
+		hub:       hub,
+		conn:      conn,
+		send:      make(chan []byte, 256),
+		userID:    claims.UserID,
+		username:  claims.Username,
+		sessionID: claims.SessionID,
+		kick:      make(chan []byte, 1),
+		ip:        c.ClientIP(),
+		userAgent: c.GetHeader("User-Agent"),
+		binary:    conn.Subprotocol() == SubprotocolProtobuf,
+		presence:  PresenceOnline,
+
+		pendingAcks: make(map[uint]time.Time),
 	}
 
 	client.hub.register <- client
@@ -0,0 +1,460 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	goredis "github.com/go-redis/redis/v8"
+)
+
+const (
+	// 节点心跳周期
+	nodeHeartbeatInterval = 10 * time.Second
+
+	// 超过该时间未心跳的节点视为下线
+	nodeExpireAfter = 3 * nodeHeartbeatInterval
+
+	// Redis键和频道
+	clusterNodesKey        = "ws:nodes"       // ZSET: nodeID -> 最后心跳时间
+	clusterPresencePrefix  = "ws:presence:"   // HASH: ws:presence:<uid> 该用户所在的节点 -> 在该节点上的状态
+	clusterNodeUsersPrefix = "ws:node_users:" // SET:  ws:node_users:<nodeID> 该节点上的在线用户
+	clusterDeliverPrefix   = "ws:deliver:"    // 频道: ws:deliver:<nodeID> 投递给该节点的消息
+	clusterBroadcastTopic  = "ws:broadcast"   // 频道: 全量广播
+)
+
+// clusterEnvelope 节点间转发的投递信封
+type clusterEnvelope struct {
+	Origin       string          `json:"origin"`                  // 发出投递的节点
+	UserID       uint            `json:"user_id,omitempty"`       // 目标用户（广播时为0）
+	AckID        uint            `json:"ack_id,omitempty"`        // 需要客户端确认的消息ID
+	CloseSession uint            `json:"close_session,omitempty"` // 发出Data后关闭该设备会话的连接
+	Data         json.RawMessage `json:"data"`                    // 已序列化的WebSocket消息
+}
+
+// Cluster 基于Redis的多节点协调器
+//
+// 功能说明:
+//   - 在Redis中登记每个用户连接在哪些节点上（在线注册表）
+//   - 向远端用户投递时，发布到该用户所在节点的专属频道
+//   - 每个节点订阅自己的频道，收到后投递给本地连接
+//   - 节点定期心跳，失联节点的在线记录由存活节点清理
+//   - 在线注册表由后台协程写入，Redis变慢或不可用时不阻塞Hub主循环
+type Cluster struct {
+	hub    *Hub
+	rdb    *goredis.Client
+	nodeID string
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// 待写入在线注册表的连接状态（userID -> 在本节点的状态，offline表示已断开）
+	registryMu      sync.Mutex
+	registryPending map[uint]string
+	registryWake    chan struct{}
+	registryDone    chan struct{}
+}
+
+func newCluster(hub *Hub, rdb *goredis.Client, nodeID string) *Cluster {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Cluster{
+		hub:    hub,
+		rdb:    rdb,
+		nodeID: nodeID,
+		ctx:    ctx,
+		cancel: cancel,
+
+		registryPending: make(map[uint]string),
+		registryWake:    make(chan struct{}, 1),
+		registryDone:    make(chan struct{}),
+	}
+}
+
+// start 清理本节点残留状态并启动心跳与订阅
+func (c *Cluster) start() {
+	// 节点ID可能被重启后复用，先清掉上一次运行留下的在线记录
+	c.purgeNode(c.nodeID)
+	c.heartbeat()
+
+	go c.heartbeatLoop()
+	go c.subscribeLoop()
+	go c.registryLoop()
+
+	log.Printf("WebSocket cluster enabled: node=%s", c.nodeID)
+}
+
+// stop 停止后台任务并注销本节点
+func (c *Cluster) stop() {
+	c.cancel()
+	// 等待注册表写入结束，避免清理之后又写入本节点的在线记录
+	<-c.registryDone
+	c.purgeNode(c.nodeID)
+	c.rdb.ZRem(context.Background(), clusterNodesKey, c.nodeID)
+}
+
+// heartbeatLoop 定期上报心跳并清理失联节点
+func (c *Cluster) heartbeatLoop() {
+	ticker := time.NewTicker(nodeHeartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.ctx.Done():
+			return
+		case <-ticker.C:
+			c.heartbeat()
+			c.reapDeadNodes()
+		}
+	}
+}
+
+// heartbeat 上报本节点心跳
+func (c *Cluster) heartbeat() {
+	err := c.rdb.ZAdd(c.ctx, clusterNodesKey, &goredis.Z{
+		Score:  float64(time.Now().Unix()),
+		Member: c.nodeID,
+	}).Err()
+	if err != nil {
+		log.Printf("Cluster heartbeat failed: %v", err)
+	}
+}
+
+// reapDeadNodes 清理超时未心跳节点的在线记录
+func (c *Cluster) reapDeadNodes() {
+	deadline := strconv.FormatInt(time.Now().Add(-nodeExpireAfter).Unix(), 10)
+	nodes, err := c.rdb.ZRangeByScore(c.ctx, clusterNodesKey, &goredis.ZRangeBy{
+		Min: "-inf",
+		Max: "(" + deadline,
+	}).Result()
+	if err != nil {
+		return
+	}
+
+	for _, node := range nodes {
+		log.Printf("Cluster node %s expired, purging its presence", node)
+		userIDs := c.purgeNode(node)
+		c.rdb.ZRem(c.ctx, clusterNodesKey, node)
+
+		// 失联节点上的用户可能已经离线
+		for _, userID := range userIDs {
+			c.hub.notifyPresence(userID)
+		}
+	}
+}
+
+// purgeNode 删除某个节点登记的全部在线记录，返回被清理的用户
+func (c *Cluster) purgeNode(nodeID string) []uint {
+	ctx := context.Background()
+	setKey := clusterNodeUsersPrefix + nodeID
+
+	uids, err := c.rdb.SMembers(ctx, setKey).Result()
+	if err != nil {
+		return nil
+	}
+
+	pipe := c.rdb.Pipeline()
+	for _, uid := range uids {
+		pipe.HDel(ctx, clusterPresencePrefix+uid, nodeID)
+	}
+	pipe.Del(ctx, setKey)
+	if _, err := pipe.Exec(ctx); err != nil {
+		log.Printf("Cluster purge node %s failed: %v", nodeID, err)
+		return nil
+	}
+
+	userIDs := make([]uint, 0, len(uids))
+	for _, uid := range uids {
+		if id, err := strconv.ParseUint(uid, 10, 64); err == nil {
+			userIDs = append(userIDs, uint(id))
+		}
+	}
+	return userIDs
+}
+
+// liveNodes 返回当前存活的节点集合
+func (c *Cluster) liveNodes() (map[string]bool, error) {
+	min := strconv.FormatInt(time.Now().Add(-nodeExpireAfter).Unix(), 10)
+	nodes, err := c.rdb.ZRangeByScore(c.ctx, clusterNodesKey, &goredis.ZRangeBy{
+		Min: min,
+		Max: "+inf",
+	}).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	live := make(map[string]bool, len(nodes))
+	for _, node := range nodes {
+		live[node] = true
+	}
+	return live, nil
+}
+
+// userConnected 登记用户已连接到本节点及其在本节点的状态（online/away）
+// 只记录状态，由registryLoop写入Redis，可以在Hub主循环中调用
+func (c *Cluster) userConnected(userID uint, state string) {
+	c.queueRegistry(userID, state)
+}
+
+// userDisconnected 登记用户在本节点上的连接已全部断开
+func (c *Cluster) userDisconnected(userID uint) {
+	c.queueRegistry(userID, PresenceOffline)
+}
+
+// queueRegistry 记录用户在本节点的最新状态并唤醒registryLoop
+// 写入之前多次变化的用户只写入最后的状态
+func (c *Cluster) queueRegistry(userID uint, state string) {
+	c.registryMu.Lock()
+	c.registryPending[userID] = state
+	c.registryMu.Unlock()
+
+	select {
+	case c.registryWake <- struct{}{}:
+	default:
+		// 已有未处理的唤醒
+	}
+}
+
+// registryLoop 把本节点的连接变化写入在线注册表
+func (c *Cluster) registryLoop() {
+	defer close(c.registryDone)
+
+	for {
+		select {
+		case <-c.ctx.Done():
+			return
+		case <-c.registryWake:
+			c.flushRegistry()
+		}
+	}
+}
+
+// flushRegistry 写入所有待写入的连接状态
+func (c *Cluster) flushRegistry() {
+	c.registryMu.Lock()
+	pending := c.registryPending
+	c.registryPending = make(map[uint]string)
+	c.registryMu.Unlock()
+
+	for userID, state := range pending {
+		if state == PresenceOffline {
+			c.unregisterUser(userID)
+		} else {
+			c.registerUser(userID, state)
+		}
+	}
+}
+
+// registerUser 在在线注册表中登记用户连接在本节点及其状态
+func (c *Cluster) registerUser(userID uint, state string) {
+	uid := strconv.FormatUint(uint64(userID), 10)
+	pipe := c.rdb.TxPipeline()
+	pipe.HSet(c.ctx, clusterPresencePrefix+uid, c.nodeID, state)
+	pipe.SAdd(c.ctx, clusterNodeUsersPrefix+c.nodeID, uid)
+	if _, err := pipe.Exec(c.ctx); err != nil {
+		log.Printf("Cluster register presence failed: UserID=%d, err=%v", userID, err)
+	}
+}
+
+// unregisterUser 从在线注册表中移除用户在本节点的记录
+func (c *Cluster) unregisterUser(userID uint) {
+	uid := strconv.FormatUint(uint64(userID), 10)
+	pipe := c.rdb.TxPipeline()
+	pipe.HDel(c.ctx, clusterPresencePrefix+uid, c.nodeID)
+	pipe.SRem(c.ctx, clusterNodeUsersPrefix+c.nodeID, uid)
+	if _, err := pipe.Exec(c.ctx); err != nil {
+		log.Printf("Cluster unregister presence failed: UserID=%d, err=%v", userID, err)
+	}
+}
+
+// remoteNodes 返回用户所在的其他存活节点
+func (c *Cluster) remoteNodes(userID uint) []string {
+	uid := strconv.FormatUint(uint64(userID), 10)
+	nodes, err := c.rdb.HKeys(c.ctx, clusterPresencePrefix+uid).Result()
+	if err != nil || len(nodes) == 0 {
+		return nil
+	}
+
+	live, err := c.liveNodes()
+	if err != nil {
+		return nil
+	}
+
+	remote := make([]string, 0, len(nodes))
+	for _, node := range nodes {
+		if node != c.nodeID && live[node] {
+			remote = append(remote, node)
+		}
+	}
+	return remote
+}
+
+// deliverRemote 将消息发布到用户所在的远端节点，返回发布到的节点数
+// ackID不为0时由远端节点在连接上登记待确认
+func (c *Cluster) deliverRemote(userID uint, data []byte, ackID uint) (int, error) {
+	nodes := c.remoteNodes(userID)
+	if len(nodes) == 0 {
+		return 0, nil
+	}
+
+	payload, err := json.Marshal(clusterEnvelope{
+		Origin: c.nodeID,
+		UserID: userID,
+		AckID:  ackID,
+		Data:   data,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	for i, node := range nodes {
+		if err := c.rdb.Publish(c.ctx, clusterDeliverPrefix+node, payload).Err(); err != nil {
+			log.Printf("Cluster publish to node %s failed: %v", node, err)
+			return i, err
+		}
+	}
+	return len(nodes), nil
+}
+
+// closeSession 通知用户所在的远端节点关闭指定设备会话的连接
+func (c *Cluster) closeSession(userID, sessionID uint, data []byte) error {
+	nodes := c.remoteNodes(userID)
+	if len(nodes) == 0 {
+		return nil
+	}
+
+	payload, err := json.Marshal(clusterEnvelope{
+		Origin:       c.nodeID,
+		UserID:       userID,
+		CloseSession: sessionID,
+		Data:         data,
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, node := range nodes {
+		if err := c.rdb.Publish(c.ctx, clusterDeliverPrefix+node, payload).Err(); err != nil {
+			log.Printf("Cluster publish to node %s failed: %v", node, err)
+			return err
+		}
+	}
+	return nil
+}
+
+// broadcast 向所有节点广播消息
+func (c *Cluster) broadcast(data []byte) error {
+	payload, err := json.Marshal(clusterEnvelope{
+		Origin: c.nodeID,
+		Data:   data,
+	})
+	if err != nil {
+		return err
+	}
+	return c.rdb.Publish(c.ctx, clusterBroadcastTopic, payload).Err()
+}
+
+// subscribeLoop 订阅本节点频道和广播频道，把远端投递转给本地连接
+func (c *Cluster) subscribeLoop() {
+	sub := c.rdb.Subscribe(c.ctx, clusterDeliverPrefix+c.nodeID, clusterBroadcastTopic)
+	defer sub.Close()
+
+	ch := sub.Channel()
+	for {
+		select {
+		case <-c.ctx.Done():
+			return
+		case msg, ok := <-ch:
+			if !ok {
+				return
+			}
+
+			var env clusterEnvelope
+			if err := json.Unmarshal([]byte(msg.Payload), &env); err != nil {
+				log.Printf("Cluster envelope decode failed: %v", err)
+				continue
+			}
+
+			if msg.Channel == clusterBroadcastTopic {
+				if env.Origin != c.nodeID {
+					c.hub.broadcast <- []byte(env.Data)
+				}
+				continue
+			}
+
+			if env.CloseSession != 0 {
+				c.hub.closeSessionLocal(env.UserID, env.CloseSession, []byte(env.Data))
+				continue
+			}
+
+			c.hub.deliverLocal(env.UserID, []byte(env.Data), env.AckID)
+		}
+	}
+}
+
+// isUserOnline 检查用户是否在任意存活节点上在线
+func (c *Cluster) isUserOnline(userID uint) bool {
+	uid := strconv.FormatUint(uint64(userID), 10)
+	nodes, err := c.rdb.HKeys(c.ctx, clusterPresencePrefix+uid).Result()
+	if err != nil || len(nodes) == 0 {
+		return false
+	}
+
+	live, err := c.liveNodes()
+	if err != nil {
+		return false
+	}
+
+	for _, node := range nodes {
+		if live[node] {
+			return true
+		}
+	}
+	return false
+}
+
+// remotePresence 返回用户在其他存活节点上的状态（取最活跃的一个）
+func (c *Cluster) remotePresence(userID uint) string {
+	uid := strconv.FormatUint(uint64(userID), 10)
+	nodes, err := c.rdb.HGetAll(c.ctx, clusterPresencePrefix+uid).Result()
+	if err != nil || len(nodes) == 0 {
+		return PresenceOffline
+	}
+
+	live, err := c.liveNodes()
+	if err != nil {
+		return PresenceOffline
+	}
+
+	state := PresenceOffline
+	for node, nodeState := range nodes {
+		if node != c.nodeID && live[node] {
+			state = mergePresence(state, nodeState)
+		}
+	}
+	return state
+}
+
+// onlineUserCount 统计全集群在线用户数（同一用户多节点只计一次）
+func (c *Cluster) onlineUserCount() (int, error) {
+	live, err := c.liveNodes()
+	if err != nil {
+		return 0, err
+	}
+	if len(live) == 0 {
+		return 0, nil
+	}
+
+	keys := make([]string, 0, len(live))
+	for node := range live {
+		keys = append(keys, clusterNodeUsersPrefix+node)
+	}
+
+	n, err := c.rdb.SUnion(c.ctx, keys...).Result()
+	if err != nil {
+		return 0, fmt.Errorf("count cluster online users: %w", err)
+	}
+	return len(n), nil
+}
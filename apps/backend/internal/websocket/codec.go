@@ -0,0 +1,243 @@
+package websocket
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"github.com/lanxin/im-backend/internal/websocket/pb"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// WebSocket子协议（通过Sec-WebSocket-Protocol协商）
+// 客户端不指定子协议时使用JSON协议，兼容旧版客户端
+const (
+	SubprotocolJSON     = "lanxin.json.v1"
+	SubprotocolProtobuf = "lanxin.pb.v1"
+
+	// 二进制协议版本，写入每个Envelope
+	protobufVersion = 1
+)
+
+// 下行JSON转换为protobuf时忽略未定义的字段（如Message.receiver、Group.members）
+var protoJSONUnmarshal = protojson.UnmarshalOptions{DiscardUnknown: true}
+
+// jsonFrame 下行JSON帧的通用结构
+type jsonFrame struct {
+	Type      string          `json:"type"`
+	RequestID string          `json:"request_id"`
+	Code      int32           `json:"code"`
+	Message   string          `json:"message"`
+	Silent    bool            `json:"silent"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// encodeEnvelope 把下行JSON帧转换为protobuf Envelope
+// Hub内部统一以JSON投递，只在写入二进制协议连接时转换
+func encodeEnvelope(data []byte) ([]byte, error) {
+	var frame jsonFrame
+	if err := json.Unmarshal(data, &frame); err != nil {
+		return nil, err
+	}
+
+	env := &pb.Envelope{
+		Version:   protobufVersion,
+		Type:      frame.Type,
+		RequestId: frame.RequestID,
+		Silent:    frame.Silent,
+	}
+
+	if frame.Type == FrameTypeResponse {
+		env.Code = frame.Code
+		env.Message = frame.Message
+	}
+
+	if len(frame.Data) > 0 && string(frame.Data) != "null" {
+		if !setTypedBody(env, frame.Type, frame.Data) {
+			env.Body = &pb.Envelope_Json{Json: frame.Data}
+		}
+	}
+
+	return proto.Marshal(env)
+}
+
+// setTypedBody 按帧类型填充结构化消息体，类型未定义或解析失败时返回false
+func setTypedBody(env *pb.Envelope, frameType string, data json.RawMessage) bool {
+	switch {
+	case frameType == "message":
+		body, ok := decodeChatMessage(data)
+		if !ok {
+			return false
+		}
+		env.Body = &pb.Envelope_ChatMessage{ChatMessage: body}
+
+	case frameType == "message_status":
+		body := &pb.MessageStatus{}
+		if protoJSONUnmarshal.Unmarshal(data, body) != nil {
+			return false
+		}
+		env.Body = &pb.Envelope_MessageStatus{MessageStatus: body}
+
+	case frameType == "read_receipt":
+		body := &pb.ReadReceipt{}
+		if protoJSONUnmarshal.Unmarshal(data, body) != nil {
+			return false
+		}
+		env.Body = &pb.Envelope_ReadReceipt{ReadReceipt: body}
+
+	case frameType == "call_invite":
+		body := &pb.CallInvite{}
+		if protoJSONUnmarshal.Unmarshal(data, body) != nil {
+			return false
+		}
+		env.Body = &pb.Envelope_CallInvite{CallInvite: body}
+
+	case frameType == "group_created":
+		// group_created的data是完整的群信息
+		group := &pb.Group{}
+		if protoJSONUnmarshal.Unmarshal(data, group) != nil {
+			return false
+		}
+		env.Body = &pb.Envelope_GroupEvent{GroupEvent: &pb.GroupEvent{
+			GroupId:   group.Id,
+			GroupName: group.Name,
+			Group:     group,
+		}}
+
+	case strings.HasPrefix(frameType, "group_"):
+		body := &pb.GroupEvent{}
+		if protoJSONUnmarshal.Unmarshal(data, body) != nil {
+			return false
+		}
+		env.Body = &pb.Envelope_GroupEvent{GroupEvent: body}
+
+	case frameType == FrameTypeTyping || frameType == FrameTypeRecordingVoice:
+		body := &pb.Typing{}
+		if protoJSONUnmarshal.Unmarshal(data, body) != nil {
+			return false
+		}
+		env.Body = &pb.Envelope_Typing{Typing: body}
+
+	case frameType == FrameTypePong:
+		body := &pb.Pong{}
+		if protoJSONUnmarshal.Unmarshal(data, body) != nil {
+			return false
+		}
+		env.Body = &pb.Envelope_Pong{Pong: body}
+
+	default:
+		return false
+	}
+
+	return true
+}
+
+// decodeChatMessage 把消息JSON转换为ChatMessage
+// payload是任意JSON对象，protojson无法映射到bytes字段，单独取出后原样写入
+func decodeChatMessage(data json.RawMessage) (*pb.ChatMessage, bool) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, false
+	}
+	payload := fields["payload"]
+	delete(fields, "payload")
+
+	rest, err := json.Marshal(fields)
+	if err != nil {
+		return nil, false
+	}
+	body := &pb.ChatMessage{}
+	if protoJSONUnmarshal.Unmarshal(rest, body) != nil {
+		return nil, false
+	}
+	if len(payload) > 0 && string(payload) != "null" {
+		body.Payload = payload
+	}
+	return body, true
+}
+
+// decodeEnvelope 把上行protobuf Envelope转换为ClientFrame，复用JSON协议的处理逻辑
+func decodeEnvelope(data []byte) (*ClientFrame, error) {
+	env := &pb.Envelope{}
+	if err := proto.Unmarshal(data, env); err != nil {
+		return nil, err
+	}
+	if env.Version > protobufVersion {
+		return nil, errors.New("unsupported protocol version")
+	}
+
+	frame := &ClientFrame{
+		Type:      env.Type,
+		RequestID: env.RequestId,
+	}
+
+	var payload interface{}
+	switch body := env.Body.(type) {
+	case *pb.Envelope_Send:
+		req := map[string]interface{}{
+			"receiver_id":   body.Send.ReceiverId,
+			"group_id":      body.Send.GroupId,
+			"content":       body.Send.Content,
+			"type":          body.Send.Type,
+			"client_msg_id": body.Send.ClientMsgId,
+		}
+		if body.Send.FileUrl != "" {
+			req["file_url"] = body.Send.FileUrl
+		}
+		if body.Send.FileSize != 0 {
+			req["file_size"] = body.Send.FileSize
+		}
+		if body.Send.Duration != 0 {
+			req["duration"] = body.Send.Duration
+		}
+		if body.Send.ReplyToMessageId != 0 {
+			req["reply_to_message_id"] = body.Send.ReplyToMessageId
+		}
+		if body.Send.ThreadRootId != 0 {
+			req["thread_root_id"] = body.Send.ThreadRootId
+		}
+		if len(body.Send.MentionUserIds) > 0 {
+			req["mention_user_ids"] = body.Send.MentionUserIds
+		}
+		if body.Send.MentionAll {
+			req["mention_all"] = true
+		}
+		if len(body.Send.Payload) > 0 {
+			req["payload"] = json.RawMessage(body.Send.Payload)
+		}
+		payload = req
+
+	case *pb.Envelope_Ack:
+		payload = map[string]interface{}{"message_ids": body.Ack.MessageIds}
+
+	case *pb.Envelope_Read:
+		payload = map[string]interface{}{"conversation_id": body.Read.ConversationId}
+
+	case *pb.Envelope_TypingRef:
+		payload = map[string]interface{}{"conversation_id": body.TypingRef.ConversationId}
+
+	case *pb.Envelope_Signal:
+		payload = map[string]interface{}{
+			"conversation_id": body.Signal.ConversationId,
+			"state":           body.Signal.State,
+		}
+
+	case *pb.Envelope_Recall:
+		payload = map[string]interface{}{"message_id": body.Recall.MessageId}
+
+	case *pb.Envelope_Json:
+		frame.Data = body.Json
+		return frame, nil
+	}
+
+	if payload != nil {
+		raw, err := json.Marshal(payload)
+		if err != nil {
+			return nil, err
+		}
+		frame.Data = raw
+	}
+
+	return frame, nil
+}
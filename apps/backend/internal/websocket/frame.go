@@ -0,0 +1,54 @@
+package websocket
+
+import (
+	"encoding/json"
+)
+
+// 客户端上行帧类型
+const (
+	FrameTypePing           = "ping"
+	FrameTypeSend           = "send"
+	FrameTypeAck            = "ack"
+	FrameTypeRead           = "read"
+	FrameTypeTyping         = "typing"
+	FrameTypeRecordingVoice = "recording_voice"
+	FrameTypeRecall         = "recall"
+	FrameTypePresence       = "presence"
+)
+
+// 服务端应答帧类型
+const (
+	FrameTypePong     = "pong"
+	FrameTypeResponse = "response"
+)
+
+// ClientFrame 客户端上行帧
+// RequestID由客户端生成，服务端在应答帧中原样带回，用于匹配请求
+type ClientFrame struct {
+	Type      string          `json:"type"`
+	RequestID string          `json:"request_id,omitempty"`
+	Data      json.RawMessage `json:"data,omitempty"`
+}
+
+// ResponseFrame 服务端对上行帧的应答，格式与REST响应一致
+type ResponseFrame struct {
+	Type      string      `json:"type"`
+	RequestID string      `json:"request_id"`
+	Code      int         `json:"code"`
+	Message   string      `json:"message"`
+	Data      interface{} `json:"data"`
+}
+
+// FrameContext 上行帧所在连接的上下文
+type FrameContext struct {
+	UserID    uint
+	Username  string
+	IP        string
+	UserAgent string
+}
+
+// UpstreamHandler 上行帧处理器
+// 由业务层实现（websocket包不依赖service包），返回的data会放入应答帧
+type UpstreamHandler interface {
+	HandleFrame(ctx *FrameContext, frame *ClientFrame) (interface{}, error)
+}
@@ -2,9 +2,12 @@ package websocket
 
 import (
 	"encoding/json"
+	"errors"
 	"log"
 	"sync"
 	"time"
+
+	goredis "github.com/go-redis/redis/v8"
 )
 
 // Hub 维护活跃的客户端集合并向客户端广播消息
@@ -26,8 +29,40 @@ type Hub struct {
 
 	// 互斥锁
 	mu sync.RWMutex
+
+	// 多节点协调器（未启用集群时为nil）
+	cluster *Cluster
+
+	// 上行帧处理器（send/ack/read/typing/recall）
+	upstream UpstreamHandler
+
+	// 连接关闭或确认超时时仍未确认的消息回调
+	unacked UnackedHandler
+
+	// 连接上报的在线状态（online/away）
+	presenceUpdates chan presenceUpdate
+
+	// 在线状态变化回调，由presenceLoop在主循环之外调用
+	presence PresenceHandler
+
+	// 待回调的在线状态变化（同一用户的多次变化合并为一次）
+	presenceMu      sync.Mutex
+	presencePending map[uint]struct{}
+	presenceWake    chan struct{}
+
+	// 设备会话撤销检查
+	sessionChecker SessionChecker
 }
 
+// UnackedHandler 处理推送后未被客户端确认的消息（通常放回离线队列）
+type UnackedHandler func(userID uint, messageIDs []uint)
+
+// SessionChecker 检查设备会话是否已撤销，无法确认时返回错误
+type SessionChecker func(userID, sessionID uint) (bool, error)
+
+// ErrUserOffline 用户在任何节点上都没有连接
+var ErrUserOffline = errors.New("user has no active connections")
+
 // NewHub 创建新的Hub
 func NewHub() *Hub {
 	return &Hub{
@@ -36,30 +71,115 @@ func NewHub() *Hub {
 		broadcast:   make(chan []byte, 256),
 		register:    make(chan *Client),
 		unregister:  make(chan *Client),
+
+		presenceUpdates: make(chan presenceUpdate, 256),
+		presencePending: make(map[uint]struct{}),
+		presenceWake:    make(chan struct{}, 1),
 	}
 }
 
+// SetUpstreamHandler 设置客户端上行帧的处理器
+// 必须在接受连接之前调用
+func (h *Hub) SetUpstreamHandler(handler UpstreamHandler) {
+	h.upstream = handler
+}
+
+// SetUnackedHandler 设置未确认消息的回调
+func (h *Hub) SetUnackedHandler(handler UnackedHandler) {
+	h.unacked = handler
+}
+
+// SetSessionChecker 设置设备会话撤销检查
+// 必须在接受连接之前调用
+func (h *Hub) SetSessionChecker(checker SessionChecker) {
+	h.sessionChecker = checker
+}
+
+// EnableCluster 启用基于Redis的多节点模式
+// 启用后SendToUser会投递到用户所在的任意节点，在线状态和在线人数为全集群视角
+// 必须在Run之前调用
+func (h *Hub) EnableCluster(rdb *goredis.Client, nodeID string) {
+	h.cluster = newCluster(h, rdb, nodeID)
+	h.cluster.start()
+}
+
+// Shutdown 注销本节点在集群中的在线记录
+func (h *Hub) Shutdown() {
+	if h.cluster != nil {
+		h.cluster.stop()
+	}
+}
+
+// NodeID 返回本节点ID（未启用集群时为空）
+func (h *Hub) NodeID() string {
+	if h.cluster == nil {
+		return ""
+	}
+	return h.cluster.nodeID
+}
+
 // Run 启动Hub的主循环
 func (h *Hub) Run() {
+	go h.presenceLoop()
+
 	for {
 		select {
 		case client := <-h.register:
 			h.mu.Lock()
+			before := h.localPresence(client.userID)
 			h.clients[client] = true
 			// 将客户端添加到用户映射
 			h.userClients[client.userID] = append(h.userClients[client.userID], client)
+			after := h.localPresence(client.userID)
 			h.mu.Unlock()
 			log.Printf("Client registered: UserID=%d, Total clients=%d", client.userID, len(h.clients))
 
+			// 第一个连接或从away恢复，登记到集群在线注册表
+			if before != after {
+				h.presenceUpdated(client.userID, after)
+			}
+
+			// 握手检查之后、登记之前撤销的会话收不到断开通知，登记后再检查一次
+			if client.sessionID != 0 && h.sessionChecker != nil {
+				go h.recheckSession(client)
+			}
+
 		case client := <-h.unregister:
 			if _, ok := h.clients[client]; ok {
 				h.mu.Lock()
+				before := h.localPresence(client.userID)
 				delete(h.clients, client)
 				close(client.send)
 				// 从用户映射中移除
 				h.removeClientFromUser(client)
+				after := h.localPresence(client.userID)
 				h.mu.Unlock()
 				log.Printf("Client unregistered: UserID=%d, Total clients=%d", client.userID, len(h.clients))
+
+				// 连接关闭时仍未确认的消息交回业务层
+				if pending := client.drainAcks(); len(pending) > 0 {
+					h.notifyUnacked(client.userID, pending)
+				}
+
+				// 用户在本节点已无连接时从集群在线注册表移除，只剩away的连接时更新状态
+				if before != after {
+					h.presenceUpdated(client.userID, after)
+				}
+			}
+
+		case update := <-h.presenceUpdates:
+			h.mu.Lock()
+			if _, ok := h.clients[update.client]; !ok {
+				h.mu.Unlock()
+				continue
+			}
+			before := h.localPresence(update.client.userID)
+			update.client.presence = update.state
+			after := h.localPresence(update.client.userID)
+			h.mu.Unlock()
+
+			if before != after {
+				h.presenceUpdated(update.client.userID, after)
 			}
 
 		case message := <-h.broadcast:
@@ -93,30 +213,152 @@ func (h *Hub) removeClientFromUser(client *Client) {
 }
 
 // SendToUser 向指定用户的所有设备发送消息
+// 启用集群时，同时投递到该用户连接所在的其他节点
 func (h *Hub) SendToUser(userID uint, message interface{}) error {
 	data, err := json.Marshal(message)
 	if err != nil {
 		return err
 	}
 
+	h.deliverLocal(userID, data, 0)
+
+	if h.cluster != nil {
+		_, err := h.cluster.deliverRemote(userID, data, 0)
+		return err
+	}
+	return nil
+}
+
+// DisconnectSession 断开设备会话在所有节点上的连接
+// 连接先收到session_revoked通知，随后以CloseSessionRevoked关闭
+func (h *Hub) DisconnectSession(userID, sessionID uint, reason string) error {
+	data, err := json.Marshal(WebSocketMessage{
+		Type: "session_revoked",
+		Data: map[string]interface{}{
+			"session_id": sessionID,
+			"reason":     reason,
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	h.closeSessionLocal(userID, sessionID, data)
+
+	if h.cluster != nil {
+		return h.cluster.closeSession(userID, sessionID, data)
+	}
+	return nil
+}
+
+// recheckSession 连接登记后会话已被撤销时断开该连接
+func (h *Hub) recheckSession(client *Client) {
+	revoked, err := h.sessionChecker(client.userID, client.sessionID)
+	if err != nil {
+		log.Printf("Failed to recheck session %d: %v", client.sessionID, err)
+		return
+	}
+	if !revoked {
+		return
+	}
+
+	data, err := json.Marshal(WebSocketMessage{
+		Type: "session_revoked",
+		Data: map[string]interface{}{
+			"session_id": client.sessionID,
+		},
+	})
+	if err != nil {
+		return
+	}
+	select {
+	case client.kick <- data:
+	default:
+		// 已在关闭中
+	}
+}
+
+// closeSessionLocal 关闭本节点上属于该设备会话的连接，连接随后由readPump注销
+func (h *Hub) closeSessionLocal(userID, sessionID uint, data []byte) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for _, client := range h.userClients[userID] {
+		if client.sessionID != sessionID {
+			continue
+		}
+		select {
+		case client.kick <- data:
+		default:
+			// 已在关闭中
+		}
+	}
+}
+
+// PushMessage 推送需要客户端确认的新消息
+// 每个收到推送的连接都会记录待确认的messageID，连接关闭或确认超时后交给UnackedHandler
+// 用户在任何节点上都没有连接时返回ErrUserOffline
+// silent为true时客户端只更新界面不提醒（免打扰）
+func (h *Hub) PushMessage(userID, messageID uint, messageData interface{}, silent bool) error {
+	data, err := json.Marshal(WebSocketMessage{
+		Type:   "message",
+		Data:   messageData,
+		Silent: silent,
+	})
+	if err != nil {
+		return err
+	}
+
+	delivered := h.deliverLocal(userID, data, messageID)
+
+	if h.cluster != nil {
+		remote, err := h.cluster.deliverRemote(userID, data, messageID)
+		if err != nil {
+			return err
+		}
+		delivered += remote
+	}
+
+	if delivered == 0 {
+		return ErrUserOffline
+	}
+	return nil
+}
+
+// deliverLocal 投递给本节点上该用户的所有连接，返回投递的连接数
+// ackID不为0时，在每个连接上登记待确认
+func (h *Hub) deliverLocal(userID uint, data []byte, ackID uint) int {
 	h.mu.RLock()
 	defer h.mu.RUnlock()
 
 	clients, exists := h.userClients[userID]
 	if !exists || len(clients) == 0 {
-		log.Printf("User %d has no active connections", userID)
-		return nil
+		if h.cluster == nil {
+			log.Printf("User %d has no active connections", userID)
+		}
+		return 0
 	}
 
 	for _, client := range clients {
+		// 先登记再发送：发送队列满被丢弃的帧会在确认超时后重新入队
+		if ackID != 0 {
+			client.trackAck(ackID)
+		}
 		select {
 		case client.send <- data:
 		default:
 			log.Printf("Failed to send message to client of user %d", userID)
 		}
 	}
+	return len(clients)
+}
 
-	return nil
+// notifyUnacked 异步回调未确认消息
+func (h *Hub) notifyUnacked(userID uint, messageIDs []uint) {
+	if h.unacked == nil {
+		return
+	}
+	go h.unacked(userID, messageIDs)
 }
 
 // BroadcastToAll 向所有连接的客户端广播消息
@@ -127,11 +369,26 @@ func (h *Hub) BroadcastToAll(message interface{}) error {
 	}
 
 	h.broadcast <- data
+
+	if h.cluster != nil {
+		return h.cluster.broadcast(data)
+	}
 	return nil
 }
 
 // GetOnlineUserCount 获取在线用户数量
+// 启用集群时返回全集群在线用户数，Redis不可用时退回本节点数量
 func (h *Hub) GetOnlineUserCount() int {
+	if h.cluster != nil {
+		if count, err := h.cluster.onlineUserCount(); err == nil {
+			return count
+		}
+	}
+	return h.GetLocalOnlineUserCount()
+}
+
+// GetLocalOnlineUserCount 获取本节点在线用户数量
+func (h *Hub) GetLocalOnlineUserCount() int {
 	h.mu.RLock()
 	defer h.mu.RUnlock()
 	return len(h.userClients)
@@ -144,25 +401,34 @@ func (h *Hub) GetTotalClientCount() int {
 	return len(h.clients)
 }
 
-// IsUserOnline 检查用户是否在线
+// IsUserOnline 检查用户是否在线（启用集群时包括其他节点）
 func (h *Hub) IsUserOnline(userID uint) bool {
 	h.mu.RLock()
-	defer h.mu.RUnlock()
 	clients, exists := h.userClients[userID]
-	return exists && len(clients) > 0
+	h.mu.RUnlock()
+	if exists && len(clients) > 0 {
+		return true
+	}
+
+	if h.cluster != nil {
+		return h.cluster.isUserOnline(userID)
+	}
+	return false
 }
 
 // WebSocketMessage WebSocket消息格式
 type WebSocketMessage struct {
-	Type string      `json:"type"`
-	Data interface{} `json:"data"`
+	Type   string      `json:"type"`
+	Data   interface{} `json:"data"`
+	Silent bool        `json:"silent,omitempty"` // 接收者已免打扰且未被@，客户端只更新界面不提醒
 }
 
 // SendMessageNotification 发送新消息通知
-func (h *Hub) SendMessageNotification(userID uint, messageData interface{}) error {
+func (h *Hub) SendMessageNotification(userID uint, messageData interface{}, silent bool) error {
 	msg := WebSocketMessage{
-		Type: "message",
-		Data: messageData,
+		Type:   "message",
+		Data:   messageData,
+		Silent: silent,
 	}
 	return h.SendToUser(userID, msg)
 }
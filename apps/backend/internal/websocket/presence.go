@@ -0,0 +1,138 @@
+package websocket
+
+import (
+	"encoding/json"
+	"strconv"
+)
+
+// 在线状态
+// 每个连接是online或away（客户端切到后台时上报away），用户的状态取所有设备中最活跃的一个
+const (
+	PresenceOnline  = "online"
+	PresenceAway    = "away"
+	PresenceOffline = "offline"
+)
+
+// PresenceHandler 用户在线状态可能发生变化时的回调
+// 在Hub的通知协程中依次调用，短时间内的多次变化可能合并为一次；需要的话通过UserPresence读取最新状态
+type PresenceHandler func(userID uint)
+
+// presenceUpdate 连接上报的在线状态
+type presenceUpdate struct {
+	client *Client
+	state  string
+}
+
+// SetPresenceHandler 设置在线状态变化的回调
+// 必须在接受连接之前调用
+func (h *Hub) SetPresenceHandler(handler PresenceHandler) {
+	h.presence = handler
+}
+
+// UserPresence 返回用户在全集群的在线状态
+func (h *Hub) UserPresence(userID uint) string {
+	h.mu.RLock()
+	state := h.localPresence(userID)
+	h.mu.RUnlock()
+
+	if state == PresenceOnline || h.cluster == nil {
+		return state
+	}
+	return mergePresence(state, h.cluster.remotePresence(userID))
+}
+
+// localPresence 返回用户在本节点的在线状态，调用方需持有h.mu
+func (h *Hub) localPresence(userID uint) string {
+	state := PresenceOffline
+	for _, client := range h.userClients[userID] {
+		state = mergePresence(state, client.presence)
+	}
+	return state
+}
+
+// presenceUpdated 用户在本节点的状态变化后登记到集群在线注册表，并通知业务层
+func (h *Hub) presenceUpdated(userID uint, local string) {
+	if h.cluster != nil {
+		if local == PresenceOffline {
+			h.cluster.userDisconnected(userID)
+		} else {
+			h.cluster.userConnected(userID, local)
+		}
+	}
+	h.notifyPresence(userID)
+}
+
+// notifyPresence 登记用户的在线状态可能已变化并唤醒presenceLoop
+// 只记录用户ID，可以在Hub主循环和集群心跳中调用
+func (h *Hub) notifyPresence(userID uint) {
+	if h.presence == nil {
+		return
+	}
+
+	h.presenceMu.Lock()
+	h.presencePending[userID] = struct{}{}
+	h.presenceMu.Unlock()
+
+	select {
+	case h.presenceWake <- struct{}{}:
+	default:
+		// 已有未处理的唤醒
+	}
+}
+
+// presenceLoop 在主循环之外回调在线状态变化，回调变慢不影响连接的注册和注销
+func (h *Hub) presenceLoop() {
+	for range h.presenceWake {
+		h.presenceMu.Lock()
+		pending := h.presencePending
+		h.presencePending = make(map[uint]struct{})
+		h.presenceMu.Unlock()
+
+		for userID := range pending {
+			h.presence(userID)
+		}
+	}
+}
+
+// handlePresence 处理客户端上报的连接状态
+// data: {"state": "online" | "away"}
+func (c *Client) handlePresence(frame *ClientFrame) {
+	var req struct {
+		State string `json:"state"`
+	}
+	if err := json.Unmarshal(frame.Data, &req); err != nil ||
+		(req.State != PresenceOnline && req.State != PresenceAway) {
+		c.respond(frame.RequestID, 400, "invalid presence frame", nil)
+		return
+	}
+
+	c.hub.presenceUpdates <- presenceUpdate{client: c, state: req.State}
+
+	if frame.RequestID != "" {
+		c.respond(frame.RequestID, 0, "success", nil)
+	}
+}
+
+// mergePresence 合并两个状态，取更活跃的一个
+func mergePresence(a, b string) string {
+	if presenceRank(b) > presenceRank(a) {
+		return b
+	}
+	return a
+}
+
+func presenceRank(state string) int {
+	switch state {
+	case PresenceOnline:
+		return 2
+	case PresenceAway:
+		return 1
+	case PresenceOffline:
+		return 0
+	}
+	// 旧版本节点在在线注册表中写入的是连接时间戳，视为在线
+	if _, err := strconv.ParseInt(state, 10, 64); err == nil {
+		return 2
+	}
+	return 0
+}
@@ -0,0 +1,110 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	goredis "github.com/go-redis/redis/v8"
+	"github.com/lanxin/im-backend/internal/dao"
+	"github.com/lanxin/im-backend/internal/model"
+	"github.com/lanxin/im-backend/internal/pkg/redis"
+	"github.com/lanxin/im-backend/internal/service"
+	"github.com/lanxin/im-backend/pkg/kafka"
+	kafkago "github.com/segmentio/kafka-go"
+	"gorm.io/gorm"
+)
+
+const (
+	// 处理中标记的有效期，进程崩溃后超过该时间可被重新处理
+	processingTTL = 5 * time.Minute
+
+	// 已处理标记的保留时间，覆盖Kafka重复投递的窗口
+	processedTTL = 7 * 24 * time.Hour
+
+	processingMark = "processing"
+	processedMark  = "done"
+)
+
+// MessageHandler 消息Topic的消费处理器
+//
+// 处理流程:
+//  1. 以消息ID做幂等检查，重复消费直接跳过
+//  2. 从数据库加载消息（API已落库）
+//  3. 在线推送（客户端ACK后更新为已送达），离线则进入离线队列并发送通知栏推送，群消息投递给每个成员
+//
+// 搜索依赖messages表上的FULLTEXT索引，写入即生效，无需额外建索引
+type MessageHandler struct {
+	messageService *service.MessageService
+	messageDAO     *dao.MessageDAO
+	redisClient    *goredis.Client
+}
+
+// NewMessageHandler 创建消息处理器
+func NewMessageHandler(messageService *service.MessageService) *MessageHandler {
+	return &MessageHandler{
+		messageService: messageService,
+		messageDAO:     dao.NewMessageDAO(),
+		redisClient:    redis.GetClient(),
+	}
+}
+
+// Handle 实现kafka.MessageHandler
+func (h *MessageHandler) Handle(ctx context.Context, msg kafkago.Message) error {
+	var data kafka.MessageData
+	if err := json.Unmarshal(msg.Value, &data); err != nil {
+		return fmt.Errorf("decode message data: %w", err)
+	}
+	if data.ID == 0 {
+		return errors.New("message data without id")
+	}
+
+	key := fmt.Sprintf("kafka:msg_processed:%d", data.ID)
+
+	// 抢占处理权：已处理或正在处理的消息直接跳过
+	acquired, err := h.redisClient.SetNX(ctx, key, processingMark, processingTTL).Result()
+	if err != nil {
+		return fmt.Errorf("idempotency check: %w", err)
+	}
+	if !acquired {
+		log.Printf("Skip duplicate message: id=%d", data.ID)
+		return nil
+	}
+
+	if err := h.process(data); err != nil {
+		// 释放处理权，允许重试
+		h.redisClient.Del(ctx, key)
+		return err
+	}
+
+	h.redisClient.Set(ctx, key, processedMark, processedTTL)
+	return nil
+}
+
+// process 执行消息的下游处理
+func (h *MessageHandler) process(data kafka.MessageData) error {
+	message, err := h.messageDAO.GetByID(data.ID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// 消息已被删除，无需投递
+			log.Printf("Message %d not found, skip delivery", data.ID)
+			return nil
+		}
+		return fmt.Errorf("load message %d: %w", data.ID, err)
+	}
+
+	// 已撤回的消息不再投递
+	if message.Status == model.MessageStatusRecalled {
+		return nil
+	}
+
+	// 已送达或已读说明之前已完成投递
+	if message.Status != model.MessageStatusSent {
+		return nil
+	}
+
+	return h.messageService.DeliverMessage(message)
+}
@@ -0,0 +1,21 @@
+-- 创建事务发件箱表
+-- 用途：消息与Kafka事件在同一事务中写入，由中继循环异步投递，避免事件丢失
+-- 同Key的事件按ID顺序投递，中继领取事件时通过 idx_key_status 检查同Key是否还有更早的待投递事件
+CREATE TABLE IF NOT EXISTS outbox (
+    id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    aggregate_type VARCHAR(50) NOT NULL COMMENT '业务对象类型',
+    aggregate_id BIGINT UNSIGNED NOT NULL COMMENT '业务对象ID',
+    topic VARCHAR(100) NOT NULL COMMENT '目标Kafka Topic',
+    message_key VARCHAR(100) COMMENT 'Kafka消息Key',
+    payload TEXT NOT NULL COMMENT 'JSON消息体',
+    status ENUM('pending', 'sent') DEFAULT 'pending' COMMENT '投递状态',
+    attempts INT DEFAULT 0 COMMENT '已尝试次数',
+    last_error VARCHAR(500) COMMENT '最近一次失败原因',
+    next_attempt_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP COMMENT '下次可投递时间',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+    sent_at TIMESTAMP NULL COMMENT '投递成功时间',
+
+    INDEX idx_status_next_attempt (status, next_attempt_at),
+    INDEX idx_key_status (message_key, status),
+    INDEX idx_created_at (created_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='事务发件箱表';
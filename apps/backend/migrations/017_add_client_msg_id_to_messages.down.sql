@@ -0,0 +1,4 @@
+-- 删除messages表的client_msg_id字段
+ALTER TABLE `messages`
+DROP INDEX `uk_sender_client_msg`,
+DROP COLUMN `client_msg_id`;
@@ -0,0 +1,6 @@
+-- 添加messages表的client_msg_id字段
+-- 用途：客户端重试发送时按 (sender_id, client_msg_id) 去重，并用于匹配本地乐观消息
+ALTER TABLE `messages`
+ADD COLUMN `client_msg_id` VARCHAR(64) NULL COMMENT '客户端生成的消息ID'
+AFTER `group_id`,
+ADD UNIQUE INDEX `uk_sender_client_msg` (`sender_id`, `client_msg_id`);
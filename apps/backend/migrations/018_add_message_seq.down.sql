@@ -0,0 +1,7 @@
+-- 删除会话内消息序号
+ALTER TABLE `messages`
+DROP INDEX `uk_conversation_seq`,
+DROP COLUMN `seq`;
+
+ALTER TABLE `conversations`
+DROP COLUMN `max_seq`;
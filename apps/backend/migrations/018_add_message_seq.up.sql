@@ -0,0 +1,24 @@
+-- 添加会话内消息序号
+-- 用途：每个会话内消息按seq单调递增，客户端按seq增量同步，断线后可无缝补齐
+
+ALTER TABLE `conversations`
+ADD COLUMN `max_seq` BIGINT UNSIGNED NOT NULL DEFAULT 0 COMMENT '会话内最大消息序号'
+AFTER `last_message_at`;
+
+ALTER TABLE `messages`
+ADD COLUMN `seq` BIGINT UNSIGNED NOT NULL DEFAULT 0 COMMENT '会话内消息序号'
+AFTER `conversation_id`;
+
+-- 为已有消息按ID顺序回填序号（MySQL 8.0+）
+UPDATE `messages` m
+JOIN (
+    SELECT id, ROW_NUMBER() OVER (PARTITION BY conversation_id ORDER BY id) AS rn
+    FROM `messages`
+) t ON m.id = t.id
+SET m.seq = t.rn;
+
+UPDATE `conversations` c
+SET c.max_seq = (SELECT COALESCE(MAX(seq), 0) FROM `messages` WHERE conversation_id = c.id);
+
+ALTER TABLE `messages`
+ADD UNIQUE INDEX `uk_conversation_seq` (`conversation_id`, `seq`);
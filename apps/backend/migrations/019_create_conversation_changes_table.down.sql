@@ -0,0 +1,2 @@
+-- 删除会话变更日志表
+DROP TABLE IF EXISTS conversation_changes;
@@ -0,0 +1,14 @@
+-- 创建会话变更日志表
+-- 用途：记录不产生新seq的变更（如撤回），离线设备通过 /sync 的变更游标补齐
+-- user_id 为0的变更对会话所有参与者可见，否则只属于该用户；超过保留期的记录由清理任务删除
+CREATE TABLE IF NOT EXISTS conversation_changes (
+    id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    conversation_id BIGINT UNSIGNED NOT NULL COMMENT '会话ID',
+    user_id BIGINT UNSIGNED NOT NULL DEFAULT 0 COMMENT '只对该用户可见的变更，0表示所有参与者',
+    kind VARCHAR(32) NOT NULL COMMENT '变更类型',
+    message_id BIGINT UNSIGNED NULL COMMENT '变更的消息ID',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP COMMENT '变更时间',
+
+    INDEX idx_conversation_id (conversation_id, id),
+    INDEX idx_created_at (created_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='会话变更日志表';
@@ -0,0 +1,14 @@
+-- 恢复conversations表上的设置字段（个人设置无法还原为共享设置，取user1一方的值）
+ALTER TABLE conversations
+ADD COLUMN is_muted BOOLEAN DEFAULT FALSE COMMENT '是否免打扰',
+ADD COLUMN is_top BOOLEAN DEFAULT FALSE COMMENT '是否置顶',
+ADD COLUMN is_starred BOOLEAN DEFAULT FALSE COMMENT '是否星标',
+ADD COLUMN is_blocked BOOLEAN DEFAULT FALSE COMMENT '是否拉黑',
+ADD INDEX idx_is_top (is_top),
+ADD INDEX idx_is_muted (is_muted);
+
+UPDATE conversations c
+JOIN conversation_members cm ON cm.conversation_id = c.id AND cm.user_id = c.user1_id
+SET c.is_muted = cm.is_muted, c.is_top = cm.is_top, c.is_starred = cm.is_starred, c.is_blocked = cm.is_blocked;
+
+DROP TABLE IF EXISTS conversation_members;
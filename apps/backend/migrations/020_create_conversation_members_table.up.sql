@@ -0,0 +1,49 @@
+-- 创建用户会话状态表
+-- 用途：免打扰、置顶、星标、拉黑、未读数、已读位置、隐藏和草稿按用户独立保存
+-- 原conversations表上的设置字段由双方共享，一方修改会影响另一方
+CREATE TABLE IF NOT EXISTS conversation_members (
+    id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    conversation_id BIGINT UNSIGNED NOT NULL COMMENT '会话ID',
+    user_id BIGINT UNSIGNED NOT NULL COMMENT '用户ID',
+    is_muted BOOLEAN DEFAULT FALSE COMMENT '是否免打扰',
+    is_top BOOLEAN DEFAULT FALSE COMMENT '是否置顶',
+    is_starred BOOLEAN DEFAULT FALSE COMMENT '是否星标',
+    is_blocked BOOLEAN DEFAULT FALSE COMMENT '是否拉黑',
+    is_hidden BOOLEAN DEFAULT FALSE COMMENT '是否从会话列表隐藏（收到新消息后恢复）',
+    unread_count INT NOT NULL DEFAULT 0 COMMENT '未读消息数',
+    last_read_seq BIGINT UNSIGNED NOT NULL DEFAULT 0 COMMENT '已读到的消息序号',
+    draft TEXT COMMENT '草稿',
+    draft_updated_at TIMESTAMP NULL COMMENT '草稿更新时间',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+
+    UNIQUE KEY uk_conversation_user (conversation_id, user_id),
+    INDEX idx_user_hidden (user_id, is_hidden),
+    FOREIGN KEY (conversation_id) REFERENCES conversations(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='用户会话状态表';
+
+-- 为已有单聊回填双方的状态行，原共享设置复制给双方
+INSERT IGNORE INTO conversation_members
+    (conversation_id, user_id, is_muted, is_top, is_starred, is_blocked, unread_count, last_read_seq)
+SELECT c.id, p.user_id, c.is_muted, c.is_top, c.is_starred, c.is_blocked,
+    (SELECT COUNT(*) FROM messages m
+        WHERE m.conversation_id = c.id AND m.receiver_id = p.user_id
+        AND m.status IN ('sent', 'delivered')),
+    (SELECT COALESCE(MAX(m.seq), 0) FROM messages m
+        WHERE m.conversation_id = c.id AND (m.sender_id = p.user_id OR m.status = 'read'))
+FROM conversations c
+JOIN (
+    SELECT id AS conversation_id, user1_id AS user_id FROM conversations WHERE type = 'single' AND user1_id IS NOT NULL
+    UNION ALL
+    SELECT id AS conversation_id, user2_id AS user_id FROM conversations WHERE type = 'single' AND user2_id IS NOT NULL
+) p ON p.conversation_id = c.id;
+
+-- 删除共享的设置字段
+ALTER TABLE conversations
+DROP INDEX idx_is_top,
+DROP INDEX idx_is_muted,
+DROP COLUMN is_muted,
+DROP COLUMN is_top,
+DROP COLUMN is_starred,
+DROP COLUMN is_blocked;
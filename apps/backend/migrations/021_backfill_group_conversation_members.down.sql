@@ -0,0 +1,4 @@
+-- 删除群聊的用户会话状态
+DELETE cm FROM conversation_members cm
+JOIN conversations c ON c.id = cm.conversation_id
+WHERE c.type = 'group';
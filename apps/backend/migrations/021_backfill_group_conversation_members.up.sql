@@ -0,0 +1,18 @@
+-- 群聊接入用户会话状态表
+-- 用途：群聊通过conversation_members出现在成员的会话列表中，并按成员各自的已读位置计算未读数
+
+-- 为还没有会话的群创建群会话
+INSERT INTO conversations (type, group_id)
+SELECT 'group', g.id
+FROM `groups` g
+WHERE g.status = 'active'
+AND NOT EXISTS (
+    SELECT 1 FROM conversations c WHERE c.type = 'group' AND c.group_id = g.id
+);
+
+-- 为现有群成员回填状态行，历史群消息没有已读记录，已读位置从当前最新消息开始
+INSERT IGNORE INTO conversation_members (conversation_id, user_id, unread_count, last_read_seq)
+SELECT c.id, gm.user_id, 0, c.max_seq
+FROM conversations c
+JOIN group_members gm ON gm.group_id = c.group_id
+WHERE c.type = 'group';
@@ -0,0 +1,6 @@
+-- 删除消息编辑支持
+DROP TABLE IF EXISTS message_edits;
+
+ALTER TABLE messages
+DROP COLUMN edit_count,
+DROP COLUMN edited_at;
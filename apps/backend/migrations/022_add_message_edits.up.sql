@@ -0,0 +1,18 @@
+-- 支持消息编辑
+-- 用途：发送者可在时限内编辑文本消息，每次编辑保留历史
+
+ALTER TABLE messages
+ADD COLUMN edited_at TIMESTAMP NULL COMMENT '最后一次编辑时间' AFTER status,
+ADD COLUMN edit_count INT NOT NULL DEFAULT 0 COMMENT '编辑次数' AFTER edited_at;
+
+CREATE TABLE IF NOT EXISTS message_edits (
+    id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    message_id BIGINT UNSIGNED NOT NULL COMMENT '消息ID',
+    editor_id BIGINT UNSIGNED NOT NULL COMMENT '编辑者ID',
+    previous_content TEXT NOT NULL COMMENT '编辑前的内容',
+    content TEXT NOT NULL COMMENT '编辑后的内容',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP COMMENT '编辑时间',
+
+    INDEX idx_message_id (message_id),
+    FOREIGN KEY (message_id) REFERENCES messages(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='消息编辑历史表';
@@ -0,0 +1,9 @@
+-- 删除引用回复和消息话题
+ALTER TABLE messages
+DROP INDEX idx_thread_root_seq,
+DROP INDEX idx_reply_to_message_id,
+DROP COLUMN last_thread_reply_at,
+DROP COLUMN last_thread_reply_id,
+DROP COLUMN thread_reply_count,
+DROP COLUMN thread_root_id,
+DROP COLUMN reply_to_message_id;
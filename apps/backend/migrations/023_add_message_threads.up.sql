@@ -0,0 +1,11 @@
+-- 支持引用回复和消息话题
+-- 用途：消息可引用同一会话中的另一条消息，或作为某条根消息下的话题回复
+
+ALTER TABLE messages
+ADD COLUMN reply_to_message_id BIGINT UNSIGNED NULL COMMENT '引用回复的消息ID' AFTER edit_count,
+ADD COLUMN thread_root_id BIGINT UNSIGNED NULL COMMENT '所属话题的根消息ID' AFTER reply_to_message_id,
+ADD COLUMN thread_reply_count INT NOT NULL DEFAULT 0 COMMENT '话题回复数（根消息）' AFTER thread_root_id,
+ADD COLUMN last_thread_reply_id BIGINT UNSIGNED NULL COMMENT '最后一条话题回复ID（根消息）' AFTER thread_reply_count,
+ADD COLUMN last_thread_reply_at TIMESTAMP NULL COMMENT '最后一条话题回复时间（根消息）' AFTER last_thread_reply_id,
+ADD INDEX idx_reply_to_message_id (reply_to_message_id),
+ADD INDEX idx_thread_root_seq (thread_root_id, seq);
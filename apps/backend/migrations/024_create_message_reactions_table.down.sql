@@ -0,0 +1,2 @@
+-- 删除消息表情回应表
+DROP TABLE IF EXISTS message_reactions;
@@ -0,0 +1,14 @@
+-- 创建消息表情回应表
+-- 用途：用户可对消息添加/移除表情回应，消息列表按表情汇总展示
+CREATE TABLE IF NOT EXISTS message_reactions (
+    id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    message_id BIGINT UNSIGNED NOT NULL COMMENT '消息ID',
+    user_id BIGINT UNSIGNED NOT NULL COMMENT '用户ID',
+    emoji VARCHAR(32) NOT NULL COMMENT '表情',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP COMMENT '回应时间',
+
+    UNIQUE KEY uk_message_user_emoji (message_id, user_id, emoji),
+    INDEX idx_user_id (user_id),
+    FOREIGN KEY (message_id) REFERENCES messages(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_bin COMMENT='消息表情回应表';
@@ -0,0 +1,7 @@
+-- 删除@提醒
+ALTER TABLE conversation_members
+DROP COLUMN mention_message_id;
+
+ALTER TABLE messages
+DROP COLUMN mention_all,
+DROP COLUMN mention_user_ids;
@@ -0,0 +1,9 @@
+-- 支持@提醒
+-- 用途：群消息可@指定成员或@所有人，被@的成员在会话列表中看到"有人@我"，并且不受免打扰影响
+
+ALTER TABLE messages
+ADD COLUMN mention_user_ids JSON NULL COMMENT '被@的成员ID列表' AFTER last_thread_reply_at,
+ADD COLUMN mention_all BOOLEAN DEFAULT FALSE COMMENT '是否@所有人' AFTER mention_user_ids;
+
+ALTER TABLE conversation_members
+ADD COLUMN mention_message_id BIGINT UNSIGNED NULL COMMENT '最早一条未读的@本人消息ID' AFTER last_read_seq;
@@ -0,0 +1,8 @@
+-- 删除消息转发
+DELETE FROM messages WHERE type = 'chat_record';
+
+ALTER TABLE messages
+DROP INDEX idx_forward_from_id,
+DROP COLUMN forward_sender_id,
+DROP COLUMN forward_from_id,
+MODIFY COLUMN type ENUM('text', 'image', 'voice', 'video', 'file') DEFAULT 'text' COMMENT '消息类型';
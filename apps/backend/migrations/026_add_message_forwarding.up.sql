@@ -0,0 +1,8 @@
+-- 支持消息转发
+-- 用途：逐条转发时记录源消息和原始发送者；合并转发生成chat_record类型的聊天记录消息
+
+ALTER TABLE messages
+MODIFY COLUMN type ENUM('text', 'image', 'voice', 'video', 'file', 'chat_record') DEFAULT 'text' COMMENT '消息类型',
+ADD COLUMN forward_from_id BIGINT UNSIGNED NULL COMMENT '转发的源消息ID' AFTER mention_all,
+ADD COLUMN forward_sender_id BIGINT UNSIGNED NULL COMMENT '源消息的原始发送者ID' AFTER forward_from_id,
+ADD INDEX idx_forward_from_id (forward_from_id);
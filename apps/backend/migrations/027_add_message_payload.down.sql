@@ -0,0 +1,11 @@
+-- 删除结构化消息内容
+UPDATE messages
+SET content = CAST(payload AS CHAR)
+WHERE type = 'chat_record' AND payload IS NOT NULL;
+
+DELETE FROM messages WHERE type IN ('location', 'card', 'sticker', 'rich_text', 'system');
+
+ALTER TABLE messages
+DROP COLUMN payload_version,
+DROP COLUMN payload,
+MODIFY COLUMN type ENUM('text', 'image', 'voice', 'video', 'file', 'chat_record') DEFAULT 'text' COMMENT '消息类型';
@@ -0,0 +1,14 @@
+-- 支持结构化消息内容
+-- 用途：位置、名片、表情贴图、富文本、系统通知等类型的内容存入带版本号的payload，content保留纯文本摘要
+-- 合并转发的聊天记录从content迁移到payload
+
+ALTER TABLE messages
+MODIFY COLUMN type ENUM('text', 'image', 'voice', 'video', 'file', 'chat_record', 'location', 'card', 'sticker', 'rich_text', 'system') DEFAULT 'text' COMMENT '消息类型',
+ADD COLUMN payload JSON NULL COMMENT '结构化内容' AFTER duration,
+ADD COLUMN payload_version INT NOT NULL DEFAULT 0 COMMENT 'payload结构版本' AFTER payload;
+
+UPDATE messages
+SET payload = CAST(content AS JSON),
+    payload_version = 1,
+    content = CONCAT('[聊天记录] ', JSON_UNQUOTE(JSON_EXTRACT(content, '$.title')))
+WHERE type = 'chat_record' AND JSON_VALID(content);
@@ -0,0 +1,5 @@
+-- 删除按用户的消息可见性
+DROP TABLE IF EXISTS message_deletions;
+
+ALTER TABLE conversation_members
+DROP COLUMN cleared_seq;
@@ -0,0 +1,19 @@
+-- 支持按用户删除消息和清空聊天记录
+-- 用途：删除消息（仅自己）记录在message_deletions；清空聊天记录时记录cleared_seq，之前的消息对本人不可见
+
+ALTER TABLE conversation_members
+ADD COLUMN cleared_seq BIGINT UNSIGNED NOT NULL DEFAULT 0 COMMENT '清空聊天记录时的消息序号' AFTER last_read_seq;
+
+CREATE TABLE IF NOT EXISTS message_deletions (
+    id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    user_id BIGINT UNSIGNED NOT NULL COMMENT '用户ID',
+    message_id BIGINT UNSIGNED NOT NULL COMMENT '消息ID',
+    conversation_id BIGINT UNSIGNED NOT NULL COMMENT '会话ID',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP COMMENT '删除时间',
+
+    UNIQUE KEY uk_user_message (user_id, message_id),
+    INDEX idx_message_id (message_id),
+    INDEX idx_conversation_id (conversation_id),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (message_id) REFERENCES messages(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='消息删除记录表（仅对本人）';
@@ -0,0 +1,2 @@
+-- 删除定时消息表
+DROP TABLE IF EXISTS scheduled_messages;
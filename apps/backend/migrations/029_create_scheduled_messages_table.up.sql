@@ -0,0 +1,29 @@
+-- 创建定时消息表
+-- 用途：用户预约在指定时间发送的消息，由服务端轮询到期记录并按普通消息发送
+-- 调度器领取到期消息后标记为dispatching并提交事务，发送期间不持有行锁；发送中退出时，claimed_at超过租约的记录会被重新领取
+CREATE TABLE IF NOT EXISTS scheduled_messages (
+    id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    sender_id BIGINT UNSIGNED NOT NULL COMMENT '发送者ID',
+    receiver_id BIGINT UNSIGNED NULL COMMENT '单聊接收者ID',
+    group_id BIGINT UNSIGNED NULL COMMENT '群组ID',
+    content TEXT NOT NULL COMMENT '消息内容',
+    type VARCHAR(20) NOT NULL DEFAULT 'text' COMMENT '消息类型',
+    file_url VARCHAR(500) COMMENT '文件URL',
+    file_size BIGINT COMMENT '文件大小',
+    duration INT COMMENT '语音/视频时长（秒）',
+    payload JSON NULL COMMENT '结构化内容',
+    mention_user_ids JSON NULL COMMENT '被@的成员ID列表',
+    mention_all BOOLEAN DEFAULT FALSE COMMENT '是否@所有人',
+    scheduled_at TIMESTAMP NOT NULL COMMENT '计划发送时间',
+    status ENUM('pending', 'dispatching', 'sent', 'failed', 'canceled') DEFAULT 'pending' COMMENT '状态',
+    claimed_at TIMESTAMP NULL COMMENT '调度器领取时间',
+    message_id BIGINT UNSIGNED NULL COMMENT '发送成功后的消息ID',
+    last_error VARCHAR(500) COMMENT '发送失败原因',
+    sent_at TIMESTAMP NULL COMMENT '实际发送时间',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+
+    INDEX idx_status_scheduled (status, scheduled_at),
+    INDEX idx_sender_status (sender_id, status),
+    FOREIGN KEY (sender_id) REFERENCES users(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='定时消息表';
@@ -0,0 +1,9 @@
+-- 删除阅后即焚
+ALTER TABLE messages
+DROP INDEX idx_expires_at,
+DROP COLUMN expires_at,
+DROP COLUMN expire_seconds;
+
+ALTER TABLE conversations
+DROP COLUMN disappear_seconds,
+DROP COLUMN disappear_mode;
@@ -0,0 +1,11 @@
+-- 支持阅后即焚
+-- 用途：会话级的过期设置；消息记录存活时长和过期时间，到期后由服务端删除消息及其文件
+
+ALTER TABLE conversations
+ADD COLUMN disappear_mode ENUM('off', 'after_read', 'after_send') DEFAULT 'off' COMMENT '阅后即焚模式' AFTER max_seq,
+ADD COLUMN disappear_seconds INT NOT NULL DEFAULT 0 COMMENT '已读后或发送后多少秒过期' AFTER disappear_mode;
+
+ALTER TABLE messages
+ADD COLUMN expire_seconds INT NOT NULL DEFAULT 0 COMMENT '消息存活时长（秒），0表示不过期' AFTER forward_sender_id,
+ADD COLUMN expires_at TIMESTAMP NULL COMMENT '过期时间' AFTER expire_seconds,
+ADD INDEX idx_expires_at (expires_at);
@@ -0,0 +1,2 @@
+-- 删除置顶消息表
+DROP TABLE IF EXISTS message_pins;
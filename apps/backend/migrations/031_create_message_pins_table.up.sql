@@ -0,0 +1,14 @@
+-- 创建置顶消息表
+-- 用途：群主/管理员（单聊双方）把重要消息置顶在会话顶部，对所有参与者可见
+CREATE TABLE IF NOT EXISTS message_pins (
+    id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    conversation_id BIGINT UNSIGNED NOT NULL COMMENT '会话ID',
+    message_id BIGINT UNSIGNED NOT NULL COMMENT '消息ID',
+    pinned_by BIGINT UNSIGNED NOT NULL COMMENT '置顶操作者ID',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP COMMENT '置顶时间',
+
+    UNIQUE KEY uk_conversation_message (conversation_id, message_id),
+    INDEX idx_message_id (message_id),
+    FOREIGN KEY (conversation_id) REFERENCES conversations(id) ON DELETE CASCADE,
+    FOREIGN KEY (message_id) REFERENCES messages(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='置顶消息表';
@@ -0,0 +1,2 @@
+-- 删除用户在线信息表
+DROP TABLE IF EXISTS user_presence;
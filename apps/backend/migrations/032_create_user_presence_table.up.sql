@@ -0,0 +1,12 @@
+-- 创建用户在线信息表
+-- 用途：保存最后在线时间（由Redis定期写回）、自定义状态和隐藏最后在线时间的隐私设置
+CREATE TABLE IF NOT EXISTS user_presence (
+    user_id BIGINT UNSIGNED PRIMARY KEY COMMENT '用户ID',
+    last_seen_at TIMESTAMP NULL COMMENT '最后在线时间',
+    custom_status VARCHAR(100) NOT NULL DEFAULT '' COMMENT '自定义状态文本',
+    custom_status_expires_at TIMESTAMP NULL COMMENT '自定义状态过期时间，为空表示不过期',
+    hide_last_seen BOOLEAN NOT NULL DEFAULT FALSE COMMENT '对其他用户隐藏最后在线时间',
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='用户在线信息表';
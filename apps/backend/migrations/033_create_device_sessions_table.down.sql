@@ -0,0 +1,2 @@
+-- 删除设备会话表
+DROP TABLE IF EXISTS device_sessions;
@@ -0,0 +1,20 @@
+-- 创建设备会话表
+-- 用途：记录每台设备的登录会话，Token携带会话ID；支持查看登录设备、移除设备和按平台限制同时登录的设备数
+CREATE TABLE IF NOT EXISTS device_sessions (
+    id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    user_id BIGINT UNSIGNED NOT NULL COMMENT '用户ID',
+    device_id VARCHAR(64) NOT NULL COMMENT '客户端生成的设备标识',
+    device_name VARCHAR(100) COMMENT '设备名称',
+    platform ENUM('android', 'ios', 'windows', 'macos', 'linux', 'web', 'unknown') DEFAULT 'unknown' COMMENT '平台',
+    app_version VARCHAR(32) COMMENT '客户端版本',
+    last_ip VARCHAR(45) COMMENT '最近访问IP',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP COMMENT '登录时间',
+    last_active_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP COMMENT '最近活跃时间',
+    expires_at TIMESTAMP NOT NULL COMMENT '最近签发的Token过期时间',
+    revoked_at TIMESTAMP NULL COMMENT '撤销时间',
+    revoke_reason VARCHAR(20) COMMENT '撤销原因：logout/revoked/replaced',
+
+    INDEX idx_user_id (user_id),
+    INDEX idx_revoked_at (revoked_at),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='设备会话表';
@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"net/url"
 	"path"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -16,9 +17,10 @@ import (
 // Client 自建对象存储COS客户端
 // 注意：这是自建的对象存储服务，不是腾讯云COS
 type Client struct {
-	client *cos.Client
-	bucket string
-	region string
+	client  *cos.Client
+	bucket  string
+	region  string
+	baseURL string
 }
 
 // Config 自建COS配置
@@ -47,9 +49,10 @@ func NewClient(cfg Config) (*Client, error) {
 	})
 
 	return &Client{
-		client: cosClient,
-		bucket: cfg.Bucket,
-		region: cfg.Region,
+		client:  cosClient,
+		bucket:  cfg.Bucket,
+		region:  cfg.Region,
+		baseURL: strings.TrimRight(cfg.BaseURL, "/"),
 	}, nil
 }
 
@@ -84,6 +87,22 @@ func (c *Client) DeleteFile(ctx context.Context, objectKey string) error {
 	return err
 }
 
+// ObjectKey 从文件URL中解析对象Key（uploads/...），不是本存储服务上的文件时返回false
+func (c *Client) ObjectKey(fileURL string) (string, bool) {
+	if c.baseURL == "" || !strings.HasPrefix(fileURL, c.baseURL+"/") {
+		return "", false
+	}
+	u, err := url.Parse(fileURL)
+	if err != nil {
+		return "", false
+	}
+	idx := strings.Index(u.Path, "/uploads/")
+	if idx < 0 {
+		return "", false
+	}
+	return u.Path[idx+1:], true
+}
+
 // GetPresignedURL 获取预签名URL（用于临时访问）
 func (c *Client) GetPresignedURL(ctx context.Context, objectKey string, expire time.Duration) (string, error) {
 	presignedURL, err := c.client.Object.GetPresignedURL(ctx, http.MethodGet, objectKey, "", "", expire, nil)
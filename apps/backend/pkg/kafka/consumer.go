@@ -2,7 +2,9 @@ package kafka
 
 import (
 	"context"
+	"errors"
 	"log"
+	"strconv"
 	"time"
 
 	"github.com/segmentio/kafka-go"
@@ -12,6 +14,10 @@ import (
 type Consumer struct {
 	reader  *kafka.Reader
 	handler MessageHandler
+
+	// 死信队列（为nil时失败的消息一直重试，阻塞所在分区直到处理成功）
+	deadLetter *Producer
+	maxRetries int
 }
 
 // MessageHandler 消息处理接口
@@ -37,6 +43,13 @@ func NewConsumer(brokers []string, topic, groupID string, handler MessageHandler
 	}
 }
 
+// SetDeadLetter 配置死信队列
+// 消息处理失败后最多重试maxRetries次，仍失败则转发到死信Topic并提交offset，避免阻塞分区
+func (c *Consumer) SetDeadLetter(producer *Producer, maxRetries int) {
+	c.deadLetter = producer
+	c.maxRetries = maxRetries
+}
+
 // Start 启动消费者
 func (c *Consumer) Start(ctx context.Context) {
 	log.Printf("Starting Kafka consumer for topic: %s", c.reader.Config().Topic)
@@ -49,6 +62,9 @@ func (c *Consumer) Start(ctx context.Context) {
 		default:
 			message, err := c.reader.FetchMessage(ctx)
 			if err != nil {
+				if errors.Is(err, context.Canceled) {
+					return
+				}
 				log.Printf("Error fetching message: %v", err)
 				continue
 			}
@@ -56,21 +72,95 @@ func (c *Consumer) Start(ctx context.Context) {
 			log.Printf("Received message: topic=%s, partition=%d, offset=%d, key=%s",
 				message.Topic, message.Partition, message.Offset, string(message.Key))
 
-			// 处理消息
-			if err := c.handler.Handle(ctx, message); err != nil {
-				log.Printf("Error handling message: %v", err)
-				// ✅ 错误处理：记录日志，生产环境可配置死信队列
-				// 建议：使用Kafka的重试机制或实现死信队列（DLQ）
-			} else {
-				// 提交offset
-				if err := c.reader.CommitMessages(ctx, message); err != nil {
-					log.Printf("Error committing message: %v", err)
-				}
+			// 处理消息，只有退出时才会返回错误
+			if err := c.process(ctx, message); err != nil {
+				log.Printf("Kafka consumer stopped before message was handled: offset=%d", message.Offset)
+				return
+			}
+
+			// 提交offset
+			if err := c.reader.CommitMessages(ctx, message); err != nil {
+				log.Printf("Error committing message: %v", err)
 			}
 		}
 	}
 }
 
+// process 处理一条消息，直到处理成功或转入死信队列才返回nil
+// 失败的消息不能跳过：之后处理的任何一条消息提交offset时都会越过它，消息就丢失了
+func (c *Consumer) process(ctx context.Context, message kafka.Message) error {
+	for round := 0; ; round++ {
+		if round > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(retryBackoff(round)):
+			}
+		}
+
+		err := c.handle(ctx, message)
+		if err == nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		log.Printf("Error handling message: %v", err)
+
+		if c.deadLetter == nil {
+			continue
+		}
+		// 多次失败，转入死信队列
+		if err := c.sendToDeadLetter(ctx, message, err); err != nil {
+			log.Printf("Error sending message to dead letter topic: %v", err)
+			continue
+		}
+		return nil
+	}
+}
+
+// retryBackoff 第round轮重试前的等待时间，最长30秒
+func retryBackoff(round int) time.Duration {
+	backoff := time.Duration(round) * time.Second
+	if backoff > 30*time.Second {
+		backoff = 30 * time.Second
+	}
+	return backoff
+}
+
+// handle 调用处理器，配置了死信队列时按退避间隔重试
+func (c *Consumer) handle(ctx context.Context, message kafka.Message) error {
+	var err error
+	for attempt := 0; attempt <= c.maxRetries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(time.Duration(attempt) * 500 * time.Millisecond):
+			}
+		}
+
+		if err = c.handler.Handle(ctx, message); err == nil {
+			return nil
+		}
+		log.Printf("Handle message failed: offset=%d, attempt=%d, err=%v", message.Offset, attempt+1, err)
+	}
+	return err
+}
+
+// sendToDeadLetter 把处理失败的消息连同来源和错误信息转发到死信Topic
+func (c *Consumer) sendToDeadLetter(ctx context.Context, message kafka.Message, cause error) error {
+	headers := append([]kafka.Header{}, message.Headers...)
+	headers = append(headers,
+		kafka.Header{Key: "dlq-origin-topic", Value: []byte(message.Topic)},
+		kafka.Header{Key: "dlq-origin-partition", Value: []byte(strconv.Itoa(message.Partition))},
+		kafka.Header{Key: "dlq-origin-offset", Value: []byte(strconv.FormatInt(message.Offset, 10))},
+		kafka.Header{Key: "dlq-error", Value: []byte(cause.Error())},
+	)
+
+	return c.deadLetter.SendWithHeaders(ctx, message.Key, message.Value, headers)
+}
+
 // Close 关闭消费者
 func (c *Consumer) Close() error {
 	return c.reader.Close()
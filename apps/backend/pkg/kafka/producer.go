@@ -3,6 +3,7 @@ package kafka
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"log"
 	"time"
 
@@ -19,12 +20,14 @@ func NewProducer(brokers []string, topic string) *Producer {
 	w := &kafka.Writer{
 		Addr:         kafka.TCP(brokers...),
 		Topic:        topic,
-		Balancer:     &kafka.LeastBytes{},
+		Balancer:     &kafka.Hash{},    // 按Key分区，同Key消息在同一分区内保持顺序
 		RequiredAcks: kafka.RequireAll, // 等待所有副本确认
-		Async:        false,             // 同步发送，保证可靠性
+		Async:        false,            // 同步发送，保证可靠性
 		Compression:  kafka.Snappy,
 		MaxAttempts:  3,
 		WriteTimeout: 10 * time.Second,
+		// 同步发送时每次WriteMessages都要等批次凑满或超时，默认1秒会把吞吐限制在每秒一批
+		BatchTimeout: 10 * time.Millisecond,
 	}
 
 	return &Producer{writer: w}
@@ -47,6 +50,62 @@ func (p *Producer) SendMessage(ctx context.Context, key, value []byte) error {
 	return nil
 }
 
+// SendWithHeaders 发送带Header的消息到Kafka
+func (p *Producer) SendWithHeaders(ctx context.Context, key, value []byte, headers []kafka.Header) error {
+	message := kafka.Message{
+		Key:     key,
+		Value:   value,
+		Headers: headers,
+		Time:    time.Now(),
+	}
+
+	err := p.writer.WriteMessages(ctx, message)
+	if err != nil {
+		log.Printf("Failed to write message to Kafka: %v", err)
+		return err
+	}
+
+	return nil
+}
+
+// Message 批量发送的一条消息
+type Message struct {
+	Key   []byte
+	Value []byte
+}
+
+// SendBatch 一次写入多条消息，返回与messages一一对应的错误（nil表示成功）
+// 同Key的消息按传入顺序写入同一分区
+func (p *Producer) SendBatch(ctx context.Context, messages []Message) []error {
+	errs := make([]error, len(messages))
+	if len(messages) == 0 {
+		return errs
+	}
+
+	now := time.Now()
+	batch := make([]kafka.Message, len(messages))
+	for i, m := range messages {
+		batch[i] = kafka.Message{Key: m.Key, Value: m.Value, Time: now}
+	}
+
+	err := p.writer.WriteMessages(ctx, batch...)
+	if err == nil {
+		return errs
+	}
+	log.Printf("Failed to write batch to Kafka: %v", err)
+
+	// 部分失败时kafka-go返回逐条的WriteErrors
+	var writeErrs kafka.WriteErrors
+	if errors.As(err, &writeErrs) && len(writeErrs) == len(messages) {
+		copy(errs, writeErrs)
+		return errs
+	}
+	for i := range errs {
+		errs[i] = err
+	}
+	return errs
+}
+
 // SendMessageWithPartition 发送消息到指定分区
 func (p *Producer) SendMessageWithPartition(ctx context.Context, partition int, key, value []byte) error {
 	message := kafka.Message{
@@ -86,9 +145,11 @@ type MessageData struct {
 	ConversationID uint   `json:"conversation_id"`
 	SenderID       uint   `json:"sender_id"`
 	ReceiverID     uint   `json:"receiver_id"`
+	GroupID        uint   `json:"group_id,omitempty"`
 	Content        string `json:"content"`
 	Type           string `json:"type"`
 	FileURL        string `json:"file_url,omitempty"`
+	ClientMsgID    string `json:"client_msg_id,omitempty"`
 	CreatedAt      int64  `json:"created_at"`
 }
 